@@ -6,10 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 
 	"github.com/temirov/ghttp/internal/certificates"
+	"github.com/temirov/ghttp/internal/server"
 	"github.com/temirov/ghttp/pkg/logging"
 )
 
@@ -21,12 +23,14 @@ const (
 	contextKeyHTTPSHosts           contextKey = "https-hosts"
 	contextKeyHTTPSCertificateDir  contextKey = "https-certificate-directory"
 
-	defaultServePort       = "8000"
-	defaultHTTPSServePort  = "8443"
-	defaultProtocolVersion = "HTTP/1.1"
-	defaultConfigFileName  = "config"
-	defaultConfigFileType  = "yaml"
-	defaultApplicationName = "ghttp"
+	defaultServePort        = "8000"
+	defaultHTTPSServePort   = "8443"
+	defaultOnChangeDebounce = 300 * time.Millisecond
+	defaultSPAFallbackFile  = "index.html"
+	defaultProtocolVersion  = "HTTP/1.1"
+	defaultConfigFileName   = "config"
+	defaultConfigFileType   = "yaml"
+	defaultApplicationName  = "ghttp"
 
 	flagNameConfigFile         = "config"
 	flagNameBindAddress        = "bind"
@@ -40,6 +44,49 @@ const (
 	flagNameLoggingType        = "logging-type"
 	flagNameCertificateDir     = "cert-dir"
 	flagNameHTTPSHosts         = "host"
+	flagNameHTTPPort           = "http-port"
+	flagNameRedirectHTTP       = "redirect-http"
+	flagNameHSTSMaxAge         = "hsts-max-age"
+	flagNameHSTSSubdomains     = "hsts-include-subdomains"
+	flagNameHSTSPreload        = "hsts-preload"
+	flagNameUnixSocket         = "unix"
+	flagNameUnixSocketMode     = "unix-socket-mode"
+	flagNameSystemdSocket      = "systemd-socket"
+	flagNamePortFallback       = "port-fallback"
+	flagNamePortFile           = "port-file"
+	flagNameReadHeaderTimeout  = "read-header-timeout"
+	flagNameReadTimeout        = "read-timeout"
+	flagNameWriteTimeout       = "write-timeout"
+	flagNameIdleTimeout        = "idle-timeout"
+	flagNameShutdownTimeout    = "shutdown-timeout"
+	flagNameMaxHeaderBytes     = "max-header-bytes"
+	flagNameMaxConnections     = "max-connections"
+	flagNameProxyProtocol      = "proxy-protocol"
+	flagNameProxyProtocolCIDRs = "proxy-protocol-trusted"
+	flagNameLiveReload         = "live-reload"
+	flagNameOnChange           = "on-change"
+	flagNameWatchInclude       = "watch-include"
+	flagNameWatchExclude       = "watch-exclude"
+	flagNameOnChangeDebounce   = "on-change-debounce"
+	flagNameSPA                = "spa"
+	flagNameErrorPages         = "error-pages"
+	flagNameErrorPagesDir      = "error-pages-dir"
+	flagNameCleanURLs          = "clean-urls"
+	flagNameCleanURLsRedirect  = "clean-urls-redirect"
+	flagNameTrailingSlash      = "trailing-slash"
+	flagNameHostingFiles       = "hosting-files"
+	flagNameReplay             = "replay"
+	flagNameMockDirectory      = "mock-dir"
+	flagNameThrottle           = "throttle"
+	flagNameThrottleScope      = "throttle-scope"
+	flagNameInspect            = "inspect"
+	flagNameForwardConsole     = "forward-console"
+	flagNameCSP                = "csp"
+	flagNameCSPReportOnly      = "csp-report-only"
+	flagNameRecordUpstream     = "upstream"
+	flagNameRecordOutput       = "out"
+	flagNameRecordRedactHeader = "redact-header"
+	flagNameTrustedProxies     = "trusted-proxy"
 
 	configKeyServeBindAddress        = "serve.bind_address"
 	configKeyServeDirectory          = "serve.directory"
@@ -51,6 +98,52 @@ const (
 	configKeyServeBrowse             = "serve.browse"
 	configKeyServeHTTPS              = "serve.https"
 	configKeyServeLoggingType        = "serve.logging_type"
+	configKeyServeHTTPPort           = "serve.http_port"
+	configKeyServeRedirectHTTP       = "serve.redirect_http"
+	configKeyServeHSTSMaxAge         = "serve.hsts_max_age"
+	configKeyServeHSTSSubdomains     = "serve.hsts_include_subdomains"
+	configKeyServeHSTSPreload        = "serve.hsts_preload"
+	configKeyServeUnixSocket         = "serve.unix_socket"
+	configKeyServeUnixSocketMode     = "serve.unix_socket_permissions"
+	configKeyServeSystemdSocket      = "serve.systemd_socket_activation"
+	configKeyServePortFallback       = "serve.port_fallback"
+	configKeyServePortFile           = "serve.port_file"
+	configKeyServeReadHeaderTimeout  = "serve.read_header_timeout"
+	configKeyServeReadTimeout        = "serve.read_timeout"
+	configKeyServeWriteTimeout       = "serve.write_timeout"
+	configKeyServeIdleTimeout        = "serve.idle_timeout"
+	configKeyServeShutdownTimeout    = "serve.shutdown_timeout"
+	configKeyServeMaxHeaderBytes     = "serve.max_header_bytes"
+	configKeyServeMaxConnections     = "serve.max_connections"
+	configKeyServeProxyProtocol      = "serve.proxy_protocol"
+	configKeyServeProxyProtocolCIDRs = "serve.proxy_protocol_trusted_cidrs"
+	configKeyServeLiveReload         = "serve.live_reload"
+	configKeyServeOnChange           = "serve.on_change"
+	configKeyServeWatchInclude       = "serve.watch_include"
+	configKeyServeWatchExclude       = "serve.watch_exclude"
+	configKeyServeOnChangeDebounce   = "serve.on_change_debounce"
+	configKeyServeSPA                = "serve.spa"
+	configKeyServeErrorPages         = "serve.error_pages"
+	configKeyServeErrorPagesDir      = "serve.error_pages_directory"
+	configKeyServeCleanURLs          = "serve.clean_urls"
+	configKeyServeCleanURLsRedirect  = "serve.clean_urls_redirect"
+	configKeyServeTrailingSlash      = "serve.trailing_slash"
+	configKeyServeRules              = "serve.rules"
+	configKeyServeHostingFiles       = "serve.hosting_files"
+	configKeyServeProxy              = "serve.proxy"
+	configKeyServeReplay             = "serve.replay"
+	configKeyServeMockDirectory      = "serve.mock_directory"
+	configKeyServeChaos              = "serve.chaos"
+	configKeyServeThrottle           = "serve.throttle"
+	configKeyServeThrottleScope      = "serve.throttle_scope"
+	configKeyServeInspect            = "serve.inspect"
+	configKeyServeForwardConsole     = "serve.forward_console"
+	configKeyServeCSP                = "serve.csp"
+	configKeyServeCSPReportOnly      = "serve.csp_report_only"
+	configKeyRecordUpstream          = "record.upstream"
+	configKeyRecordOutputDirectory   = "record.output_directory"
+	configKeyRecordRedactHeaders     = "record.redact_headers"
+	configKeyServeTrustedProxies     = "serve.trusted_proxies"
 	configKeyHTTPSCertificateDir     = "https.certificate_directory"
 	configKeyHTTPSHosts              = "https.hosts"
 	configKeyHTTPSPort               = "https.port"
@@ -111,7 +204,7 @@ func Execute(ctx context.Context, arguments []string) int {
 	}
 	applicationConfigDir := filepath.Join(userConfigDir, defaultApplicationName)
 
-	configurationManager.SetDefault(configKeyServeBindAddress, "")
+	configurationManager.SetDefault(configKeyServeBindAddress, []string{})
 	configurationManager.SetDefault(configKeyServeDirectory, ".")
 	configurationManager.SetDefault(configKeyServeProtocol, defaultProtocolVersion)
 	configurationManager.SetDefault(configKeyServePort, defaultServePort)
@@ -121,6 +214,49 @@ func Execute(ctx context.Context, arguments []string) int {
 	configurationManager.SetDefault(configKeyServeBrowse, false)
 	configurationManager.SetDefault(configKeyServeHTTPS, false)
 	configurationManager.SetDefault(configKeyServeLoggingType, logging.TypeConsole)
+	configurationManager.SetDefault(configKeyServeHTTPPort, "")
+	configurationManager.SetDefault(configKeyServeRedirectHTTP, false)
+	configurationManager.SetDefault(configKeyServeHSTSMaxAge, time.Duration(0))
+	configurationManager.SetDefault(configKeyServeHSTSSubdomains, false)
+	configurationManager.SetDefault(configKeyServeHSTSPreload, false)
+	configurationManager.SetDefault(configKeyServeUnixSocket, "")
+	configurationManager.SetDefault(configKeyServeUnixSocketMode, "")
+	configurationManager.SetDefault(configKeyServeSystemdSocket, false)
+	configurationManager.SetDefault(configKeyServePortFallback, false)
+	configurationManager.SetDefault(configKeyServePortFile, "")
+	configurationManager.SetDefault(configKeyServeReadHeaderTimeout, server.DefaultReadHeaderTimeout)
+	configurationManager.SetDefault(configKeyServeReadTimeout, time.Duration(0))
+	configurationManager.SetDefault(configKeyServeWriteTimeout, time.Duration(0))
+	configurationManager.SetDefault(configKeyServeIdleTimeout, time.Duration(0))
+	configurationManager.SetDefault(configKeyServeShutdownTimeout, server.DefaultShutdownTimeout)
+	configurationManager.SetDefault(configKeyServeMaxHeaderBytes, 0)
+	configurationManager.SetDefault(configKeyServeMaxConnections, 0)
+	configurationManager.SetDefault(configKeyServeProxyProtocol, false)
+	configurationManager.SetDefault(configKeyServeProxyProtocolCIDRs, []string{})
+	configurationManager.SetDefault(configKeyServeTrustedProxies, []string{})
+	configurationManager.SetDefault(configKeyServeLiveReload, false)
+	configurationManager.SetDefault(configKeyServeOnChange, "")
+	configurationManager.SetDefault(configKeyServeWatchInclude, []string{})
+	configurationManager.SetDefault(configKeyServeWatchExclude, []string{})
+	configurationManager.SetDefault(configKeyServeOnChangeDebounce, defaultOnChangeDebounce)
+	configurationManager.SetDefault(configKeyServeSPA, "")
+	configurationManager.SetDefault(configKeyServeErrorPages, false)
+	configurationManager.SetDefault(configKeyServeErrorPagesDir, "")
+	configurationManager.SetDefault(configKeyServeCleanURLs, false)
+	configurationManager.SetDefault(configKeyServeCleanURLsRedirect, false)
+	configurationManager.SetDefault(configKeyServeTrailingSlash, server.TrailingSlashAsIs)
+	configurationManager.SetDefault(configKeyServeHostingFiles, false)
+	configurationManager.SetDefault(configKeyServeReplay, "")
+	configurationManager.SetDefault(configKeyServeMockDirectory, "")
+	configurationManager.SetDefault(configKeyServeThrottle, "")
+	configurationManager.SetDefault(configKeyServeThrottleScope, throttleScopeConnection)
+	configurationManager.SetDefault(configKeyServeInspect, false)
+	configurationManager.SetDefault(configKeyServeForwardConsole, false)
+	configurationManager.SetDefault(configKeyServeCSP, "")
+	configurationManager.SetDefault(configKeyServeCSPReportOnly, "")
+	configurationManager.SetDefault(configKeyRecordUpstream, "")
+	configurationManager.SetDefault(configKeyRecordOutputDirectory, defaultRecordOutputDirectory)
+	configurationManager.SetDefault(configKeyRecordRedactHeaders, []string{})
 	configurationManager.SetDefault(configKeyHTTPSCertificateDir, filepath.Join(applicationConfigDir, certificates.DefaultCertificateDirectoryName))
 	configurationManager.SetDefault(configKeyHTTPSHosts, []string{"localhost", "127.0.0.1", "::1"})
 	configurationManager.SetDefault(configKeyHTTPSPort, defaultHTTPSServePort)
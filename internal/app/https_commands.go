@@ -162,7 +162,7 @@ func runHTTPSServe(cmd *cobra.Command) error {
 		return errors.New("certificate directory type mismatch")
 	}
 
-	return executeHTTPSServe(cmd, resources, serveConfiguration, hosts, certificateDirectory)
+	return ignoreListenerHandoff(executeHTTPSServe(cmd, resources, serveConfiguration, hosts, certificateDirectory))
 }
 
 func executeHTTPSServe(cmd *cobra.Command, resources *applicationResources, serveConfiguration ServeConfiguration, hosts []string, certificateDirectory string) error {
@@ -199,19 +199,12 @@ func executeHTTPSServe(cmd *cobra.Command, resources *applicationResources, serv
 		return fmt.Errorf("parse server certificate: %w", parseErr)
 	}
 
-	fileServerConfiguration := server.FileServerConfiguration{
-		BindAddress:             serveConfiguration.BindAddress,
-		Port:                    serveConfiguration.Port,
-		DirectoryPath:           serveConfiguration.DirectoryPath,
-		ProtocolVersion:         serveConfiguration.ProtocolVersion,
-		DisableDirectoryListing: serveConfiguration.DisableDirectoryListing,
-		EnableMarkdown:          serveConfiguration.EnableMarkdown,
-		BrowseDirectories:       serveConfiguration.BrowseDirectories,
-		InitialFileRelativePath: serveConfiguration.InitialFileRelativePath,
-		LoggingType:             serveConfiguration.LoggingType,
-		TLS: &server.TLSConfiguration{
-			LoadedCertificate: &tlsCertificate,
-		},
+	fileServerConfiguration := newFileServerConfiguration(serveConfiguration)
+	fileServerConfiguration.TLS = &server.TLSConfiguration{
+		LoadedCertificate: &tlsCertificate,
+	}
+	if err := attachInheritedListeners(&fileServerConfiguration, serveConfiguration); err != nil {
+		return err
 	}
 
 	logServingHTTPSMessage(resources, certificateDirectory, hosts)
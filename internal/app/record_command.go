@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/temirov/ghttp/internal/server"
+)
+
+const defaultRecordOutputDirectory = "fixtures"
+
+func newRecordCommand(resources *applicationResources, serveFlags *pflag.FlagSet) *cobra.Command {
+	recordCommand := &cobra.Command{
+		Use:           "record [port]",
+		Short:         "Proxy every request to an upstream and save the responses for --replay",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := prepareServeConfiguration(cmd, args, configKeyServePort, true); err != nil {
+				return err
+			}
+			return prepareRecordConfiguration(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(cmd)
+		},
+	}
+
+	if serveFlags != nil {
+		recordCommand.Flags().AddFlagSet(serveFlags)
+	}
+	configurationManager := resources.configurationManager
+	recordCommand.Flags().String(flagNameRecordUpstream, configurationManager.GetString(configKeyRecordUpstream), "Upstream base URL that receives every request")
+	recordCommand.Flags().String(flagNameRecordOutput, configurationManager.GetString(configKeyRecordOutputDirectory), "Directory that receives the recorded fixtures")
+	recordCommand.Flags().StringArray(flagNameRecordRedactHeader, configurationManager.GetStringSlice(configKeyRecordRedactHeaders), "Response header removed before writing fixtures, in addition to authorization and cookie headers (repeatable)")
+	_ = configurationManager.BindPFlag(configKeyRecordUpstream, recordCommand.Flags().Lookup(flagNameRecordUpstream))
+	_ = configurationManager.BindPFlag(configKeyRecordOutputDirectory, recordCommand.Flags().Lookup(flagNameRecordOutput))
+	_ = configurationManager.BindPFlag(configKeyRecordRedactHeaders, recordCommand.Flags().Lookup(flagNameRecordRedactHeader))
+
+	return recordCommand
+}
+
+// prepareRecordConfiguration mounts the upstream at the root of the prepared
+// serve configuration and turns recording on. Mounts from serve.proxy keep
+// their longer prefixes and are recorded as well.
+func prepareRecordConfiguration(cmd *cobra.Command) error {
+	resources, err := getApplicationResources(cmd)
+	if err != nil {
+		return err
+	}
+	configurationManager := resources.configurationManager
+	serveConfiguration, ok := cmd.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+	if !ok {
+		return errors.New("serve configuration not initialized")
+	}
+	if serveConfiguration.ReplayDirectory != "" {
+		return fmt.Errorf("%s cannot be combined with record", flagNameReplay)
+	}
+
+	upstream := strings.TrimSpace(configurationManager.GetString(configKeyRecordUpstream))
+	if upstream == "" {
+		return fmt.Errorf("%s is required", flagNameRecordUpstream)
+	}
+	rootMount, mountErr := compileProxyMount(proxyMountDefinition{Path: "/", Upstream: upstream})
+	if mountErr != nil {
+		return fmt.Errorf("invalid %s: %w", flagNameRecordUpstream, mountErr)
+	}
+	for _, existingMount := range serveConfiguration.ProxyMounts {
+		if existingMount.PathPrefix == rootMount.PathPrefix {
+			return fmt.Errorf("%s conflicts with the %s mount for /", flagNameRecordUpstream, configKeyServeProxy)
+		}
+	}
+
+	outputDirectory := strings.TrimSpace(configurationManager.GetString(configKeyRecordOutputDirectory))
+	if outputDirectory == "" {
+		outputDirectory = defaultRecordOutputDirectory
+	}
+	absoluteOutputDirectory, absErr := filepath.Abs(outputDirectory)
+	if absErr != nil {
+		return fmt.Errorf("resolve %s: %w", flagNameRecordOutput, absErr)
+	}
+
+	var redactHeaders []string
+	for _, headerName := range configurationManager.GetStringSlice(configKeyRecordRedactHeaders) {
+		if trimmedName := strings.TrimSpace(headerName); trimmedName != "" {
+			redactHeaders = append(redactHeaders, trimmedName)
+		}
+	}
+
+	serveConfiguration.ProxyMounts = append(serveConfiguration.ProxyMounts, rootMount)
+	serveConfiguration.Record = &server.RecordConfiguration{Directory: absoluteOutputDirectory, RedactHeaders: redactHeaders}
+	cmd.SetContext(context.WithValue(cmd.Context(), contextKeyServeConfiguration, serveConfiguration))
+	return nil
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	pathpkg "path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+func TestPrepareRecordConfigurationMountsUpstream(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	configurationManager := viper.New()
+	configurationManager.Set(configKeyServeDirectory, temporaryDirectory)
+	configurationManager.Set(configKeyServeProtocol, "HTTP/1.1")
+	configurationManager.Set(configKeyServePort, "8000")
+	configurationManager.Set(configKeyRecordOutputDirectory, pathpkg.Join(temporaryDirectory, "fixtures"))
+	configurationManager.Set(configKeyRecordRedactHeaders, []string{" X-Api-Key ", ""})
+
+	resources := &applicationResources{
+		configurationManager: configurationManager,
+		loggingService:       logging.NewTestService(logging.TypeConsole),
+		defaultConfigDirPath: temporaryDirectory,
+	}
+	command := &cobra.Command{}
+	command.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+	if err := prepareServeConfiguration(command, nil, configKeyServePort, true); err != nil {
+		t.Fatalf("prepare serve configuration: %v", err)
+	}
+
+	if err := prepareRecordConfiguration(command); err == nil {
+		t.Fatalf("expected a missing upstream to be rejected")
+	}
+
+	configurationManager.Set(configKeyRecordUpstream, "https://staging.example")
+	if err := prepareRecordConfiguration(command); err != nil {
+		t.Fatalf("prepare record configuration: %v", err)
+	}
+	serveConfiguration, ok := command.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+	if !ok {
+		t.Fatalf("serve configuration stored with unexpected type")
+	}
+	if len(serveConfiguration.ProxyMounts) != 1 || serveConfiguration.ProxyMounts[0].PathPrefix != "/" || serveConfiguration.ProxyMounts[0].Upstream.String() != "https://staging.example" {
+		t.Fatalf("unexpected proxy mounts %+v", serveConfiguration.ProxyMounts)
+	}
+	if serveConfiguration.Record == nil || serveConfiguration.Record.Directory != pathpkg.Join(temporaryDirectory, "fixtures") {
+		t.Fatalf("unexpected record configuration %+v", serveConfiguration.Record)
+	}
+	if len(serveConfiguration.Record.RedactHeaders) != 1 || serveConfiguration.Record.RedactHeaders[0] != "X-Api-Key" {
+		t.Fatalf("unexpected redacted headers %v", serveConfiguration.Record.RedactHeaders)
+	}
+}
+
+func TestResolveOptionalDirectoryRequiresDirectory(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	if resolved, err := resolveOptionalDirectory(flagNameReplay, " "); err != nil || resolved != "" {
+		t.Fatalf("expected replay to be off, got %q, %v", resolved, err)
+	}
+	if resolved, err := resolveOptionalDirectory(flagNameReplay, temporaryDirectory); err != nil || resolved != temporaryDirectory {
+		t.Fatalf("expected %q, got %q, %v", temporaryDirectory, resolved, err)
+	}
+	if _, err := resolveOptionalDirectory(flagNameReplay, pathpkg.Join(temporaryDirectory, "missing")); err == nil {
+		t.Fatalf("expected a missing replay directory to be rejected")
+	}
+}
@@ -42,23 +42,105 @@ func newRootCommand(resources *applicationResources) *cobra.Command {
 	rootCommand.PersistentFlags().String(flagNameConfigFile, "", "Path to configuration file")
 
 	rootCommand.AddCommand(newHTTPSCommand(resources, serveFlags, httpsOptionFlags))
+	rootCommand.AddCommand(newRecordCommand(resources, serveFlags))
 
 	return rootCommand
 }
 
 func configureServeFlags(flagSet *pflag.FlagSet, configurationManager *viper.Viper) {
-	flagSet.String(flagNameBindAddress, configurationManager.GetString(configKeyServeBindAddress), "Specify bind address")
+	flagSet.StringArray(flagNameBindAddress, configurationManager.GetStringSlice(configKeyServeBindAddress), "Specify bind address (repeat to listen on several addresses)")
 	flagSet.String(flagNameDirectory, configurationManager.GetString(configKeyServeDirectory), "Serve files from this directory")
-	flagSet.String(flagNameProtocol, configurationManager.GetString(configKeyServeProtocol), "HTTP protocol version (HTTP/1.0 or HTTP/1.1)")
+	flagSet.String(flagNameProtocol, configurationManager.GetString(configKeyServeProtocol), "HTTP protocol version (HTTP/1.0, HTTP/1.1, or HTTP/2)")
 	flagSet.Bool(flagNameNoMarkdown, configurationManager.GetBool(configKeyServeNoMarkdown), "Disable Markdown rendering")
 	flagSet.Bool(flagNameBrowse, configurationManager.GetBool(configKeyServeBrowse), "Browse directories without automatic rendering")
 	flagSet.String(flagNameLoggingType, configurationManager.GetString(configKeyServeLoggingType), "Logging type (CONSOLE or JSON)")
+	flagSet.String(flagNameHTTPPort, configurationManager.GetString(configKeyServeHTTPPort), "Additional plain HTTP port served alongside HTTPS")
+	flagSet.Bool(flagNameRedirectHTTP, configurationManager.GetBool(configKeyServeRedirectHTTP), "Redirect the additional HTTP port to HTTPS with 308 responses")
+	flagSet.Duration(flagNameHSTSMaxAge, configurationManager.GetDuration(configKeyServeHSTSMaxAge), "Strict-Transport-Security max-age for HTTPS responses (0 disables the header)")
+	flagSet.Bool(flagNameHSTSSubdomains, configurationManager.GetBool(configKeyServeHSTSSubdomains), "Add includeSubDomains to the Strict-Transport-Security header")
+	flagSet.Bool(flagNameHSTSPreload, configurationManager.GetBool(configKeyServeHSTSPreload), "Add preload to the Strict-Transport-Security header")
+	flagSet.String(flagNameUnixSocket, configurationManager.GetString(configKeyServeUnixSocket), "Listen on a Unix domain socket instead of a TCP port")
+	flagSet.String(flagNameUnixSocketMode, configurationManager.GetString(configKeyServeUnixSocketMode), "Octal permissions applied to the Unix domain socket (for example 0660)")
+	flagSet.Bool(flagNameSystemdSocket, configurationManager.GetBool(configKeyServeSystemdSocket), "Serve on listeners inherited through systemd socket activation")
+	flagSet.Bool(flagNamePortFallback, configurationManager.GetBool(configKeyServePortFallback), "Probe upward for a free port when the requested port is in use")
+	flagSet.String(flagNamePortFile, configurationManager.GetString(configKeyServePortFile), "Write the URL the server listens on to this file")
+	flagSet.Duration(flagNameReadHeaderTimeout, configurationManager.GetDuration(configKeyServeReadHeaderTimeout), "Time allowed to read request headers")
+	flagSet.Duration(flagNameReadTimeout, configurationManager.GetDuration(configKeyServeReadTimeout), "Time allowed to read an entire request (0 disables the limit)")
+	flagSet.Duration(flagNameWriteTimeout, configurationManager.GetDuration(configKeyServeWriteTimeout), "Time allowed to write a response (0 disables the limit)")
+	flagSet.Duration(flagNameIdleTimeout, configurationManager.GetDuration(configKeyServeIdleTimeout), "Time an idle keep-alive connection stays open (0 falls back to the read timeout)")
+	flagSet.Duration(flagNameShutdownTimeout, configurationManager.GetDuration(configKeyServeShutdownTimeout), "Time allowed for in-flight requests to finish on shutdown")
+	flagSet.Int(flagNameMaxHeaderBytes, configurationManager.GetInt(configKeyServeMaxHeaderBytes), "Maximum size of request headers in bytes (0 uses the 1 MB default)")
+	flagSet.Int(flagNameMaxConnections, configurationManager.GetInt(configKeyServeMaxConnections), "Maximum number of concurrent connections (0 disables the limit)")
+	flagSet.Bool(flagNameProxyProtocol, configurationManager.GetBool(configKeyServeProxyProtocol), "Read PROXY protocol v1/v2 headers from trusted load balancers")
+	flagSet.StringSlice(flagNameProxyProtocolCIDRs, configurationManager.GetStringSlice(configKeyServeProxyProtocolCIDRs), "Networks (CIDR or IP) allowed to send PROXY protocol headers")
+	flagSet.StringSlice(flagNameTrustedProxies, configurationManager.GetStringSlice(configKeyServeTrustedProxies), "Reverse proxy networks (CIDR or IP) whose Forwarded and X-Forwarded-* headers are honoured")
+	flagSet.Bool(flagNameLiveReload, configurationManager.GetBool(configKeyServeLiveReload), "Reload browsers viewing HTML pages when files in the served directory change")
+	flagSet.String(flagNameOnChange, configurationManager.GetString(configKeyServeOnChange), "Shell command to run when watched files in the working directory change")
+	flagSet.StringSlice(flagNameWatchInclude, configurationManager.GetStringSlice(configKeyServeWatchInclude), "Glob patterns of files that trigger the on-change command (default: all files)")
+	flagSet.StringSlice(flagNameWatchExclude, configurationManager.GetStringSlice(configKeyServeWatchExclude), "Glob patterns of files and directories that never trigger the on-change command")
+	flagSet.String(flagNameSPA, configurationManager.GetString(configKeyServeSPA), "Serve this document for missing extensionless paths requested as HTML; a custom document must be given as --spa=FILE")
+	flagSet.Lookup(flagNameSPA).NoOptDefVal = defaultSPAFallbackFile
+	flagSet.Bool(flagNameErrorPages, configurationManager.GetBool(configKeyServeErrorPages), "Serve 404.html, 4xx.html and similar documents from the served directory for errors, or JSON to clients that prefer it")
+	flagSet.String(flagNameErrorPagesDir, configurationManager.GetString(configKeyServeErrorPagesDir), "Directory searched for error documents before the served directory (implies --error-pages)")
+	flagSet.Bool(flagNameCleanURLs, configurationManager.GetBool(configKeyServeCleanURLs), "Serve /page from page.html, or page.md when markdown is enabled")
+	flagSet.Bool(flagNameCleanURLsRedirect, configurationManager.GetBool(configKeyServeCleanURLsRedirect), "Permanently redirect /page.html to /page (requires --clean-urls)")
+	flagSet.String(flagNameTrailingSlash, configurationManager.GetString(configKeyServeTrailingSlash), "Trailing slash policy for directories and clean URLs: always, never, or as-is")
+	flagSet.Bool(flagNameHostingFiles, configurationManager.GetBool(configKeyServeHostingFiles), "Apply _redirects and _headers files from the served directory, reloading them when they change")
+	flagSet.String(flagNameMockDirectory, configurationManager.GetString(configKeyServeMockDirectory), "Answer requests from mock files such as api/users.GET.json or api/users/_id/GET.json in this directory")
+	flagSet.String(flagNameThrottle, configurationManager.GetString(configKeyServeThrottle), "Simulate a slow network with a profile (slow-3g, 3g, dsl) or down=KBPS,up=KBPS,rtt=DURATION")
+	flagSet.String(flagNameThrottleScope, configurationManager.GetString(configKeyServeThrottleScope), "Apply the --throttle bandwidth to each connection or to the whole server: connection or global")
+	flagSet.Bool(flagNameInspect, configurationManager.GetBool(configKeyServeInspect), "Serve request-inspection endpoints such as /__ghttp/echo, /__ghttp/tls and /__ghttp/status/{code}")
+	flagSet.Bool(flagNameForwardConsole, configurationManager.GetBool(configKeyServeForwardConsole), "Inject a script into HTML pages that logs browser console output, uncaught errors and unhandled rejections in the terminal")
+	flagSet.String(flagNameCSP, configurationManager.GetString(configKeyServeCSP), "Content-Security-Policy for served responses; browsers report violations to /__ghttp/csp-reports, summarized at /__ghttp/csp")
+	flagSet.String(flagNameCSPReportOnly, configurationManager.GetString(configKeyServeCSPReportOnly), "Content-Security-Policy-Report-Only policy whose violations are reported but not blocked")
+	flagSet.String(flagNameReplay, configurationManager.GetString(configKeyServeReplay), "Answer requests from fixtures saved by the record command, falling back to files on a miss")
+	flagSet.Duration(flagNameOnChangeDebounce, configurationManager.GetDuration(configKeyServeOnChangeDebounce), "Quiet period after the last change before the on-change command runs")
 	_ = configurationManager.BindPFlag(configKeyServeBindAddress, flagSet.Lookup(flagNameBindAddress))
 	_ = configurationManager.BindPFlag(configKeyServeDirectory, flagSet.Lookup(flagNameDirectory))
 	_ = configurationManager.BindPFlag(configKeyServeProtocol, flagSet.Lookup(flagNameProtocol))
 	_ = configurationManager.BindPFlag(configKeyServeNoMarkdown, flagSet.Lookup(flagNameNoMarkdown))
 	_ = configurationManager.BindPFlag(configKeyServeBrowse, flagSet.Lookup(flagNameBrowse))
 	_ = configurationManager.BindPFlag(configKeyServeLoggingType, flagSet.Lookup(flagNameLoggingType))
+	_ = configurationManager.BindPFlag(configKeyServeHTTPPort, flagSet.Lookup(flagNameHTTPPort))
+	_ = configurationManager.BindPFlag(configKeyServeRedirectHTTP, flagSet.Lookup(flagNameRedirectHTTP))
+	_ = configurationManager.BindPFlag(configKeyServeHSTSMaxAge, flagSet.Lookup(flagNameHSTSMaxAge))
+	_ = configurationManager.BindPFlag(configKeyServeHSTSSubdomains, flagSet.Lookup(flagNameHSTSSubdomains))
+	_ = configurationManager.BindPFlag(configKeyServeHSTSPreload, flagSet.Lookup(flagNameHSTSPreload))
+	_ = configurationManager.BindPFlag(configKeyServeUnixSocket, flagSet.Lookup(flagNameUnixSocket))
+	_ = configurationManager.BindPFlag(configKeyServeUnixSocketMode, flagSet.Lookup(flagNameUnixSocketMode))
+	_ = configurationManager.BindPFlag(configKeyServeSystemdSocket, flagSet.Lookup(flagNameSystemdSocket))
+	_ = configurationManager.BindPFlag(configKeyServePortFallback, flagSet.Lookup(flagNamePortFallback))
+	_ = configurationManager.BindPFlag(configKeyServePortFile, flagSet.Lookup(flagNamePortFile))
+	_ = configurationManager.BindPFlag(configKeyServeReadHeaderTimeout, flagSet.Lookup(flagNameReadHeaderTimeout))
+	_ = configurationManager.BindPFlag(configKeyServeReadTimeout, flagSet.Lookup(flagNameReadTimeout))
+	_ = configurationManager.BindPFlag(configKeyServeWriteTimeout, flagSet.Lookup(flagNameWriteTimeout))
+	_ = configurationManager.BindPFlag(configKeyServeIdleTimeout, flagSet.Lookup(flagNameIdleTimeout))
+	_ = configurationManager.BindPFlag(configKeyServeShutdownTimeout, flagSet.Lookup(flagNameShutdownTimeout))
+	_ = configurationManager.BindPFlag(configKeyServeMaxHeaderBytes, flagSet.Lookup(flagNameMaxHeaderBytes))
+	_ = configurationManager.BindPFlag(configKeyServeMaxConnections, flagSet.Lookup(flagNameMaxConnections))
+	_ = configurationManager.BindPFlag(configKeyServeProxyProtocol, flagSet.Lookup(flagNameProxyProtocol))
+	_ = configurationManager.BindPFlag(configKeyServeProxyProtocolCIDRs, flagSet.Lookup(flagNameProxyProtocolCIDRs))
+	_ = configurationManager.BindPFlag(configKeyServeTrustedProxies, flagSet.Lookup(flagNameTrustedProxies))
+	_ = configurationManager.BindPFlag(configKeyServeLiveReload, flagSet.Lookup(flagNameLiveReload))
+	_ = configurationManager.BindPFlag(configKeyServeOnChange, flagSet.Lookup(flagNameOnChange))
+	_ = configurationManager.BindPFlag(configKeyServeWatchInclude, flagSet.Lookup(flagNameWatchInclude))
+	_ = configurationManager.BindPFlag(configKeyServeWatchExclude, flagSet.Lookup(flagNameWatchExclude))
+	_ = configurationManager.BindPFlag(configKeyServeOnChangeDebounce, flagSet.Lookup(flagNameOnChangeDebounce))
+	_ = configurationManager.BindPFlag(configKeyServeSPA, flagSet.Lookup(flagNameSPA))
+	_ = configurationManager.BindPFlag(configKeyServeErrorPages, flagSet.Lookup(flagNameErrorPages))
+	_ = configurationManager.BindPFlag(configKeyServeErrorPagesDir, flagSet.Lookup(flagNameErrorPagesDir))
+	_ = configurationManager.BindPFlag(configKeyServeCleanURLs, flagSet.Lookup(flagNameCleanURLs))
+	_ = configurationManager.BindPFlag(configKeyServeCleanURLsRedirect, flagSet.Lookup(flagNameCleanURLsRedirect))
+	_ = configurationManager.BindPFlag(configKeyServeTrailingSlash, flagSet.Lookup(flagNameTrailingSlash))
+	_ = configurationManager.BindPFlag(configKeyServeHostingFiles, flagSet.Lookup(flagNameHostingFiles))
+	_ = configurationManager.BindPFlag(configKeyServeReplay, flagSet.Lookup(flagNameReplay))
+	_ = configurationManager.BindPFlag(configKeyServeMockDirectory, flagSet.Lookup(flagNameMockDirectory))
+	_ = configurationManager.BindPFlag(configKeyServeThrottle, flagSet.Lookup(flagNameThrottle))
+	_ = configurationManager.BindPFlag(configKeyServeThrottleScope, flagSet.Lookup(flagNameThrottleScope))
+	_ = configurationManager.BindPFlag(configKeyServeInspect, flagSet.Lookup(flagNameInspect))
+	_ = configurationManager.BindPFlag(configKeyServeForwardConsole, flagSet.Lookup(flagNameForwardConsole))
+	_ = configurationManager.BindPFlag(configKeyServeCSP, flagSet.Lookup(flagNameCSP))
+	_ = configurationManager.BindPFlag(configKeyServeCSPReportOnly, flagSet.Lookup(flagNameCSPReportOnly))
 }
 
 func configureServeHTTPSOptions(flagSet *pflag.FlagSet, configurationManager *viper.Viper) {
@@ -2,6 +2,9 @@ package app
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/spf13/pflag"
@@ -66,3 +69,56 @@ func TestRootCommandBindsBrowseFlag(t *testing.T) {
 		t.Fatalf("expected browse flag to bind configuration")
 	}
 }
+
+func TestRootCommandParsesSPAFlag(t *testing.T) {
+	servedDirectory := t.TempDir()
+	for _, fileName := range []string{"index.html", "app.html"} {
+		if writeErr := os.WriteFile(filepath.Join(servedDirectory, fileName), []byte("<html></html>"), 0o644); writeErr != nil {
+			t.Fatalf("write %s: %v", fileName, writeErr)
+		}
+	}
+	testCases := []struct {
+		name             string
+		arguments        []string
+		expectedFallback string
+		expectedError    string
+	}{
+		{name: "bare flag", arguments: []string{"--spa"}, expectedFallback: "index.html"},
+		{name: "document with equals", arguments: []string{"--spa=app.html"}, expectedFallback: "app.html"},
+		{name: "document as a separate argument", arguments: []string{"--spa", "app.html"}, expectedError: "use --spa=app.html"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			configurationManager := viper.New()
+			configurationManager.Set(configKeyServeProtocol, defaultProtocolVersion)
+			configurationManager.Set(configKeyServeLoggingType, logging.TypeConsole)
+			resources := &applicationResources{
+				configurationManager: configurationManager,
+				loggingService:       logging.NewTestService(logging.TypeConsole),
+				defaultConfigDirPath: t.TempDir(),
+			}
+			rootCommand := newRootCommand(resources)
+			rootCommand.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+			arguments := append([]string{"--directory", servedDirectory}, testCase.arguments...)
+			if parseErr := rootCommand.ParseFlags(arguments); parseErr != nil {
+				t.Fatalf("parse flags: %v", parseErr)
+			}
+
+			prepareErr := rootCommand.PreRunE(rootCommand, rootCommand.Flags().Args())
+			if testCase.expectedError != "" {
+				if prepareErr == nil || !strings.Contains(prepareErr.Error(), testCase.expectedError) {
+					t.Fatalf("expected an error containing %q, got %v", testCase.expectedError, prepareErr)
+				}
+				return
+			}
+			if prepareErr != nil {
+				t.Fatalf("prepare serve configuration: %v", prepareErr)
+			}
+			serveConfiguration := rootCommand.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+			if serveConfiguration.SPAFallbackRelativePath != testCase.expectedFallback || serveConfiguration.InitialFileRelativePath != "" {
+				t.Fatalf("expected fallback %q without an initial file, got %q and %q", testCase.expectedFallback, serveConfiguration.SPAFallbackRelativePath, serveConfiguration.InitialFileRelativePath)
+			}
+		})
+	}
+}
@@ -4,18 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/temirov/ghttp/internal/server"
 	"github.com/temirov/ghttp/internal/serverdetails"
+	"github.com/temirov/ghttp/internal/socketactivation"
+	"github.com/temirov/ghttp/internal/watch"
 	"github.com/temirov/ghttp/pkg/logging"
 )
 
@@ -25,6 +33,12 @@ const (
 	logMessageReceivedSignal                   = "received signal"
 )
 
+var supportedProtocolVersions = map[string]struct{}{
+	"HTTP/1.0": {},
+	"HTTP/1.1": {},
+	"HTTP/2":   {},
+}
+
 var allowedInitialServeFileExtensions = map[string]struct{}{
 	".html": {},
 	".htm":  {},
@@ -32,7 +46,7 @@ var allowedInitialServeFileExtensions = map[string]struct{}{
 }
 
 type ServeConfiguration struct {
-	BindAddress             string
+	BindAddresses           []string
 	Port                    string
 	DirectoryPath           string
 	ProtocolVersion         string
@@ -43,7 +57,36 @@ type ServeConfiguration struct {
 	EnableMarkdown          bool
 	BrowseDirectories       bool
 	InitialFileRelativePath string
+	SPAFallbackRelativePath string
+	ErrorPages              *server.ErrorPagesConfiguration
+	CleanURLs               bool
+	RedirectHTMLToCleanURLs bool
+	TrailingSlash           string
+	Rules                   []server.Rule
+	HostingFiles            bool
+	ProxyMounts             []server.ProxyMount
+	Record                  *server.RecordConfiguration
+	ReplayDirectory         string
+	MockDirectory           string
+	Chaos                   []server.ChaosRule
+	Throttle                *server.ThrottleConfiguration
+	Inspect                 bool
+	ForwardConsole          bool
+	ContentSecurityPolicy   *server.ContentSecurityPolicyConfiguration
 	LoggingType             string
+	HTTPPort                string
+	RedirectHTTPToHTTPS     bool
+	StrictTransportSecurity *server.StrictTransportSecurityConfiguration
+	UnixSocketPath          string
+	UnixSocketPermissions   os.FileMode
+	SystemdSocketActivation bool
+	PortFallback            bool
+	PortFilePath            string
+	Limits                  server.ServerLimits
+	ProxyProtocolNetworks   []netip.Prefix
+	TrustedProxies          []netip.Prefix
+	LiveReload              bool
+	OnChange                *server.OnChangeConfiguration
 }
 
 func prepareServeConfiguration(cmd *cobra.Command, args []string, portConfigKey string, allowTLSFiles bool) error {
@@ -52,8 +95,11 @@ func prepareServeConfiguration(cmd *cobra.Command, args []string, portConfigKey
 		return err
 	}
 	configurationManager := resources.configurationManager
+	if spaErr := checkSPAArgument(cmd, args); spaErr != nil {
+		return spaErr
+	}
 
-	bindAddress := strings.TrimSpace(configurationManager.GetString(configKeyServeBindAddress))
+	bindAddresses := sanitizeHosts(configurationManager.GetStringSlice(configKeyServeBindAddress))
 	directoryPath := strings.TrimSpace(configurationManager.GetString(configKeyServeDirectory))
 	if directoryPath == "" {
 		directoryPath = "."
@@ -65,7 +111,7 @@ func prepareServeConfiguration(cmd *cobra.Command, args []string, portConfigKey
 		argumentValue := strings.TrimSpace(args[0])
 		if argumentValue != "" {
 			portCandidate, parseErr := strconv.Atoi(argumentValue)
-			if parseErr == nil && portCandidate > 0 && portCandidate <= 65535 {
+			if parseErr == nil && portCandidate >= 0 && portCandidate <= 65535 {
 				portValue = argumentValue
 			} else {
 				resolvedDirectory, resolvedFile, resolveErr := resolveInitialServeFile(argumentValue)
@@ -89,8 +135,63 @@ func prepareServeConfiguration(cmd *cobra.Command, args []string, portConfigKey
 		return fmt.Errorf("path is not a directory: %s", absoluteDirectory)
 	}
 
+	spaFallbackRelativePath, spaErr := resolveSPAFallback(absoluteDirectory, configurationManager.GetString(configKeyServeSPA))
+	if spaErr != nil {
+		return spaErr
+	}
+
+	errorPages, errorPagesErr := readErrorPagesConfiguration(configurationManager)
+	if errorPagesErr != nil {
+		return errorPagesErr
+	}
+
+	cleanURLs := configurationManager.GetBool(configKeyServeCleanURLs)
+	redirectHTMLToCleanURLs := configurationManager.GetBool(configKeyServeCleanURLsRedirect)
+	if redirectHTMLToCleanURLs && !cleanURLs {
+		return fmt.Errorf("%s requires %s", flagNameCleanURLsRedirect, flagNameCleanURLs)
+	}
+	trailingSlash := strings.ToLower(strings.TrimSpace(configurationManager.GetString(configKeyServeTrailingSlash)))
+	switch trailingSlash {
+	case "":
+		trailingSlash = server.TrailingSlashAsIs
+	case server.TrailingSlashAsIs, server.TrailingSlashAlways, server.TrailingSlashNever:
+	default:
+		return fmt.Errorf("unsupported %s %s", flagNameTrailingSlash, trailingSlash)
+	}
+
+	rules, rulesErr := readRules(configurationManager)
+	if rulesErr != nil {
+		return rulesErr
+	}
+	proxyMounts, proxyMountsErr := readProxyMounts(configurationManager)
+	if proxyMountsErr != nil {
+		return proxyMountsErr
+	}
+	replayDirectory, replayErr := resolveOptionalDirectory(flagNameReplay, configurationManager.GetString(configKeyServeReplay))
+	if replayErr != nil {
+		return replayErr
+	}
+	mockDirectory, mockErr := resolveOptionalDirectory(flagNameMockDirectory, configurationManager.GetString(configKeyServeMockDirectory))
+	if mockErr != nil {
+		return mockErr
+	}
+	chaosRules, chaosErr := readChaosRules(configurationManager)
+	if chaosErr != nil {
+		return chaosErr
+	}
+	var contentSecurityPolicy *server.ContentSecurityPolicyConfiguration
+	policy := strings.TrimSpace(configurationManager.GetString(configKeyServeCSP))
+	reportOnlyPolicy := strings.TrimSpace(configurationManager.GetString(configKeyServeCSPReportOnly))
+	if policy != "" || reportOnlyPolicy != "" {
+		contentSecurityPolicy = &server.ContentSecurityPolicyConfiguration{Policy: policy, ReportOnlyPolicy: reportOnlyPolicy}
+	}
+	throttle, throttleErr := parseThrottle(configurationManager.GetString(configKeyServeThrottle), configurationManager.GetString(configKeyServeThrottleScope))
+	if throttleErr != nil {
+		return throttleErr
+	}
+
 	protocolValue := strings.ToUpper(strings.TrimSpace(configurationManager.GetString(configKeyServeProtocol)))
-	if protocolValue != "HTTP/1.0" && protocolValue != "HTTP/1.1" {
+	if _, supported := supportedProtocolVersions[protocolValue]; !supported {
 		return fmt.Errorf("unsupported protocol %s", protocolValue)
 	}
 
@@ -98,7 +199,7 @@ func prepareServeConfiguration(cmd *cobra.Command, args []string, portConfigKey
 		portValue = defaultServePort
 	}
 	portNumber, portErr := strconv.Atoi(portValue)
-	if portErr != nil || portNumber <= 0 || portNumber > 65535 {
+	if portErr != nil || portNumber < 0 || portNumber > 65535 {
 		return fmt.Errorf("invalid port %s", portValue)
 	}
 
@@ -136,12 +237,95 @@ func prepareServeConfiguration(cmd *cobra.Command, args []string, portConfigKey
 		}
 	}
 
+	tlsActive := enableDynamicHTTPS || tlsCertificatePath != "" || !allowTLSFiles
+	httpPortValue := strings.TrimSpace(configurationManager.GetString(configKeyServeHTTPPort))
+	redirectHTTPToHTTPS := configurationManager.GetBool(configKeyServeRedirectHTTP)
+	if httpPortValue != "" {
+		if !tlsActive {
+			return errors.New("http port requires https")
+		}
+		httpPortNumber, httpPortErr := strconv.Atoi(httpPortValue)
+		if httpPortErr != nil || httpPortNumber <= 0 || httpPortNumber > 65535 {
+			return fmt.Errorf("invalid http port %s", httpPortValue)
+		}
+		if httpPortValue == portValue {
+			return fmt.Errorf("http port %s collides with https port", httpPortValue)
+		}
+	}
+	if redirectHTTPToHTTPS && httpPortValue == "" {
+		return errors.New("redirect-http requires an http port")
+	}
+	var strictTransportSecurity *server.StrictTransportSecurityConfiguration
+	hstsMaxAge := configurationManager.GetDuration(configKeyServeHSTSMaxAge)
+	if hstsMaxAge < 0 {
+		return fmt.Errorf("invalid hsts max-age %s", hstsMaxAge)
+	}
+	if hstsMaxAge > 0 {
+		if !tlsActive {
+			return errors.New("hsts requires https")
+		}
+		strictTransportSecurity = &server.StrictTransportSecurityConfiguration{
+			MaxAge:            hstsMaxAge,
+			IncludeSubdomains: configurationManager.GetBool(configKeyServeHSTSSubdomains),
+			Preload:           configurationManager.GetBool(configKeyServeHSTSPreload),
+		}
+	}
+
+	unixSocketPath := strings.TrimSpace(configurationManager.GetString(configKeyServeUnixSocket))
+	if unixSocketPath != "" {
+		absoluteSocketPath, socketPathErr := filepath.Abs(unixSocketPath)
+		if socketPathErr != nil {
+			return fmt.Errorf("resolve unix socket path: %w", socketPathErr)
+		}
+		unixSocketPath = absoluteSocketPath
+	}
+	unixSocketPermissions, permissionsErr := parseFilePermissions(configurationManager.GetString(configKeyServeUnixSocketMode))
+	if permissionsErr != nil {
+		return fmt.Errorf("invalid unix socket permissions: %w", permissionsErr)
+	}
+
+	portFilePath := strings.TrimSpace(configurationManager.GetString(configKeyServePortFile))
+	if portFilePath != "" {
+		absolutePortFilePath, portFileErr := filepath.Abs(portFilePath)
+		if portFileErr != nil {
+			return fmt.Errorf("resolve port file path: %w", portFileErr)
+		}
+		portFilePath = absolutePortFilePath
+	}
+
+	limits, limitsErr := readServerLimits(configurationManager)
+	if limitsErr != nil {
+		return limitsErr
+	}
+
+	var proxyProtocolNetworks []netip.Prefix
+	if configurationManager.GetBool(configKeyServeProxyProtocol) {
+		trustedNetworks, networksErr := parseTrustedNetworks(configurationManager.GetStringSlice(configKeyServeProxyProtocolCIDRs))
+		if networksErr != nil {
+			return fmt.Errorf("invalid proxy protocol trusted networks: %w", networksErr)
+		}
+		if len(trustedNetworks) == 0 {
+			return errors.New("proxy protocol requires trusted networks")
+		}
+		proxyProtocolNetworks = trustedNetworks
+	}
+
+	trustedProxies, trustedProxiesErr := parseTrustedNetworks(configurationManager.GetStringSlice(configKeyServeTrustedProxies))
+	if trustedProxiesErr != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", trustedProxiesErr)
+	}
+
+	onChange, onChangeErr := readOnChangeConfiguration(configurationManager)
+	if onChangeErr != nil {
+		return onChangeErr
+	}
+
 	disableDirectoryListing := os.Getenv(environmentVariableDisableDirectoryListing) == "1"
 	if browseDirectories {
 		disableDirectoryListing = false
 	}
 	serveConfiguration := ServeConfiguration{
-		BindAddress:             bindAddress,
+		BindAddresses:           bindAddresses,
 		Port:                    portValue,
 		DirectoryPath:           absoluteDirectory,
 		ProtocolVersion:         protocolValue,
@@ -152,7 +336,35 @@ func prepareServeConfiguration(cmd *cobra.Command, args []string, portConfigKey
 		EnableMarkdown:          !markdownDisabled,
 		BrowseDirectories:       browseDirectories,
 		InitialFileRelativePath: initialFileRelativePath,
+		SPAFallbackRelativePath: spaFallbackRelativePath,
+		ErrorPages:              errorPages,
+		CleanURLs:               cleanURLs,
+		RedirectHTMLToCleanURLs: redirectHTMLToCleanURLs,
+		TrailingSlash:           trailingSlash,
+		Rules:                   rules,
+		HostingFiles:            configurationManager.GetBool(configKeyServeHostingFiles),
+		ProxyMounts:             proxyMounts,
+		ReplayDirectory:         replayDirectory,
+		MockDirectory:           mockDirectory,
+		Chaos:                   chaosRules,
+		Throttle:                throttle,
+		Inspect:                 configurationManager.GetBool(configKeyServeInspect),
+		ForwardConsole:          configurationManager.GetBool(configKeyServeForwardConsole),
+		ContentSecurityPolicy:   contentSecurityPolicy,
 		LoggingType:             loggingTypeValue,
+		HTTPPort:                httpPortValue,
+		RedirectHTTPToHTTPS:     redirectHTTPToHTTPS,
+		StrictTransportSecurity: strictTransportSecurity,
+		UnixSocketPath:          unixSocketPath,
+		UnixSocketPermissions:   unixSocketPermissions,
+		SystemdSocketActivation: configurationManager.GetBool(configKeyServeSystemdSocket),
+		PortFallback:            configurationManager.GetBool(configKeyServePortFallback),
+		PortFilePath:            portFilePath,
+		Limits:                  limits,
+		ProxyProtocolNetworks:   proxyProtocolNetworks,
+		TrustedProxies:          trustedProxies,
+		LiveReload:              configurationManager.GetBool(configKeyServeLiveReload),
+		OnChange:                onChange,
 	}
 
 	if loggerErr := resources.updateLogger(loggingTypeValue); loggerErr != nil {
@@ -180,30 +392,375 @@ func runServe(cmd *cobra.Command) error {
 		return serveWithDynamicHTTPS(cmd, resources, serveConfiguration)
 	}
 
-	fileServerConfiguration := server.FileServerConfiguration{
-		BindAddress:             serveConfiguration.BindAddress,
-		Port:                    serveConfiguration.Port,
-		DirectoryPath:           serveConfiguration.DirectoryPath,
-		ProtocolVersion:         serveConfiguration.ProtocolVersion,
-		DisableDirectoryListing: serveConfiguration.DisableDirectoryListing,
-		EnableMarkdown:          serveConfiguration.EnableMarkdown,
-		BrowseDirectories:       serveConfiguration.BrowseDirectories,
-		InitialFileRelativePath: serveConfiguration.InitialFileRelativePath,
-		LoggingType:             serveConfiguration.LoggingType,
-	}
+	fileServerConfiguration := newFileServerConfiguration(serveConfiguration)
 	if serveConfiguration.TLSCertificatePath != "" {
 		fileServerConfiguration.TLS = &server.TLSConfiguration{
 			CertificatePath: serveConfiguration.TLSCertificatePath,
 			PrivateKeyPath:  serveConfiguration.TLSPrivateKeyPath,
 		}
 	}
+	if err := attachInheritedListeners(&fileServerConfiguration, serveConfiguration); err != nil {
+		return err
+	}
 
 	servingAddressFormatter := serverdetails.NewServingAddressFormatter()
 	fileServerInstance := server.NewFileServer(resources.loggingService, servingAddressFormatter)
 	serveContext, cancel := createSignalContext(cmd.Context(), resources.loggingService)
 	defer cancel()
 
-	return fileServerInstance.Serve(serveContext, fileServerConfiguration)
+	return ignoreListenerHandoff(fileServerInstance.Serve(serveContext, fileServerConfiguration))
+}
+
+// ignoreListenerHandoff treats a handoff to a re-executed process as a clean
+// exit of the current one.
+func ignoreListenerHandoff(serveErr error) error {
+	if errors.Is(serveErr, server.ErrListenersHandedOff) {
+		return nil
+	}
+	return serveErr
+}
+
+// newFileServerConfiguration maps the validated serve configuration onto the
+// file server settings shared by the HTTP and HTTPS entry points.
+func newFileServerConfiguration(serveConfiguration ServeConfiguration) server.FileServerConfiguration {
+	return server.FileServerConfiguration{
+		BindAddresses:                serveConfiguration.BindAddresses,
+		Port:                         serveConfiguration.Port,
+		DirectoryPath:                serveConfiguration.DirectoryPath,
+		ProtocolVersion:              serveConfiguration.ProtocolVersion,
+		DisableDirectoryListing:      serveConfiguration.DisableDirectoryListing,
+		EnableMarkdown:               serveConfiguration.EnableMarkdown,
+		BrowseDirectories:            serveConfiguration.BrowseDirectories,
+		InitialFileRelativePath:      serveConfiguration.InitialFileRelativePath,
+		SPAFallbackRelativePath:      serveConfiguration.SPAFallbackRelativePath,
+		ErrorPages:                   serveConfiguration.ErrorPages,
+		CleanURLs:                    serveConfiguration.CleanURLs,
+		RedirectHTMLToCleanURLs:      serveConfiguration.RedirectHTMLToCleanURLs,
+		TrailingSlash:                serveConfiguration.TrailingSlash,
+		Rules:                        serveConfiguration.Rules,
+		HostingFiles:                 serveConfiguration.HostingFiles,
+		ProxyMounts:                  serveConfiguration.ProxyMounts,
+		Record:                       serveConfiguration.Record,
+		ReplayDirectory:              serveConfiguration.ReplayDirectory,
+		MockDirectory:                serveConfiguration.MockDirectory,
+		Chaos:                        serveConfiguration.Chaos,
+		Throttle:                     serveConfiguration.Throttle,
+		Inspect:                      serveConfiguration.Inspect,
+		ForwardConsole:               serveConfiguration.ForwardConsole,
+		ContentSecurityPolicy:        serveConfiguration.ContentSecurityPolicy,
+		LoggingType:                  serveConfiguration.LoggingType,
+		HTTPPort:                     serveConfiguration.HTTPPort,
+		RedirectHTTPToHTTPS:          serveConfiguration.RedirectHTTPToHTTPS,
+		StrictTransportSecurity:      serveConfiguration.StrictTransportSecurity,
+		UnixSocketPath:               serveConfiguration.UnixSocketPath,
+		UnixSocketPermissions:        serveConfiguration.UnixSocketPermissions,
+		PortFallback:                 serveConfiguration.PortFallback,
+		PortFilePath:                 serveConfiguration.PortFilePath,
+		Limits:                       serveConfiguration.Limits,
+		ProxyProtocolTrustedNetworks: serveConfiguration.ProxyProtocolNetworks,
+		TrustedProxies:               serveConfiguration.TrustedProxies,
+		LiveReload:                   serveConfiguration.LiveReload,
+		OnChange:                     serveConfiguration.OnChange,
+	}
+}
+
+// attachInheritedListeners hands listeners passed by a previous ghttp process
+// on SIGUSR2, or through systemd socket activation, to the file server.
+// Descriptors named "http" or "https" take those roles; any other name serves
+// as the primary endpoint.
+func attachInheritedListeners(fileServerConfiguration *server.FileServerConfiguration, serveConfiguration ServeConfiguration) error {
+	environment := socketactivation.NewProcessEnvironment()
+	namedListeners, handoffErr := socketactivation.HandoffListeners(environment)
+	if handoffErr != nil {
+		return fmt.Errorf("inherit handed off listeners: %w", handoffErr)
+	}
+	if len(namedListeners) > 0 {
+		fileServerConfiguration.OnReady = func() {
+			_ = socketactivation.SignalHandoffReady(environment)
+		}
+	} else {
+		if !serveConfiguration.SystemdSocketActivation {
+			return nil
+		}
+		systemdListeners, listenersErr := socketactivation.Listeners(environment)
+		if listenersErr != nil {
+			return fmt.Errorf("inherit systemd sockets: %w", listenersErr)
+		}
+		if len(systemdListeners) == 0 {
+			return errors.New("systemd socket activation enabled but no sockets were passed")
+		}
+		namedListeners = systemdListeners
+	}
+	for _, namedListener := range namedListeners {
+		fileServerConfiguration.InheritedListeners = append(fileServerConfiguration.InheritedListeners, server.InheritedListener{
+			Role:     listenerRoleForName(namedListener.Name),
+			Listener: namedListener.Listener,
+		})
+	}
+	return nil
+}
+
+// listenerRoleForName maps an inherited listener name to its role, treating
+// unknown names as primary.
+func listenerRoleForName(name string) server.ListenerRole {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case string(server.ListenerRoleHTTP):
+		return server.ListenerRoleHTTP
+	case string(server.ListenerRoleHTTPS):
+		return server.ListenerRoleHTTPS
+	default:
+		return server.ListenerRolePrimary
+	}
+}
+
+// readServerLimits collects the timeouts and connection limits, rejecting
+// negative values.
+func readServerLimits(configurationManager *viper.Viper) (server.ServerLimits, error) {
+	limits := server.ServerLimits{
+		ReadHeaderTimeout: configurationManager.GetDuration(configKeyServeReadHeaderTimeout),
+		ReadTimeout:       configurationManager.GetDuration(configKeyServeReadTimeout),
+		WriteTimeout:      configurationManager.GetDuration(configKeyServeWriteTimeout),
+		IdleTimeout:       configurationManager.GetDuration(configKeyServeIdleTimeout),
+		ShutdownTimeout:   configurationManager.GetDuration(configKeyServeShutdownTimeout),
+		MaxHeaderBytes:    configurationManager.GetInt(configKeyServeMaxHeaderBytes),
+		MaxConnections:    configurationManager.GetInt(configKeyServeMaxConnections),
+	}
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{name: flagNameReadHeaderTimeout, value: limits.ReadHeaderTimeout},
+		{name: flagNameReadTimeout, value: limits.ReadTimeout},
+		{name: flagNameWriteTimeout, value: limits.WriteTimeout},
+		{name: flagNameIdleTimeout, value: limits.IdleTimeout},
+		{name: flagNameShutdownTimeout, value: limits.ShutdownTimeout},
+	}
+	for _, duration := range durations {
+		if duration.value < 0 {
+			return server.ServerLimits{}, fmt.Errorf("invalid %s %s", duration.name, duration.value)
+		}
+	}
+	if limits.MaxHeaderBytes < 0 {
+		return server.ServerLimits{}, fmt.Errorf("invalid %s %d", flagNameMaxHeaderBytes, limits.MaxHeaderBytes)
+	}
+	if limits.MaxConnections < 0 {
+		return server.ServerLimits{}, fmt.Errorf("invalid %s %d", flagNameMaxConnections, limits.MaxConnections)
+	}
+	return limits, nil
+}
+
+// checkSPAArgument rejects "--spa app.html". Because the document is
+// optional, pflag reads that as a bare --spa followed by app.html as the
+// initial file, so a custom document has to be written as --spa=app.html.
+// pflag does not tell a bare --spa apart from --spa=index.html, so the latter
+// is rejected alongside an HTML argument too.
+func checkSPAArgument(cmd *cobra.Command, args []string) error {
+	spaFlag := cmd.Flags().Lookup(flagNameSPA)
+	if spaFlag == nil || !spaFlag.Changed || spaFlag.Value.String() != spaFlag.NoOptDefVal || len(args) != 1 {
+		return nil
+	}
+	argumentValue := strings.TrimSpace(args[0])
+	if !strings.EqualFold(filepath.Ext(argumentValue), ".html") {
+		return nil
+	}
+	return fmt.Errorf("--%s serves %s when given without a value; use --%s=%s to serve %s instead", flagNameSPA, defaultSPAFallbackFile, flagNameSPA, argumentValue, argumentValue)
+}
+
+// resolveSPAFallback checks that the single-page-application fallback names a
+// file inside the served directory and returns its slash-separated path.
+func resolveSPAFallback(absoluteDirectory string, rawValue string) (string, error) {
+	fallbackValue := strings.TrimSpace(rawValue)
+	if fallbackValue == "" {
+		return "", nil
+	}
+	fallbackPath := filepath.Join(absoluteDirectory, filepath.FromSlash(fallbackValue))
+	relativePath, relativeErr := filepath.Rel(absoluteDirectory, fallbackPath)
+	if relativeErr != nil || relativePath == "." || strings.HasPrefix(relativePath, "..") {
+		return "", fmt.Errorf("spa fallback %s must be a file inside %s", fallbackValue, absoluteDirectory)
+	}
+	fallbackInfo, statErr := os.Stat(fallbackPath)
+	if statErr != nil {
+		return "", fmt.Errorf("stat spa fallback: %w", statErr)
+	}
+	if fallbackInfo.IsDir() {
+		return "", fmt.Errorf("spa fallback is a directory: %s", fallbackPath)
+	}
+	return filepath.ToSlash(relativePath), nil
+}
+
+// ruleDefinition is one entry of serve.rules in the configuration file.
+type ruleDefinition struct {
+	Name          string   `mapstructure:"name"`
+	Match         string   `mapstructure:"match"`
+	Host          string   `mapstructure:"host"`
+	Methods       []string `mapstructure:"methods"`
+	Redirect      string   `mapstructure:"redirect"`
+	Rewrite       string   `mapstructure:"rewrite"`
+	Status        int      `mapstructure:"status"`
+	PreserveQuery *bool    `mapstructure:"preserve_query"`
+}
+
+var supportedRuleRedirectStatuses = []int{http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect}
+
+// readRules compiles serve.rules. Each rule needs a match expression and
+// exactly one of redirect or rewrite; redirects default to 302.
+func readRules(configurationManager *viper.Viper) ([]server.Rule, error) {
+	var definitions []ruleDefinition
+	if decodeErr := configurationManager.UnmarshalKey(configKeyServeRules, &definitions); decodeErr != nil {
+		return nil, fmt.Errorf("invalid %s: %w", configKeyServeRules, decodeErr)
+	}
+	rules := make([]server.Rule, 0, len(definitions))
+	for definitionIndex, definition := range definitions {
+		rule, ruleErr := compileRule(definition)
+		if ruleErr != nil {
+			return nil, fmt.Errorf("invalid %s[%d]: %w", configKeyServeRules, definitionIndex, ruleErr)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// compileRule validates one serve.rules entry and compiles its patterns.
+func compileRule(definition ruleDefinition) (server.Rule, error) {
+	if definition.Match == "" {
+		return server.Rule{}, errors.New("match is required")
+	}
+	if (definition.Redirect == "") == (definition.Rewrite == "") {
+		return server.Rule{}, errors.New("exactly one of redirect or rewrite is required")
+	}
+	pattern, patternErr := regexp.Compile(definition.Match)
+	if patternErr != nil {
+		return server.Rule{}, fmt.Errorf("match: %w", patternErr)
+	}
+	rule := server.Rule{
+		Name:      strings.TrimSpace(definition.Name),
+		Pattern:   pattern,
+		Redirect:  definition.Redirect,
+		Rewrite:   definition.Rewrite,
+		Status:    definition.Status,
+		DropQuery: definition.PreserveQuery != nil && !*definition.PreserveQuery,
+	}
+	if definition.Host != "" {
+		hostPattern, hostErr := regexp.Compile(definition.Host)
+		if hostErr != nil {
+			return server.Rule{}, fmt.Errorf("host: %w", hostErr)
+		}
+		rule.Host = hostPattern
+	}
+	for _, method := range definition.Methods {
+		rule.Methods = append(rule.Methods, strings.ToUpper(strings.TrimSpace(method)))
+	}
+	if rule.Rewrite != "" {
+		if rule.Status != 0 {
+			return server.Rule{}, errors.New("status applies to redirects only")
+		}
+		if !strings.HasPrefix(rule.Rewrite, "/") {
+			return server.Rule{}, fmt.Errorf("rewrite target %s must start with /", rule.Rewrite)
+		}
+		return rule, nil
+	}
+	if rule.Status == 0 {
+		rule.Status = http.StatusFound
+	}
+	if !slices.Contains(supportedRuleRedirectStatuses, rule.Status) {
+		return server.Rule{}, fmt.Errorf("unsupported redirect status %d", rule.Status)
+	}
+	return rule, nil
+}
+
+// readErrorPagesConfiguration returns nil unless error pages are enabled,
+// either directly or by naming an error page directory.
+func readErrorPagesConfiguration(configurationManager *viper.Viper) (*server.ErrorPagesConfiguration, error) {
+	errorPagesDirectory := strings.TrimSpace(configurationManager.GetString(configKeyServeErrorPagesDir))
+	if errorPagesDirectory == "" {
+		if !configurationManager.GetBool(configKeyServeErrorPages) {
+			return nil, nil
+		}
+		return &server.ErrorPagesConfiguration{}, nil
+	}
+	absoluteDirectory, absoluteErr := filepath.Abs(errorPagesDirectory)
+	if absoluteErr != nil {
+		return nil, fmt.Errorf("resolve error pages directory: %w", absoluteErr)
+	}
+	directoryInfo, statErr := os.Stat(absoluteDirectory)
+	if statErr != nil {
+		return nil, fmt.Errorf("stat error pages directory: %w", statErr)
+	}
+	if !directoryInfo.IsDir() {
+		return nil, fmt.Errorf("error pages path is not a directory: %s", absoluteDirectory)
+	}
+	return &server.ErrorPagesConfiguration{Directory: absoluteDirectory}, nil
+}
+
+// readOnChangeConfiguration returns nil when no on-change command is set. The
+// command runs in, and watches, the current working directory.
+func readOnChangeConfiguration(configurationManager *viper.Viper) (*server.OnChangeConfiguration, error) {
+	command := strings.TrimSpace(configurationManager.GetString(configKeyServeOnChange))
+	includePatterns := configurationManager.GetStringSlice(configKeyServeWatchInclude)
+	excludePatterns := configurationManager.GetStringSlice(configKeyServeWatchExclude)
+	if command == "" {
+		if len(includePatterns) > 0 || len(excludePatterns) > 0 {
+			return nil, fmt.Errorf("%s and %s require %s", flagNameWatchInclude, flagNameWatchExclude, flagNameOnChange)
+		}
+		return nil, nil
+	}
+	for _, pattern := range append(append([]string{}, includePatterns...), excludePatterns...) {
+		if validateErr := watch.ValidateGlob(pattern); validateErr != nil {
+			return nil, validateErr
+		}
+	}
+	debounce := configurationManager.GetDuration(configKeyServeOnChangeDebounce)
+	if debounce < 0 {
+		return nil, fmt.Errorf("invalid %s %s", flagNameOnChangeDebounce, debounce)
+	}
+	workingDirectory, workingDirectoryErr := os.Getwd()
+	if workingDirectoryErr != nil {
+		return nil, fmt.Errorf("resolve working directory: %w", workingDirectoryErr)
+	}
+	return &server.OnChangeConfiguration{
+		Command:          command,
+		WorkingDirectory: workingDirectory,
+		IncludePatterns:  includePatterns,
+		ExcludePatterns:  excludePatterns,
+		Debounce:         debounce,
+	}, nil
+}
+
+// parseTrustedNetworks parses CIDR prefixes; a bare IP address is treated as
+// a single-host prefix.
+func parseTrustedNetworks(rawValues []string) ([]netip.Prefix, error) {
+	var trustedNetworks []netip.Prefix
+	for _, rawValue := range sanitizeHosts(rawValues) {
+		if strings.Contains(rawValue, "/") {
+			prefix, prefixErr := netip.ParsePrefix(rawValue)
+			if prefixErr != nil {
+				return nil, fmt.Errorf("expected CIDR or IP address, got %s", rawValue)
+			}
+			trustedNetworks = append(trustedNetworks, prefix.Masked())
+			continue
+		}
+		address, addressErr := netip.ParseAddr(rawValue)
+		if addressErr != nil {
+			return nil, fmt.Errorf("expected CIDR or IP address, got %s", rawValue)
+		}
+		address = address.Unmap()
+		trustedNetworks = append(trustedNetworks, netip.PrefixFrom(address, address.BitLen()))
+	}
+	return trustedNetworks, nil
+}
+
+// parseFilePermissions parses an octal permission string such as "0660". An
+// empty value yields zero, which leaves the permissions untouched.
+func parseFilePermissions(rawValue string) (os.FileMode, error) {
+	trimmedValue := strings.TrimSpace(rawValue)
+	if trimmedValue == "" {
+		return 0, nil
+	}
+	parsedValue, parseErr := strconv.ParseUint(trimmedValue, 8, 32)
+	if parseErr != nil || parsedValue > 0o777 {
+		return 0, fmt.Errorf("expected octal permissions, got %s", trimmedValue)
+	}
+	return os.FileMode(parsedValue), nil
 }
 
 func loadConfigurationFile(cmd *cobra.Command) error {
@@ -291,6 +848,10 @@ func serveWithDynamicHTTPS(cmd *cobra.Command, resources *applicationResources,
 	}
 
 	serveErr := executeHTTPSServe(cmd, resources, serveConfiguration, hosts, certificateDirectory)
+	if errors.Is(serveErr, server.ErrListenersHandedOff) {
+		// The re-executed process keeps serving with the installed certificate authority.
+		return nil
+	}
 	uninstallErr := runHTTPSUninstall(cmd)
 	if uninstallErr != nil {
 		if serveErr != nil {
@@ -323,3 +884,236 @@ func createSignalContext(parent context.Context, loggingService *logging.Service
 		cancel()
 	}
 }
+
+// proxyMountDefinition is one entry of serve.proxy in the configuration file.
+type proxyMountDefinition struct {
+	Path         string `mapstructure:"path"`
+	Upstream     string `mapstructure:"upstream"`
+	StripPrefix  bool   `mapstructure:"strip_prefix"`
+	PreserveHost bool   `mapstructure:"preserve_host"`
+}
+
+// readProxyMounts validates serve.proxy. Each mount needs a path starting
+// with / and an absolute http or https upstream; ws and wss upstreams are
+// accepted as aliases because WebSocket upgrades travel over HTTP.
+func readProxyMounts(configurationManager *viper.Viper) ([]server.ProxyMount, error) {
+	var definitions []proxyMountDefinition
+	if decodeErr := configurationManager.UnmarshalKey(configKeyServeProxy, &definitions); decodeErr != nil {
+		return nil, fmt.Errorf("invalid %s: %w", configKeyServeProxy, decodeErr)
+	}
+	mounts := make([]server.ProxyMount, 0, len(definitions))
+	seenPaths := map[string]bool{}
+	for definitionIndex, definition := range definitions {
+		mount, mountErr := compileProxyMount(definition)
+		if mountErr != nil {
+			return nil, fmt.Errorf("invalid %s[%d]: %w", configKeyServeProxy, definitionIndex, mountErr)
+		}
+		if seenPaths[mount.PathPrefix] {
+			return nil, fmt.Errorf("invalid %s[%d]: path %s is mounted twice", configKeyServeProxy, definitionIndex, mount.PathPrefix)
+		}
+		seenPaths[mount.PathPrefix] = true
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+// compileProxyMount validates one serve.proxy entry, normalizing its path and
+// mapping ws and wss upstreams to http and https.
+func compileProxyMount(definition proxyMountDefinition) (server.ProxyMount, error) {
+	pathPrefix := strings.TrimSpace(definition.Path)
+	if !strings.HasPrefix(pathPrefix, "/") {
+		return server.ProxyMount{}, fmt.Errorf("path %q must start with /", definition.Path)
+	}
+	if pathPrefix != "/" {
+		pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	}
+	upstream, parseErr := url.Parse(strings.TrimSpace(definition.Upstream))
+	if parseErr != nil {
+		return server.ProxyMount{}, fmt.Errorf("upstream: %w", parseErr)
+	}
+	switch strings.ToLower(upstream.Scheme) {
+	case "http", "ws":
+		upstream.Scheme = "http"
+	case "https", "wss":
+		upstream.Scheme = "https"
+	default:
+		return server.ProxyMount{}, fmt.Errorf("upstream %q must be an http or https URL", definition.Upstream)
+	}
+	if upstream.Host == "" {
+		return server.ProxyMount{}, fmt.Errorf("upstream %q has no host", definition.Upstream)
+	}
+	return server.ProxyMount{
+		PathPrefix:   pathPrefix,
+		Upstream:     upstream,
+		StripPrefix:  definition.StripPrefix,
+		PreserveHost: definition.PreserveHost,
+	}, nil
+}
+
+// chaosRuleDefinition is one entry of serve.chaos in the configuration file.
+type chaosRuleDefinition struct {
+	Name            string        `mapstructure:"name"`
+	Path            string        `mapstructure:"path"`
+	Latency         time.Duration `mapstructure:"latency"`
+	LatencyJitter   time.Duration `mapstructure:"latency_jitter"`
+	ErrorStatus     int           `mapstructure:"error_status"`
+	ErrorPercent    float64       `mapstructure:"error_percent"`
+	ResetPercent    float64       `mapstructure:"reset_percent"`
+	TruncatePercent float64       `mapstructure:"truncate_percent"`
+	Enabled         *bool         `mapstructure:"enabled"`
+}
+
+// readChaosRules validates serve.chaos. Each rule needs a path glob and at
+// least one effect, its percentages may add up to at most 100, and names must
+// be unique so the admin endpoint can address them.
+func readChaosRules(configurationManager *viper.Viper) ([]server.ChaosRule, error) {
+	var definitions []chaosRuleDefinition
+	if decodeErr := configurationManager.UnmarshalKey(configKeyServeChaos, &definitions); decodeErr != nil {
+		return nil, fmt.Errorf("invalid %s: %w", configKeyServeChaos, decodeErr)
+	}
+	rules := make([]server.ChaosRule, 0, len(definitions))
+	seenNames := map[string]bool{}
+	for definitionIndex, definition := range definitions {
+		rule, ruleErr := compileChaosRule(definition)
+		if ruleErr != nil {
+			return nil, fmt.Errorf("invalid %s[%d]: %w", configKeyServeChaos, definitionIndex, ruleErr)
+		}
+		if rule.Name != "" {
+			if seenNames[rule.Name] {
+				return nil, fmt.Errorf("invalid %s[%d]: name %s is used twice", configKeyServeChaos, definitionIndex, rule.Name)
+			}
+			seenNames[rule.Name] = true
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// compileChaosRule validates one serve.chaos entry. Rules must inject latency
+// or faults, and error_status defaults to 503.
+func compileChaosRule(definition chaosRuleDefinition) (server.ChaosRule, error) {
+	pathGlob := strings.TrimSpace(definition.Path)
+	if pathGlob == "" {
+		return server.ChaosRule{}, errors.New("path is required")
+	}
+	if validateErr := watch.ValidateGlob(pathGlob); validateErr != nil {
+		return server.ChaosRule{}, fmt.Errorf("path: %w", validateErr)
+	}
+	if definition.Latency < 0 || definition.LatencyJitter < 0 {
+		return server.ChaosRule{}, errors.New("latency must not be negative")
+	}
+	for _, percent := range []float64{definition.ErrorPercent, definition.ResetPercent, definition.TruncatePercent} {
+		if percent < 0 || percent > 100 {
+			return server.ChaosRule{}, fmt.Errorf("percentage %g must be between 0 and 100", percent)
+		}
+	}
+	if definition.ErrorPercent+definition.ResetPercent+definition.TruncatePercent > 100 {
+		return server.ChaosRule{}, errors.New("error, reset and truncate percentages add up to more than 100")
+	}
+	errorStatus := definition.ErrorStatus
+	if errorStatus == 0 {
+		errorStatus = http.StatusServiceUnavailable
+	}
+	if errorStatus < 400 || errorStatus > 599 {
+		return server.ChaosRule{}, fmt.Errorf("error_status %d must be between 400 and 599", errorStatus)
+	}
+	if definition.Latency == 0 && definition.LatencyJitter == 0 && definition.ErrorPercent == 0 && definition.ResetPercent == 0 && definition.TruncatePercent == 0 {
+		return server.ChaosRule{}, errors.New("rule has no latency or fault percentages")
+	}
+	return server.ChaosRule{
+		Name:            strings.TrimSpace(definition.Name),
+		PathGlob:        pathGlob,
+		Latency:         definition.Latency,
+		LatencyJitter:   definition.LatencyJitter,
+		ErrorStatus:     errorStatus,
+		ErrorPercent:    definition.ErrorPercent,
+		ResetPercent:    definition.ResetPercent,
+		TruncatePercent: definition.TruncatePercent,
+		Disabled:        definition.Enabled != nil && !*definition.Enabled,
+	}, nil
+}
+
+const (
+	throttleScopeConnection = "connection"
+	throttleScopeGlobal     = "global"
+	throttleKeyDownload     = "down"
+	throttleKeyUpload       = "up"
+	throttleKeyRoundTrip    = "rtt"
+)
+
+// throttleProfiles mirror the WebPageTest connectivity presets of the same
+// names.
+var throttleProfiles = map[string]server.ThrottleConfiguration{
+	"slow-3g": {DownloadKbps: 400, UploadKbps: 400, RoundTripTime: 400 * time.Millisecond},
+	"3g":      {DownloadKbps: 1600, UploadKbps: 768, RoundTripTime: 300 * time.Millisecond},
+	"dsl":     {DownloadKbps: 1500, UploadKbps: 384, RoundTripTime: 50 * time.Millisecond},
+}
+
+// parseThrottle reads --throttle as a profile name or as comma-separated
+// down, up and rtt settings such as "down=2000,up=500,rtt=80ms", with rates
+// in kilobits per second. It returns nil when throttling is off.
+func parseThrottle(rawThrottle string, rawScope string) (*server.ThrottleConfiguration, error) {
+	trimmedThrottle := strings.ToLower(strings.TrimSpace(rawThrottle))
+	if trimmedThrottle == "" {
+		return nil, nil
+	}
+	configuration, isProfile := throttleProfiles[trimmedThrottle]
+	if !isProfile {
+		for _, setting := range strings.Split(trimmedThrottle, ",") {
+			key, value, found := strings.Cut(strings.TrimSpace(setting), "=")
+			if !found {
+				return nil, fmt.Errorf("unsupported %s %s: use slow-3g, 3g, dsl, or down=KBPS,up=KBPS,rtt=DURATION", flagNameThrottle, rawThrottle)
+			}
+			switch strings.TrimSpace(key) {
+			case throttleKeyDownload, throttleKeyUpload:
+				kilobitsPerSecond, parseErr := strconv.Atoi(strings.TrimSpace(value))
+				if parseErr != nil || kilobitsPerSecond <= 0 {
+					return nil, fmt.Errorf("invalid %s %s: %s must be a positive number of kilobits per second", flagNameThrottle, rawThrottle, key)
+				}
+				if key == throttleKeyDownload {
+					configuration.DownloadKbps = kilobitsPerSecond
+				} else {
+					configuration.UploadKbps = kilobitsPerSecond
+				}
+			case throttleKeyRoundTrip:
+				roundTripTime, parseErr := time.ParseDuration(strings.TrimSpace(value))
+				if parseErr != nil || roundTripTime < 0 {
+					return nil, fmt.Errorf("invalid %s %s: rtt must be a duration such as 100ms", flagNameThrottle, rawThrottle)
+				}
+				configuration.RoundTripTime = roundTripTime
+			default:
+				return nil, fmt.Errorf("invalid %s %s: unknown setting %s", flagNameThrottle, rawThrottle, key)
+			}
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(rawScope)) {
+	case throttleScopeConnection, "":
+	case throttleScopeGlobal:
+		configuration.Global = true
+	default:
+		return nil, fmt.Errorf("unsupported %s %s", flagNameThrottleScope, rawScope)
+	}
+	return &configuration, nil
+}
+
+// resolveOptionalDirectory returns the absolute path of a directory given to
+// a flag such as --replay, which must exist, or an empty string when the flag
+// is unset.
+func resolveOptionalDirectory(flagName string, rawDirectory string) (string, error) {
+	trimmedDirectory := strings.TrimSpace(rawDirectory)
+	if trimmedDirectory == "" {
+		return "", nil
+	}
+	absoluteDirectory, absErr := filepath.Abs(trimmedDirectory)
+	if absErr != nil {
+		return "", fmt.Errorf("resolve %s: %w", flagName, absErr)
+	}
+	directoryInfo, statErr := os.Stat(absoluteDirectory)
+	if statErr != nil {
+		return "", fmt.Errorf("%s directory: %w", flagName, statErr)
+	}
+	if !directoryInfo.IsDir() {
+		return "", fmt.Errorf("%s %s is not a directory", flagName, absoluteDirectory)
+	}
+	return absoluteDirectory, nil
+}
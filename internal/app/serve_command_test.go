@@ -2,14 +2,17 @@ package app
 
 import (
 	"context"
+	"net/http"
 	"os"
 	pathpkg "path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/temirov/ghttp/internal/server"
 	"github.com/temirov/ghttp/pkg/logging"
 )
 
@@ -187,3 +190,563 @@ func TestPrepareServeConfigurationAcceptsInitialFileArgument(t *testing.T) {
 		t.Fatalf("expected default port %s, got %s", defaultServePort, serveConfiguration.Port)
 	}
 }
+
+func TestPrepareServeConfigurationAcceptsHTTP2Protocol(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	configurationManager := viper.New()
+	configurationManager.Set(configKeyServeDirectory, temporaryDirectory)
+	configurationManager.Set(configKeyServeProtocol, "http/2")
+	configurationManager.Set(configKeyServePort, "8000")
+
+	resources := &applicationResources{
+		configurationManager: configurationManager,
+		loggingService:       logging.NewTestService(logging.TypeConsole),
+		defaultConfigDirPath: temporaryDirectory,
+	}
+
+	command := &cobra.Command{}
+	command.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+
+	err := prepareServeConfiguration(command, nil, configKeyServePort, true)
+	if err != nil {
+		t.Fatalf("prepare serve configuration: %v", err)
+	}
+
+	serveConfiguration, ok := command.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+	if !ok {
+		t.Fatalf("serve configuration stored with unexpected type")
+	}
+	if serveConfiguration.ProtocolVersion != "HTTP/2" {
+		t.Fatalf("expected HTTP/2 protocol, got %s", serveConfiguration.ProtocolVersion)
+	}
+}
+
+func TestPrepareServeConfigurationRejectsHTTPPortWithoutHTTPS(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	configurationManager := viper.New()
+	configurationManager.Set(configKeyServeDirectory, temporaryDirectory)
+	configurationManager.Set(configKeyServeProtocol, "HTTP/1.1")
+	configurationManager.Set(configKeyServePort, "8000")
+	configurationManager.Set(configKeyServeHTTPPort, "8080")
+
+	resources := &applicationResources{
+		configurationManager: configurationManager,
+		loggingService:       logging.NewTestService(logging.TypeConsole),
+		defaultConfigDirPath: temporaryDirectory,
+	}
+
+	command := &cobra.Command{}
+	command.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+
+	err := prepareServeConfiguration(command, nil, configKeyServePort, true)
+	if err == nil {
+		t.Fatalf("expected error when http port is configured without https")
+	}
+	if !strings.Contains(err.Error(), "http port requires https") {
+		t.Fatalf("unexpected error message: %s", err.Error())
+	}
+}
+
+func TestPrepareServeConfigurationConfiguresRedirectAndHSTSWithHTTPS(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	configurationManager := viper.New()
+	configurationManager.Set(configKeyServeDirectory, temporaryDirectory)
+	configurationManager.Set(configKeyServeProtocol, "HTTP/1.1")
+	configurationManager.Set(configKeyServePort, "8443")
+	configurationManager.Set(configKeyServeHTTPS, true)
+	configurationManager.Set(configKeyServeHTTPPort, "8080")
+	configurationManager.Set(configKeyServeRedirectHTTP, true)
+	configurationManager.Set(configKeyServeHSTSMaxAge, "1h")
+
+	resources := &applicationResources{
+		configurationManager: configurationManager,
+		loggingService:       logging.NewTestService(logging.TypeConsole),
+		defaultConfigDirPath: temporaryDirectory,
+	}
+
+	command := &cobra.Command{}
+	command.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+
+	err := prepareServeConfiguration(command, nil, configKeyServePort, true)
+	if err != nil {
+		t.Fatalf("prepare serve configuration: %v", err)
+	}
+
+	serveConfiguration, ok := command.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+	if !ok {
+		t.Fatalf("serve configuration stored with unexpected type")
+	}
+	if serveConfiguration.HTTPPort != "8080" || !serveConfiguration.RedirectHTTPToHTTPS {
+		t.Fatalf("expected redirecting http port 8080, got %+v", serveConfiguration)
+	}
+	if serveConfiguration.StrictTransportSecurity == nil {
+		t.Fatalf("expected strict transport security to be configured")
+	}
+	if headerValue := serveConfiguration.StrictTransportSecurity.HeaderValue(); headerValue != "max-age=3600" {
+		t.Fatalf("unexpected strict transport security header %s", headerValue)
+	}
+}
+
+func TestPrepareServeConfigurationReadsServerLimits(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	configurationManager := viper.New()
+	configurationManager.Set(configKeyServeDirectory, temporaryDirectory)
+	configurationManager.Set(configKeyServeProtocol, "HTTP/1.1")
+	configurationManager.Set(configKeyServePort, "8000")
+	configurationManager.Set(configKeyServeReadTimeout, "30s")
+	configurationManager.Set(configKeyServeShutdownTimeout, "1m")
+	configurationManager.Set(configKeyServeMaxHeaderBytes, 8192)
+	configurationManager.Set(configKeyServeMaxConnections, 64)
+
+	resources := &applicationResources{
+		configurationManager: configurationManager,
+		loggingService:       logging.NewTestService(logging.TypeConsole),
+		defaultConfigDirPath: temporaryDirectory,
+	}
+
+	command := &cobra.Command{}
+	command.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+
+	if err := prepareServeConfiguration(command, nil, configKeyServePort, true); err != nil {
+		t.Fatalf("prepare serve configuration: %v", err)
+	}
+	serveConfiguration, ok := command.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+	if !ok {
+		t.Fatalf("serve configuration stored with unexpected type")
+	}
+	limits := serveConfiguration.Limits
+	if limits.ReadTimeout != 30*time.Second || limits.ShutdownTimeout != time.Minute {
+		t.Fatalf("unexpected timeouts %+v", limits)
+	}
+	if limits.MaxHeaderBytes != 8192 || limits.MaxConnections != 64 {
+		t.Fatalf("unexpected limits %+v", limits)
+	}
+}
+
+func TestPrepareServeConfigurationRejectsNegativeLimits(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	configurationManager := viper.New()
+	configurationManager.Set(configKeyServeDirectory, temporaryDirectory)
+	configurationManager.Set(configKeyServeProtocol, "HTTP/1.1")
+	configurationManager.Set(configKeyServePort, "8000")
+	configurationManager.Set(configKeyServeMaxConnections, -1)
+
+	resources := &applicationResources{
+		configurationManager: configurationManager,
+		loggingService:       logging.NewTestService(logging.TypeConsole),
+		defaultConfigDirPath: temporaryDirectory,
+	}
+
+	command := &cobra.Command{}
+	command.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+
+	err := prepareServeConfiguration(command, nil, configKeyServePort, true)
+	if err == nil || !strings.Contains(err.Error(), flagNameMaxConnections) {
+		t.Fatalf("expected max-connections error, got %v", err)
+	}
+}
+
+func TestParseTrustedNetworks(t *testing.T) {
+	trustedNetworks, err := parseTrustedNetworks([]string{"10.0.0.0/8", " 192.0.2.7 ", "2001:db8::/32", "10.1.2.3/8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"10.0.0.0/8", "192.0.2.7/32", "2001:db8::/32", "10.0.0.0/8"}
+	if len(trustedNetworks) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, trustedNetworks)
+	}
+	for index, prefix := range trustedNetworks {
+		if prefix.String() != expected[index] {
+			t.Fatalf("expected %v, got %v", expected, trustedNetworks)
+		}
+	}
+	if _, err := parseTrustedNetworks([]string{"load-balancer"}); err == nil {
+		t.Fatalf("expected error for hostname")
+	}
+}
+
+func TestPrepareServeConfigurationRequiresProxyProtocolNetworks(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	configurationManager := viper.New()
+	configurationManager.Set(configKeyServeDirectory, temporaryDirectory)
+	configurationManager.Set(configKeyServeProtocol, "HTTP/1.1")
+	configurationManager.Set(configKeyServePort, "8000")
+	configurationManager.Set(configKeyServeProxyProtocol, true)
+
+	resources := &applicationResources{
+		configurationManager: configurationManager,
+		loggingService:       logging.NewTestService(logging.TypeConsole),
+		defaultConfigDirPath: temporaryDirectory,
+	}
+
+	command := &cobra.Command{}
+	command.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+
+	if err := prepareServeConfiguration(command, nil, configKeyServePort, true); err == nil {
+		t.Fatalf("expected proxy protocol without trusted networks to be rejected")
+	}
+
+	configurationManager.Set(configKeyServeProxyProtocolCIDRs, []string{"10.0.0.0/8"})
+	if err := prepareServeConfiguration(command, nil, configKeyServePort, true); err != nil {
+		t.Fatalf("prepare serve configuration: %v", err)
+	}
+	serveConfiguration, ok := command.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+	if !ok {
+		t.Fatalf("serve configuration stored with unexpected type")
+	}
+	if len(serveConfiguration.ProxyProtocolNetworks) != 1 {
+		t.Fatalf("expected one trusted network, got %v", serveConfiguration.ProxyProtocolNetworks)
+	}
+}
+
+func TestReadOnChangeConfiguration(t *testing.T) {
+	configurationManager := viper.New()
+	configurationManager.SetDefault(configKeyServeOnChangeDebounce, defaultOnChangeDebounce)
+
+	onChange, readErr := readOnChangeConfiguration(configurationManager)
+	if readErr != nil || onChange != nil {
+		t.Fatalf("expected no on-change configuration, got %+v, %v", onChange, readErr)
+	}
+
+	configurationManager.Set(configKeyServeWatchInclude, []string{"src/**"})
+	if _, readErr = readOnChangeConfiguration(configurationManager); readErr == nil {
+		t.Fatalf("expected include patterns without a command to be rejected")
+	}
+
+	configurationManager.Set(configKeyServeOnChange, "make build")
+	onChange, readErr = readOnChangeConfiguration(configurationManager)
+	if readErr != nil {
+		t.Fatalf("read on-change configuration: %v", readErr)
+	}
+	if onChange.Command != "make build" || onChange.Debounce != defaultOnChangeDebounce || onChange.WorkingDirectory == "" {
+		t.Fatalf("unexpected on-change configuration %+v", onChange)
+	}
+
+	configurationManager.Set(configKeyServeWatchExclude, []string{"[a-"})
+	if _, readErr = readOnChangeConfiguration(configurationManager); readErr == nil {
+		t.Fatalf("expected a malformed glob to be rejected")
+	}
+}
+
+func TestResolveSPAFallback(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	if writeErr := os.WriteFile(pathpkg.Join(temporaryDirectory, "index.html"), []byte("<html></html>"), 0o644); writeErr != nil {
+		t.Fatalf("write index: %v", writeErr)
+	}
+
+	if resolvedPath, resolveErr := resolveSPAFallback(temporaryDirectory, ""); resolveErr != nil || resolvedPath != "" {
+		t.Fatalf("expected the fallback to stay disabled, got %q, %v", resolvedPath, resolveErr)
+	}
+	if resolvedPath, resolveErr := resolveSPAFallback(temporaryDirectory, defaultSPAFallbackFile); resolveErr != nil || resolvedPath != "index.html" {
+		t.Fatalf("expected index.html, got %q, %v", resolvedPath, resolveErr)
+	}
+	for _, invalidValue := range []string{"missing.html", "../index.html", "."} {
+		if _, resolveErr := resolveSPAFallback(temporaryDirectory, invalidValue); resolveErr == nil {
+			t.Fatalf("expected %q to be rejected", invalidValue)
+		}
+	}
+}
+
+func TestReadErrorPagesConfiguration(t *testing.T) {
+	configurationManager := viper.New()
+	if errorPages, readErr := readErrorPagesConfiguration(configurationManager); readErr != nil || errorPages != nil {
+		t.Fatalf("expected error pages to stay disabled, got %+v, %v", errorPages, readErr)
+	}
+
+	configurationManager.Set(configKeyServeErrorPages, true)
+	if errorPages, readErr := readErrorPagesConfiguration(configurationManager); readErr != nil || errorPages == nil || errorPages.Directory != "" {
+		t.Fatalf("expected error pages from the served directory, got %+v, %v", errorPages, readErr)
+	}
+
+	errorPagesDirectory := t.TempDir()
+	configurationManager.Set(configKeyServeErrorPages, false)
+	configurationManager.Set(configKeyServeErrorPagesDir, errorPagesDirectory)
+	if errorPages, readErr := readErrorPagesConfiguration(configurationManager); readErr != nil || errorPages == nil || errorPages.Directory != errorPagesDirectory {
+		t.Fatalf("expected the configured directory to enable error pages, got %+v, %v", errorPages, readErr)
+	}
+
+	configurationManager.Set(configKeyServeErrorPagesDir, pathpkg.Join(errorPagesDirectory, "missing"))
+	if _, readErr := readErrorPagesConfiguration(configurationManager); readErr == nil {
+		t.Fatalf("expected a missing directory to be rejected")
+	}
+}
+
+func TestPrepareServeConfigurationValidatesCleanURLs(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	configurationManager := viper.New()
+	configurationManager.Set(configKeyServeDirectory, temporaryDirectory)
+	configurationManager.Set(configKeyServeProtocol, "HTTP/1.1")
+	configurationManager.Set(configKeyServePort, "8000")
+	configurationManager.Set(configKeyServeCleanURLsRedirect, true)
+
+	resources := &applicationResources{
+		configurationManager: configurationManager,
+		loggingService:       logging.NewTestService(logging.TypeConsole),
+		defaultConfigDirPath: temporaryDirectory,
+	}
+
+	command := &cobra.Command{}
+	command.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+
+	if err := prepareServeConfiguration(command, nil, configKeyServePort, true); err == nil {
+		t.Fatalf("expected the html redirect without clean urls to be rejected")
+	}
+
+	configurationManager.Set(configKeyServeCleanURLs, true)
+	configurationManager.Set(configKeyServeTrailingSlash, "sometimes")
+	if err := prepareServeConfiguration(command, nil, configKeyServePort, true); err == nil || !strings.Contains(err.Error(), flagNameTrailingSlash) {
+		t.Fatalf("expected an unsupported trailing slash policy to be rejected, got %v", err)
+	}
+
+	configurationManager.Set(configKeyServeTrailingSlash, "Never")
+	if err := prepareServeConfiguration(command, nil, configKeyServePort, true); err != nil {
+		t.Fatalf("prepare serve configuration: %v", err)
+	}
+	serveConfiguration, ok := command.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+	if !ok {
+		t.Fatalf("serve configuration stored with unexpected type")
+	}
+	if !serveConfiguration.CleanURLs || !serveConfiguration.RedirectHTMLToCleanURLs || serveConfiguration.TrailingSlash != server.TrailingSlashNever {
+		t.Fatalf("unexpected clean url configuration %+v", serveConfiguration)
+	}
+}
+
+func TestReadRulesFromConfiguration(t *testing.T) {
+	configurationManager := viper.New()
+	configurationManager.SetConfigType("yaml")
+	configuration := `
+serve:
+  rules:
+    - match: "^/old/(.*)"
+      redirect: "/new/$1"
+      status: 301
+    - name: fixtures
+      match: "^/api/v1/"
+      rewrite: "/fixtures/v1/"
+      methods: [get, head]
+      preserve_query: false
+`
+	if readErr := configurationManager.ReadConfig(strings.NewReader(configuration)); readErr != nil {
+		t.Fatalf("read configuration: %v", readErr)
+	}
+
+	rules, rulesErr := readRules(configurationManager)
+	if rulesErr != nil {
+		t.Fatalf("read rules: %v", rulesErr)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected two rules, got %d", len(rules))
+	}
+	if rules[0].Status != 301 || rules[0].Redirect != "/new/$1" || rules[0].DropQuery {
+		t.Fatalf("unexpected redirect rule %+v", rules[0])
+	}
+	if rules[1].Name != "fixtures" || !rules[1].DropQuery || strings.Join(rules[1].Methods, ",") != "GET,HEAD" {
+		t.Fatalf("unexpected rewrite rule %+v", rules[1])
+	}
+}
+
+func TestCompileRuleRejectsInvalidDefinitions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		definition ruleDefinition
+	}{
+		{name: "missing match", definition: ruleDefinition{Redirect: "/a"}},
+		{name: "no target", definition: ruleDefinition{Match: "^/a"}},
+		{name: "both targets", definition: ruleDefinition{Match: "^/a", Redirect: "/b", Rewrite: "/c"}},
+		{name: "invalid expression", definition: ruleDefinition{Match: "(", Redirect: "/b"}},
+		{name: "status on rewrite", definition: ruleDefinition{Match: "^/a", Rewrite: "/b", Status: 301}},
+		{name: "relative rewrite", definition: ruleDefinition{Match: "^/a", Rewrite: "b"}},
+		{name: "unsupported status", definition: ruleDefinition{Match: "^/a", Redirect: "/b", Status: 200}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, compileErr := compileRule(testCase.definition); compileErr == nil {
+				t.Fatalf("expected %+v to be rejected", testCase.definition)
+			}
+		})
+	}
+}
+
+func TestReadProxyMountsFromConfiguration(t *testing.T) {
+	configurationManager := viper.New()
+	configurationManager.SetConfigType("yaml")
+	configuration := `
+serve:
+  proxy:
+    - path: /api/
+      upstream: http://127.0.0.1:3000
+      strip_prefix: true
+    - path: /socket
+      upstream: wss://backend.test/live
+      preserve_host: true
+`
+	if readErr := configurationManager.ReadConfig(strings.NewReader(configuration)); readErr != nil {
+		t.Fatalf("read configuration: %v", readErr)
+	}
+
+	mounts, mountsErr := readProxyMounts(configurationManager)
+	if mountsErr != nil {
+		t.Fatalf("read proxy mounts: %v", mountsErr)
+	}
+	if len(mounts) != 2 {
+		t.Fatalf("expected two mounts, got %d", len(mounts))
+	}
+	if mounts[0].PathPrefix != "/api" || !mounts[0].StripPrefix || mounts[0].Upstream.String() != "http://127.0.0.1:3000" {
+		t.Fatalf("unexpected api mount %+v", mounts[0])
+	}
+	if mounts[1].Upstream.String() != "https://backend.test/live" || !mounts[1].PreserveHost {
+		t.Fatalf("unexpected socket mount %+v", mounts[1])
+	}
+}
+
+func TestCompileProxyMountRejectsInvalidDefinitions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		definition proxyMountDefinition
+	}{
+		{name: "relative path", definition: proxyMountDefinition{Path: "api", Upstream: "http://127.0.0.1:3000"}},
+		{name: "missing upstream", definition: proxyMountDefinition{Path: "/api"}},
+		{name: "unsupported scheme", definition: proxyMountDefinition{Path: "/api", Upstream: "ftp://127.0.0.1"}},
+		{name: "upstream without host", definition: proxyMountDefinition{Path: "/api", Upstream: "http:///api"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, compileErr := compileProxyMount(testCase.definition); compileErr == nil {
+				t.Fatalf("expected %+v to be rejected", testCase.definition)
+			}
+		})
+	}
+}
+
+func TestReadChaosRulesFromConfiguration(t *testing.T) {
+	configurationManager := viper.New()
+	configurationManager.SetConfigType("yaml")
+	configuration := `
+serve:
+  chaos:
+    - name: flaky-api
+      path: /api/**
+      latency: 200ms
+      latency_jitter: 100ms
+      error_percent: 10
+      reset_percent: 5
+    - path: "*.js"
+      truncate_percent: 50
+      error_status: 500
+      enabled: false
+`
+	if readErr := configurationManager.ReadConfig(strings.NewReader(configuration)); readErr != nil {
+		t.Fatalf("read configuration: %v", readErr)
+	}
+
+	rules, rulesErr := readChaosRules(configurationManager)
+	if rulesErr != nil {
+		t.Fatalf("read chaos rules: %v", rulesErr)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected two rules, got %d", len(rules))
+	}
+	if rules[0].Name != "flaky-api" || rules[0].Latency != 200*time.Millisecond || rules[0].LatencyJitter != 100*time.Millisecond || rules[0].ErrorStatus != http.StatusServiceUnavailable || rules[0].ErrorPercent != 10 || rules[0].ResetPercent != 5 || rules[0].Disabled {
+		t.Fatalf("unexpected api rule %+v", rules[0])
+	}
+	if rules[1].PathGlob != "*.js" || rules[1].TruncatePercent != 50 || rules[1].ErrorStatus != http.StatusInternalServerError || !rules[1].Disabled {
+		t.Fatalf("unexpected script rule %+v", rules[1])
+	}
+}
+
+func TestCompileChaosRuleRejectsInvalidDefinitions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		definition chaosRuleDefinition
+	}{
+		{name: "missing path", definition: chaosRuleDefinition{ErrorPercent: 10}},
+		{name: "malformed path", definition: chaosRuleDefinition{Path: "api/[a-", ErrorPercent: 10}},
+		{name: "no effect", definition: chaosRuleDefinition{Path: "/api/**"}},
+		{name: "negative latency", definition: chaosRuleDefinition{Path: "/api/**", Latency: -time.Second}},
+		{name: "percentage above 100", definition: chaosRuleDefinition{Path: "/api/**", ErrorPercent: 120}},
+		{name: "percentages above 100 in total", definition: chaosRuleDefinition{Path: "/api/**", ErrorPercent: 60, ResetPercent: 50}},
+		{name: "non-error status", definition: chaosRuleDefinition{Path: "/api/**", ErrorStatus: http.StatusOK, ErrorPercent: 10}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, compileErr := compileChaosRule(testCase.definition); compileErr == nil {
+				t.Fatalf("expected %+v to be rejected", testCase.definition)
+			}
+		})
+	}
+}
+
+func TestParseThrottle(t *testing.T) {
+	testCases := []struct {
+		name          string
+		throttle      string
+		scope         string
+		expected      *server.ThrottleConfiguration
+		expectedError bool
+	}{
+		{name: "disabled", throttle: "", scope: throttleScopeConnection},
+		{name: "profile", throttle: "3G", scope: throttleScopeConnection, expected: &server.ThrottleConfiguration{DownloadKbps: 1600, UploadKbps: 768, RoundTripTime: 300 * time.Millisecond}},
+		{name: "global profile", throttle: "slow-3g", scope: throttleScopeGlobal, expected: &server.ThrottleConfiguration{DownloadKbps: 400, UploadKbps: 400, RoundTripTime: 400 * time.Millisecond, Global: true}},
+		{name: "custom", throttle: "down=2000, rtt=80ms", scope: throttleScopeConnection, expected: &server.ThrottleConfiguration{DownloadKbps: 2000, RoundTripTime: 80 * time.Millisecond}},
+		{name: "unknown profile", throttle: "5g", scope: throttleScopeConnection, expectedError: true},
+		{name: "unknown setting", throttle: "down=100,loss=5", scope: throttleScopeConnection, expectedError: true},
+		{name: "invalid rate", throttle: "down=0", scope: throttleScopeConnection, expectedError: true},
+		{name: "invalid rtt", throttle: "rtt=slow", scope: throttleScopeConnection, expectedError: true},
+		{name: "invalid scope", throttle: "dsl", scope: "host", expectedError: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			throttle, parseErr := parseThrottle(testCase.throttle, testCase.scope)
+			if testCase.expectedError {
+				if parseErr == nil {
+					t.Fatalf("expected %q to be rejected", testCase.throttle)
+				}
+				return
+			}
+			if parseErr != nil {
+				t.Fatalf("parse throttle: %v", parseErr)
+			}
+			if (throttle == nil) != (testCase.expected == nil) || (throttle != nil && *throttle != *testCase.expected) {
+				t.Fatalf("expected %+v, got %+v", testCase.expected, throttle)
+			}
+		})
+	}
+}
+
+func TestPrepareServeConfigurationReadsContentSecurityPolicy(t *testing.T) {
+	temporaryDirectory := t.TempDir()
+	configurationManager := viper.New()
+	configurationManager.Set(configKeyServeDirectory, temporaryDirectory)
+	configurationManager.Set(configKeyServeProtocol, "HTTP/1.1")
+	configurationManager.Set(configKeyServePort, "8000")
+
+	resources := &applicationResources{
+		configurationManager: configurationManager,
+		loggingService:       logging.NewTestService(logging.TypeConsole),
+		defaultConfigDirPath: temporaryDirectory,
+	}
+	command := &cobra.Command{}
+	command.SetContext(context.WithValue(context.Background(), contextKeyApplicationResources, resources))
+
+	if err := prepareServeConfiguration(command, nil, configKeyServePort, true); err != nil {
+		t.Fatalf("prepare serve configuration: %v", err)
+	}
+	serveConfiguration := command.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+	if serveConfiguration.ContentSecurityPolicy != nil {
+		t.Fatalf("expected no content security policy, got %+v", serveConfiguration.ContentSecurityPolicy)
+	}
+
+	configurationManager.Set(configKeyServeCSPReportOnly, " default-src 'self' ")
+	if err := prepareServeConfiguration(command, nil, configKeyServePort, true); err != nil {
+		t.Fatalf("prepare serve configuration: %v", err)
+	}
+	serveConfiguration = command.Context().Value(contextKeyServeConfiguration).(ServeConfiguration)
+	expectedPolicy := server.ContentSecurityPolicyConfiguration{ReportOnlyPolicy: "default-src 'self'"}
+	if serveConfiguration.ContentSecurityPolicy == nil || *serveConfiguration.ContentSecurityPolicy != expectedPolicy {
+		t.Fatalf("expected %+v, got %+v", expectedPolicy, serveConfiguration.ContentSecurityPolicy)
+	}
+}
@@ -0,0 +1,85 @@
+package hostingfiles
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// HeadersFileName is the file that declares per-path response headers.
+const HeadersFileName = "_headers"
+
+const headerNameSeparator = ":"
+
+// HeaderRule attaches headers to responses for paths matching Path. A
+// _headers file lists a path pattern on an unindented line followed by
+// indented "Name: value" lines.
+type HeaderRule struct {
+	Path    PathPattern
+	Headers http.Header
+}
+
+// ParseHeaders reads a _headers file. Blank lines and lines starting with "#"
+// are ignored. Lines that cannot be parsed are skipped and reported as line
+// errors; an invalid path also drops the header lines beneath it. The error is
+// reserved for failures to read the file.
+func ParseHeaders(reader io.Reader) ([]HeaderRule, []LineError, error) {
+	var headerRules []HeaderRule
+	var lineErrors []LineError
+	skippingBlock := false
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		trimmedLine := strings.TrimSpace(line)
+		if trimmedLine == "" || strings.HasPrefix(trimmedLine, commentPrefix) {
+			continue
+		}
+		if !unicode.IsSpace(rune(line[0])) {
+			skippingBlock = !strings.HasPrefix(trimmedLine, "/")
+			if skippingBlock {
+				lineErrors = append(lineErrors, LineError{File: HeadersFileName, Line: lineNumber, Err: fmt.Errorf("path %q must start with /", trimmedLine)})
+				continue
+			}
+			headerRules = append(headerRules, HeaderRule{Path: ParsePathPattern(trimmedLine), Headers: http.Header{}})
+			continue
+		}
+		if skippingBlock {
+			continue
+		}
+		if len(headerRules) == 0 {
+			lineErrors = append(lineErrors, LineError{File: HeadersFileName, Line: lineNumber, Err: fmt.Errorf("header %q precedes any path", trimmedLine)})
+			continue
+		}
+		name, value, found := strings.Cut(trimmedLine, headerNameSeparator)
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsFunc(name, unicode.IsSpace) {
+			lineErrors = append(lineErrors, LineError{File: HeadersFileName, Line: lineNumber, Err: fmt.Errorf("expected \"Name: value\", got %q", trimmedLine)})
+			continue
+		}
+		headerRules[len(headerRules)-1].Headers.Add(name, strings.TrimSpace(value))
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", HeadersFileName, scanErr)
+	}
+	return headerRules, lineErrors, nil
+}
+
+// HeadersFor returns the headers of every rule matching the path. Values for
+// a header named by several rules are combined in file order.
+func HeadersFor(headerRules []HeaderRule, requestPath string) http.Header {
+	matchedHeaders := http.Header{}
+	for _, headerRule := range headerRules {
+		if _, matched := headerRule.Path.Match(requestPath); !matched {
+			continue
+		}
+		for name, values := range headerRule.Headers {
+			matchedHeaders[name] = append(matchedHeaders[name], values...)
+		}
+	}
+	return matchedHeaders
+}
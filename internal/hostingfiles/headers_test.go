@@ -0,0 +1,72 @@
+package hostingfiles
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHeadersAndMatch(t *testing.T) {
+	headerRules, lineErrors, parseErr := ParseHeaders(strings.NewReader(`# site wide
+/*
+  X-Frame-Options: DENY
+  Link: </style.css>; rel=preload
+
+/assets/*
+  Cache-Control: public, max-age=31536000
+  Link: </font.woff2>; rel=preload
+`))
+	if parseErr != nil || len(lineErrors) != 0 {
+		t.Fatalf("parse headers: %v %v", parseErr, lineErrors)
+	}
+
+	assetHeaders := HeadersFor(headerRules, "/assets/app.js")
+	if assetHeaders.Get("X-Frame-Options") != "DENY" {
+		t.Fatalf("expected site wide header, got %v", assetHeaders)
+	}
+	if assetHeaders.Get("Cache-Control") != "public, max-age=31536000" {
+		t.Fatalf("expected cache header, got %v", assetHeaders)
+	}
+	if links := assetHeaders.Values("Link"); len(links) != 2 {
+		t.Fatalf("expected both link headers, got %v", links)
+	}
+
+	pageHeaders := HeadersFor(headerRules, "/about")
+	if pageHeaders.Get("Cache-Control") != "" {
+		t.Fatalf("expected no cache header for /about, got %v", pageHeaders)
+	}
+}
+
+func TestParseHeadersSkipsMalformedLines(t *testing.T) {
+	testCases := []struct {
+		name               string
+		content            string
+		expectedErrorLines []int
+		expectedHeaders    map[string]string
+	}{
+		{name: "header before path", content: "  X-Test: 1\n/*\n  X-Kept: 1\n", expectedErrorLines: []int{1}, expectedHeaders: map[string]string{"X-Kept": "1"}},
+		{name: "missing separator", content: "/*\n  X-Test 1\n  X-Kept: 1\n", expectedErrorLines: []int{2}, expectedHeaders: map[string]string{"X-Kept": "1"}},
+		{name: "relative path drops its block", content: "assets/*\n  X-Test: 1\n/*\n  X-Kept: 1\n", expectedErrorLines: []int{1}, expectedHeaders: map[string]string{"X-Kept": "1", "X-Test": ""}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			headerRules, lineErrors, parseErr := ParseHeaders(strings.NewReader(testCase.content))
+			if parseErr != nil {
+				t.Fatalf("parse headers: %v", parseErr)
+			}
+			if len(lineErrors) != len(testCase.expectedErrorLines) {
+				t.Fatalf("expected errors on lines %v, got %v", testCase.expectedErrorLines, lineErrors)
+			}
+			for index, lineError := range lineErrors {
+				if lineError.Line != testCase.expectedErrorLines[index] {
+					t.Fatalf("expected errors on lines %v, got %v", testCase.expectedErrorLines, lineErrors)
+				}
+			}
+			headers := HeadersFor(headerRules, "/assets/app.js")
+			for name, expectedValue := range testCase.expectedHeaders {
+				if headers.Get(name) != expectedValue {
+					t.Fatalf("expected %s %q, got %v", name, expectedValue, headers)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Package hostingfiles parses the _redirects and _headers files that static
+// hosting providers such as Netlify and Cloudflare Pages read from the root
+// of a site.
+package hostingfiles
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	splatSegment     = "*"
+	splatName        = "splat"
+	placeholderToken = ':'
+)
+
+// LineError describes a line that was skipped because it could not be parsed.
+type LineError struct {
+	File string
+	// Line is the one-based line number.
+	Line int
+	Err  error
+}
+
+func (lineError LineError) Error() string {
+	return fmt.Sprintf("%s line %d: %v", lineError.File, lineError.Line, lineError.Err)
+}
+
+func (lineError LineError) Unwrap() error {
+	return lineError.Err
+}
+
+// PathPattern matches request paths segment by segment. A ":name" segment
+// captures one path segment, and a trailing "*" captures the remainder of the
+// path, including nothing at all, as "splat".
+type PathPattern struct {
+	segments []string
+}
+
+// ParsePathPattern compiles a pattern such as "/news/:year/*".
+func ParsePathPattern(pattern string) PathPattern {
+	return PathPattern{segments: splitPath(pattern)}
+}
+
+// Match reports whether the path matches and returns the captured values.
+func (pattern PathPattern) Match(requestPath string) (map[string]string, bool) {
+	pathSegments := splitPath(requestPath)
+	captures := map[string]string{}
+	for segmentIndex, segment := range pattern.segments {
+		if segment == splatSegment && segmentIndex == len(pattern.segments)-1 {
+			captures[splatName] = strings.Join(pathSegments[min(segmentIndex, len(pathSegments)):], "/")
+			return captures, true
+		}
+		if segmentIndex >= len(pathSegments) {
+			return nil, false
+		}
+		if len(segment) > 1 && segment[0] == placeholderToken {
+			captures[segment[1:]] = pathSegments[segmentIndex]
+			continue
+		}
+		if segment != pathSegments[segmentIndex] {
+			return nil, false
+		}
+	}
+	if len(pathSegments) != len(pattern.segments) {
+		return nil, false
+	}
+	return captures, true
+}
+
+// splitPath ignores leading and trailing slashes so that /about and /about/
+// match the same patterns.
+func splitPath(requestPath string) []string {
+	trimmedPath := strings.Trim(requestPath, "/")
+	if trimmedPath == "" {
+		return nil
+	}
+	return strings.Split(trimmedPath, "/")
+}
+
+// expandPlaceholders replaces ":name" tokens in the target with captured
+// values. Tokens without a capture are left as written.
+func expandPlaceholders(target string, captures map[string]string) string {
+	var builder strings.Builder
+	for index := 0; index < len(target); index++ {
+		if target[index] != placeholderToken {
+			builder.WriteByte(target[index])
+			continue
+		}
+		nameEnd := index + 1
+		for nameEnd < len(target) && isPlaceholderCharacter(target[nameEnd]) {
+			nameEnd++
+		}
+		value, found := captures[target[index+1:nameEnd]]
+		if nameEnd == index+1 || !found {
+			builder.WriteByte(target[index])
+			continue
+		}
+		builder.WriteString(value)
+		index = nameEnd - 1
+	}
+	return builder.String()
+}
+
+func isPlaceholderCharacter(character byte) bool {
+	return character == '_' || character == '-' ||
+		(character >= 'a' && character <= 'z') ||
+		(character >= 'A' && character <= 'Z') ||
+		(character >= '0' && character <= '9')
+}
@@ -0,0 +1,141 @@
+package hostingfiles
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RedirectsFileName is the file that declares redirect and rewrite lines.
+const RedirectsFileName = "_redirects"
+
+const (
+	commentPrefix     = "#"
+	forceSuffix       = "!"
+	queryPairOperator = "="
+)
+
+// Redirect is one line of a _redirects file:
+//
+//	/from  /to  [status][!]  [key=:placeholder ...]
+//
+// A status of 200 rewrites the request, other 2xx and 4xx statuses serve the
+// target with that status, and 3xx statuses redirect. The default status is
+// 301.
+type Redirect struct {
+	From   PathPattern
+	To     string
+	Status int
+	// Force applies the rule even when the request path names an existing
+	// file. Without it the file shadows the rule.
+	Force bool
+	// Query lists request query parameters that must be present. Values that
+	// start with ":" capture the parameter for the target.
+	Query map[string]string
+	// Line is the one-based line number in the _redirects file.
+	Line int
+}
+
+// ParseRedirects reads a _redirects file. Blank lines and lines starting with
+// "#" are ignored. Lines that cannot be parsed are skipped and reported as
+// line errors, so one unsupported line does not disable the rest of the file.
+// The error is reserved for failures to read the file.
+func ParseRedirects(reader io.Reader) ([]Redirect, []LineError, error) {
+	var redirects []Redirect
+	var lineErrors []LineError
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], commentPrefix) {
+			continue
+		}
+		redirect, parseErr := parseRedirectFields(fields)
+		if parseErr != nil {
+			lineErrors = append(lineErrors, LineError{File: RedirectsFileName, Line: lineNumber, Err: parseErr})
+			continue
+		}
+		redirect.Line = lineNumber
+		redirects = append(redirects, redirect)
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", RedirectsFileName, scanErr)
+	}
+	return redirects, lineErrors, nil
+}
+
+func parseRedirectFields(fields []string) (Redirect, error) {
+	if len(fields) < 2 {
+		return Redirect{}, fmt.Errorf("expected a source and a target, got %q", strings.Join(fields, " "))
+	}
+	if !strings.HasPrefix(fields[0], "/") {
+		return Redirect{}, fmt.Errorf("source %q must start with /", fields[0])
+	}
+	redirect := Redirect{From: ParsePathPattern(fields[0]), To: fields[1], Status: http.StatusMovedPermanently}
+	for _, field := range fields[2:] {
+		if strings.HasPrefix(field, commentPrefix) {
+			break
+		}
+		if name, value, isQuery := strings.Cut(field, queryPairOperator); isQuery {
+			if redirect.Query == nil {
+				redirect.Query = map[string]string{}
+			}
+			redirect.Query[name] = value
+			continue
+		}
+		rawStatus, forced := strings.CutSuffix(field, forceSuffix)
+		status, statusErr := strconv.Atoi(rawStatus)
+		if statusErr != nil || status < 200 || status > 599 {
+			return Redirect{}, fmt.Errorf("invalid status %q", field)
+		}
+		redirect.Status = status
+		redirect.Force = forced
+	}
+	if redirect.IsRewrite() && isAbsoluteURL(redirect.To) {
+		return Redirect{}, fmt.Errorf("rewrite target %q must be a path", redirect.To)
+	}
+	return redirect, nil
+}
+
+// IsRewrite reports whether the rule serves the target in place rather than
+// redirecting the client to it.
+func (redirect Redirect) IsRewrite() bool {
+	return redirect.Status < 300 || redirect.Status >= 400
+}
+
+// Match returns the expanded target when the rule matches the request path
+// and query. Redirect targets carry the request query over unless the rule
+// declares query parameters or the target has a query of its own.
+func (redirect Redirect) Match(requestPath string, query url.Values) (string, bool) {
+	captures, matched := redirect.From.Match(requestPath)
+	if !matched {
+		return "", false
+	}
+	for name, value := range redirect.Query {
+		if !query.Has(name) {
+			return "", false
+		}
+		if strings.HasPrefix(value, string(placeholderToken)) {
+			captures[value[1:]] = query.Get(name)
+			continue
+		}
+		if query.Get(name) != value {
+			return "", false
+		}
+	}
+	target := expandPlaceholders(redirect.To, captures)
+	if len(redirect.Query) == 0 && len(query) > 0 && !strings.Contains(target, "?") {
+		target += "?" + query.Encode()
+	}
+	return target, true
+}
+
+func isAbsoluteURL(target string) bool {
+	parsedTarget, parseErr := url.Parse(target)
+	return parseErr == nil && parsedTarget.IsAbs()
+}
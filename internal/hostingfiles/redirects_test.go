@@ -0,0 +1,99 @@
+package hostingfiles
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const sampleRedirectsFile = `# comment
+/home              /
+/news/:year/:slug  /blog/:year/:slug  302
+/docs/*            /guide/:splat      200!
+/store             /shop/:id          301  id=:id
+/*                 /404.html          404
+`
+
+func TestParseRedirects(t *testing.T) {
+	redirects, lineErrors, parseErr := ParseRedirects(strings.NewReader(sampleRedirectsFile))
+	if parseErr != nil || len(lineErrors) != 0 {
+		t.Fatalf("parse redirects: %v %v", parseErr, lineErrors)
+	}
+	if len(redirects) != 5 {
+		t.Fatalf("expected 5 redirects, got %d", len(redirects))
+	}
+	if redirects[0].Status != http.StatusMovedPermanently || redirects[0].Line != 2 {
+		t.Fatalf("expected default status on line 2, got %d on line %d", redirects[0].Status, redirects[0].Line)
+	}
+	if !redirects[2].Force || !redirects[2].IsRewrite() {
+		t.Fatalf("expected forced rewrite, got %+v", redirects[2])
+	}
+	if redirects[4].Status != http.StatusNotFound || !redirects[4].IsRewrite() {
+		t.Fatalf("expected 404 rewrite, got %+v", redirects[4])
+	}
+}
+
+func TestParseRedirectsSkipsMalformedLines(t *testing.T) {
+	testCases := []struct {
+		name        string
+		invalidLine string
+	}{
+		{name: "missing target", invalidLine: "/only-source"},
+		{name: "relative source", invalidLine: "old /new"},
+		{name: "invalid status", invalidLine: "/a /b 3O1"},
+		{name: "rewrite to another origin", invalidLine: "/api/* https://example.test/:splat 200"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			redirects, lineErrors, parseErr := ParseRedirects(strings.NewReader("/kept /target\n" + testCase.invalidLine + "\n/also-kept /target\n"))
+			if parseErr != nil {
+				t.Fatalf("parse redirects: %v", parseErr)
+			}
+			if len(lineErrors) != 1 || lineErrors[0].Line != 2 || !strings.HasPrefix(lineErrors[0].Error(), "_redirects line 2: ") {
+				t.Fatalf("expected line 2 to be reported, got %v", lineErrors)
+			}
+			if len(redirects) != 2 || redirects[0].Line != 1 || redirects[1].Line != 3 {
+				t.Fatalf("expected the valid lines to be kept, got %+v", redirects)
+			}
+		})
+	}
+}
+
+func TestRedirectMatch(t *testing.T) {
+	redirects, _, parseErr := ParseRedirects(strings.NewReader(sampleRedirectsFile))
+	if parseErr != nil {
+		t.Fatalf("parse redirects: %v", parseErr)
+	}
+	testCases := []struct {
+		name           string
+		redirectIndex  int
+		target         string
+		expectedTarget string
+		expectedMatch  bool
+	}{
+		{name: "literal path with trailing slash", redirectIndex: 0, target: "/home/", expectedTarget: "/", expectedMatch: true},
+		{name: "literal path keeps query", redirectIndex: 0, target: "/home?a=1", expectedTarget: "/?a=1", expectedMatch: true},
+		{name: "placeholders", redirectIndex: 1, target: "/news/2024/launch", expectedTarget: "/blog/2024/launch", expectedMatch: true},
+		{name: "placeholders need every segment", redirectIndex: 1, target: "/news/2024", expectedMatch: false},
+		{name: "splat spans segments", redirectIndex: 2, target: "/docs/a/b.html", expectedTarget: "/guide/a/b.html", expectedMatch: true},
+		{name: "splat may be empty", redirectIndex: 2, target: "/docs", expectedTarget: "/guide/", expectedMatch: true},
+		{name: "query placeholder", redirectIndex: 3, target: "/store?id=42", expectedTarget: "/shop/42", expectedMatch: true},
+		{name: "query parameter required", redirectIndex: 3, target: "/store", expectedMatch: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			requestURL, urlErr := url.Parse(testCase.target)
+			if urlErr != nil {
+				t.Fatalf("parse target: %v", urlErr)
+			}
+			target, matched := redirects[testCase.redirectIndex].Match(requestURL.Path, requestURL.Query())
+			if matched != testCase.expectedMatch {
+				t.Fatalf("expected match %t, got %t", testCase.expectedMatch, matched)
+			}
+			if target != testCase.expectedTarget {
+				t.Fatalf("expected target %q, got %q", testCase.expectedTarget, target)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// Package mocks resolves HTTP requests to mock response files laid out by
+// method and path, such as api/users.GET.json or api/users/_id/GET.json.
+package mocks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	wildcardPrefix    = "_"
+	metaFileSuffix    = ".meta.yaml"
+	frontMatterMarker = "---"
+)
+
+// Response is a resolved mock.
+type Response struct {
+	// FilePath is the mock file, relative to the mock directory.
+	FilePath string
+	Body     []byte
+	// Status defaults to 200.
+	Status int
+	Header http.Header
+	Delay  time.Duration
+	// Parameters holds the path segments matched by wildcard names, keyed
+	// by the name without its leading underscore.
+	Parameters map[string]string
+}
+
+// Meta is the optional status, header and delay block of a mock, read from
+// a sidecar <name>.meta.yaml file or from a front matter block delimited by
+// "---" lines at the top of the mock file.
+type Meta struct {
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	Delay   string            `yaml:"delay"`
+}
+
+// Directory resolves mocks below Root.
+type Directory struct {
+	Root string
+}
+
+// Resolve finds the mock for the method and path. For /api/users it tries
+// api/users.METHOD.* and then api/users/METHOD.*. A path segment that has no
+// literal match may match a file or directory named _name instead. Literal
+// names are preferred over wildcards at every level. Resolve reports false
+// when no mock exists.
+func (directory Directory) Resolve(method string, requestPath string) (Response, bool, error) {
+	var segments []string
+	if trimmedPath := strings.Trim(pathpkg.Clean("/"+requestPath), "/"); trimmedPath != "" {
+		segments = strings.Split(trimmedPath, "/")
+	}
+	parameters := map[string]string{}
+	relativePath, found := directory.match("", segments, strings.ToUpper(method), parameters)
+	if !found {
+		return Response{}, false, nil
+	}
+	response, loadErr := directory.load(relativePath)
+	if loadErr != nil {
+		return Response{}, true, loadErr
+	}
+	response.Parameters = parameters
+	return response, true, nil
+}
+
+func (directory Directory) match(relativeDirectory string, segments []string, method string, parameters map[string]string) (string, bool) {
+	if len(segments) == 0 {
+		return directory.findMethodFile(relativeDirectory, method)
+	}
+	segment := segments[0]
+	for _, candidate := range directory.segmentCandidates(relativeDirectory, segment) {
+		candidatePath := pathpkg.Join(relativeDirectory, candidate)
+		if len(segments) == 1 {
+			if filePath, found := directory.findMethodFile(relativeDirectory, candidate+"."+method); found {
+				recordParameter(parameters, candidate, segment)
+				return filePath, true
+			}
+		}
+		if !directory.isDirectory(candidatePath) {
+			continue
+		}
+		if filePath, found := directory.match(candidatePath, segments[1:], method, parameters); found {
+			recordParameter(parameters, candidate, segment)
+			return filePath, true
+		}
+	}
+	return "", false
+}
+
+// segmentCandidates lists the literal segment followed by the wildcard names
+// present in the directory, in sorted order.
+func (directory Directory) segmentCandidates(relativeDirectory string, segment string) []string {
+	candidates := []string{segment}
+	entries, readErr := os.ReadDir(filepath.Join(directory.Root, filepath.FromSlash(relativeDirectory)))
+	if readErr != nil {
+		return candidates
+	}
+	seen := map[string]bool{segment: true}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() {
+			name, _, _ = strings.Cut(name, ".")
+		}
+		if len(name) <= len(wildcardPrefix) || !strings.HasPrefix(name, wildcardPrefix) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates[1:])
+	return candidates
+}
+
+// findMethodFile looks for <prefix>.* in the directory, where prefix is a
+// method or a name followed by a method, ignoring meta files.
+func (directory Directory) findMethodFile(relativeDirectory string, prefix string) (string, bool) {
+	entries, readErr := os.ReadDir(filepath.Join(directory.Root, filepath.FromSlash(relativeDirectory)))
+	if readErr != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasSuffix(name, metaFileSuffix) {
+			continue
+		}
+		if name == prefix || strings.HasPrefix(name, prefix+".") {
+			return pathpkg.Join(relativeDirectory, name), true
+		}
+	}
+	return "", false
+}
+
+func (directory Directory) isDirectory(relativePath string) bool {
+	fileInfo, statErr := os.Stat(filepath.Join(directory.Root, filepath.FromSlash(relativePath)))
+	return statErr == nil && fileInfo.IsDir()
+}
+
+func (directory Directory) load(relativePath string) (Response, error) {
+	absolutePath := filepath.Join(directory.Root, filepath.FromSlash(relativePath))
+	content, readErr := os.ReadFile(absolutePath)
+	if readErr != nil {
+		return Response{}, fmt.Errorf("read mock %s: %w", relativePath, readErr)
+	}
+	response := Response{FilePath: relativePath, Body: content, Status: http.StatusOK, Header: http.Header{}}
+
+	var meta Meta
+	body, hasFrontMatter, frontMatterErr := splitFrontMatter(content, &meta)
+	if frontMatterErr != nil {
+		return Response{}, fmt.Errorf("mock %s: %w", relativePath, frontMatterErr)
+	}
+	if hasFrontMatter {
+		response.Body = body
+	} else {
+		metaPath := strings.TrimSuffix(absolutePath, filepath.Ext(absolutePath)) + metaFileSuffix
+		metaContent, metaErr := os.ReadFile(metaPath)
+		if metaErr != nil && !errors.Is(metaErr, os.ErrNotExist) {
+			return Response{}, fmt.Errorf("read mock meta %s: %w", metaPath, metaErr)
+		}
+		if metaErr == nil {
+			if decodeErr := yaml.Unmarshal(metaContent, &meta); decodeErr != nil {
+				return Response{}, fmt.Errorf("decode mock meta %s: %w", metaPath, decodeErr)
+			}
+		}
+	}
+
+	if meta.Status != 0 {
+		if meta.Status < 100 || meta.Status > 599 {
+			return Response{}, fmt.Errorf("mock %s: invalid status %d", relativePath, meta.Status)
+		}
+		response.Status = meta.Status
+	}
+	for name, value := range meta.Headers {
+		response.Header.Set(name, value)
+	}
+	if meta.Delay != "" {
+		delay, parseErr := time.ParseDuration(meta.Delay)
+		if parseErr != nil || delay < 0 {
+			return Response{}, fmt.Errorf("mock %s: invalid delay %q", relativePath, meta.Delay)
+		}
+		response.Delay = delay
+	}
+	return response, nil
+}
+
+// splitFrontMatter decodes a leading "---" block into meta and returns the
+// rest of the content.
+func splitFrontMatter(content []byte, meta *Meta) ([]byte, bool, error) {
+	normalized := bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(normalized, []byte(frontMatterMarker+"\n")) {
+		return content, false, nil
+	}
+	rest := normalized[len(frontMatterMarker)+1:]
+	if body, isEmpty := bytes.CutPrefix(rest, []byte(frontMatterMarker+"\n")); isEmpty {
+		return body, true, nil
+	}
+	block, body, found := bytes.Cut(rest, []byte("\n"+frontMatterMarker+"\n"))
+	if !found {
+		block, found = bytes.CutSuffix(rest, []byte("\n"+frontMatterMarker))
+		body = nil
+	}
+	if !found {
+		return nil, false, errors.New("unterminated front matter")
+	}
+	if decodeErr := yaml.Unmarshal(block, meta); decodeErr != nil {
+		return nil, false, fmt.Errorf("decode front matter: %w", decodeErr)
+	}
+	return body, true, nil
+}
+
+func recordParameter(parameters map[string]string, candidate string, segment string) {
+	if candidate != segment && strings.HasPrefix(candidate, wildcardPrefix) {
+		parameters[strings.TrimPrefix(candidate, wildcardPrefix)] = segment
+	}
+}
@@ -0,0 +1,98 @@
+package mocks
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeMockFile(t *testing.T, root string, relativePath string, content string) {
+	t.Helper()
+	absolutePath := filepath.Join(root, filepath.FromSlash(relativePath))
+	if mkdirErr := os.MkdirAll(filepath.Dir(absolutePath), 0o755); mkdirErr != nil {
+		t.Fatalf("mkdir: %v", mkdirErr)
+	}
+	if writeErr := os.WriteFile(absolutePath, []byte(content), 0o644); writeErr != nil {
+		t.Fatalf("write %s: %v", relativePath, writeErr)
+	}
+}
+
+func TestDirectoryResolve(t *testing.T) {
+	root := t.TempDir()
+	writeMockFile(t, root, "GET.json", `{"root":true}`)
+	writeMockFile(t, root, "api/users.GET.json", `[]`)
+	writeMockFile(t, root, "api/users/POST.json", "---\nstatus: 201\nheaders:\n  Location: /api/users/7\ndelay: 50ms\n---\n{\"id\":7}")
+	writeMockFile(t, root, "api/users/me.GET.json", `{"me":true}`)
+	writeMockFile(t, root, "api/users/_id/GET.json", `{"user":true}`)
+	writeMockFile(t, root, "api/users/_id/DELETE.json", ``)
+	writeMockFile(t, root, "api/users/_id/DELETE.meta.yaml", "status: 204\n")
+	writeMockFile(t, root, "api/_collection/_id.GET.json", `{"generic":true}`)
+
+	testCases := []struct {
+		name               string
+		method             string
+		path               string
+		expectedFound      bool
+		expectedFile       string
+		expectedStatus     int
+		expectedParameters map[string]string
+	}{
+		{name: "root", method: http.MethodGet, path: "/", expectedFound: true, expectedFile: "GET.json", expectedStatus: http.StatusOK},
+		{name: "dotted method file", method: http.MethodGet, path: "/api/users", expectedFound: true, expectedFile: "api/users.GET.json", expectedStatus: http.StatusOK},
+		{name: "method file in directory with front matter", method: http.MethodPost, path: "/api/users/", expectedFound: true, expectedFile: "api/users/POST.json", expectedStatus: http.StatusCreated},
+		{name: "literal preferred over wildcard", method: http.MethodGet, path: "/api/users/me", expectedFound: true, expectedFile: "api/users/me.GET.json", expectedStatus: http.StatusOK},
+		{name: "wildcard directory", method: http.MethodGet, path: "/api/users/42", expectedFound: true, expectedFile: "api/users/_id/GET.json", expectedStatus: http.StatusOK, expectedParameters: map[string]string{"id": "42"}},
+		{name: "sidecar meta", method: http.MethodDelete, path: "/api/users/42", expectedFound: true, expectedFile: "api/users/_id/DELETE.json", expectedStatus: http.StatusNoContent, expectedParameters: map[string]string{"id": "42"}},
+		{name: "backtracks into wildcards", method: http.MethodGet, path: "/api/orders/9", expectedFound: true, expectedFile: "api/_collection/_id.GET.json", expectedStatus: http.StatusOK, expectedParameters: map[string]string{"collection": "orders", "id": "9"}},
+		{name: "method without mock", method: http.MethodPut, path: "/api/users", expectedFound: false},
+		{name: "unknown path", method: http.MethodGet, path: "/other", expectedFound: false},
+	}
+
+	directory := Directory{Root: root}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, found, resolveErr := directory.Resolve(testCase.method, testCase.path)
+			if resolveErr != nil {
+				t.Fatalf("resolve: %v", resolveErr)
+			}
+			if found != testCase.expectedFound {
+				t.Fatalf("expected found %t, got %t", testCase.expectedFound, found)
+			}
+			if !found {
+				return
+			}
+			if response.FilePath != testCase.expectedFile || response.Status != testCase.expectedStatus {
+				t.Fatalf("expected %s with status %d, got %s with status %d", testCase.expectedFile, testCase.expectedStatus, response.FilePath, response.Status)
+			}
+			if len(response.Parameters) != len(testCase.expectedParameters) {
+				t.Fatalf("expected parameters %v, got %v", testCase.expectedParameters, response.Parameters)
+			}
+			for name, value := range testCase.expectedParameters {
+				if response.Parameters[name] != value {
+					t.Fatalf("expected parameters %v, got %v", testCase.expectedParameters, response.Parameters)
+				}
+			}
+		})
+	}
+
+	created, _, _ := directory.Resolve(http.MethodPost, "/api/users")
+	if string(created.Body) != `{"id":7}` || created.Header.Get("Location") != "/api/users/7" || created.Delay != 50*time.Millisecond {
+		t.Fatalf("unexpected front matter response %+v", created)
+	}
+}
+
+func TestDirectoryResolveReportsInvalidMeta(t *testing.T) {
+	root := t.TempDir()
+	writeMockFile(t, root, "status.GET.json", "---\nstatus: 1000\n---\n{}")
+	writeMockFile(t, root, "delay.GET.json", "---\ndelay: soon\n---\n{}")
+	writeMockFile(t, root, "open.GET.json", "---\nstatus: 200\n{}")
+
+	directory := Directory{Root: root}
+	for _, requestPath := range []string{"/status", "/delay", "/open"} {
+		if _, found, resolveErr := directory.Resolve(http.MethodGet, requestPath); !found || resolveErr == nil {
+			t.Fatalf("expected %s to be found and rejected, got found=%t err=%v", requestPath, found, resolveErr)
+		}
+	}
+}
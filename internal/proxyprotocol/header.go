@@ -0,0 +1,163 @@
+// Package proxyprotocol implements the receiving side of the HAProxy PROXY
+// protocol, versions 1 (text) and 2 (binary), which load balancers use to
+// forward the original client address ahead of the proxied stream.
+package proxyprotocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+const (
+	versionOnePrefix          = "PROXY "
+	versionOneLineEnding      = "\r\n"
+	versionOneMaximumLength   = 107
+	versionOneProtocolIPv4    = "TCP4"
+	versionOneProtocolIPv6    = "TCP6"
+	versionOneProtocolUnknown = "UNKNOWN"
+	versionTwoHeaderLength    = 16
+	versionTwoVersion         = 0x20
+	versionTwoCommandLocal    = 0x00
+	versionTwoCommandProxy    = 0x01
+	versionTwoFamilyIPv4      = 0x1
+	versionTwoFamilyIPv6      = 0x2
+	versionTwoAddressesIPv4   = 12
+	versionTwoAddressesIPv6   = 36
+)
+
+var versionTwoSignature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ErrMissingHeader reports a connection from a trusted peer that did not start
+// with a PROXY protocol header.
+var ErrMissingHeader = errors.New("proxy protocol header missing")
+
+// readHeader consumes a version 1 or version 2 header from the reader and
+// returns the source address it carries. A nil address means the header
+// described a local or unknown connection whose peer address stays in effect.
+func readHeader(reader *bufio.Reader) (*net.TCPAddr, error) {
+	firstByte, peekErr := reader.Peek(1)
+	if peekErr != nil {
+		return nil, peekErr
+	}
+	switch firstByte[0] {
+	case versionOnePrefix[0]:
+		return readVersionOneHeader(reader)
+	case versionTwoSignature[0]:
+		return readVersionTwoHeader(reader)
+	default:
+		return nil, ErrMissingHeader
+	}
+}
+
+// readVersionOneHeader parses "PROXY TCP4 src dst sport dport\r\n".
+func readVersionOneHeader(reader *bufio.Reader) (*net.TCPAddr, error) {
+	var line []byte
+	for len(line) < versionOneMaximumLength {
+		nextByte, readErr := reader.ReadByte()
+		if readErr != nil {
+			return nil, fmt.Errorf("read proxy protocol v1 header: %w", readErr)
+		}
+		line = append(line, nextByte)
+		if bytes.HasSuffix(line, []byte(versionOneLineEnding)) {
+			return parseVersionOneLine(strings.TrimSuffix(string(line), versionOneLineEnding))
+		}
+	}
+	return nil, errors.New("proxy protocol v1 header too long")
+}
+
+func parseVersionOneLine(line string) (*net.TCPAddr, error) {
+	if !strings.HasPrefix(line, versionOnePrefix) {
+		return nil, ErrMissingHeader
+	}
+	fields := strings.Split(line, " ")
+	if len(fields) >= 2 && fields[1] == versionOneProtocolUnknown {
+		return nil, nil
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("malformed proxy protocol v1 header %q", line)
+	}
+	sourceAddress, addressErr := netip.ParseAddr(fields[2])
+	if addressErr != nil {
+		return nil, fmt.Errorf("invalid proxy protocol v1 source address %q", fields[2])
+	}
+	if _, addressErr = netip.ParseAddr(fields[3]); addressErr != nil {
+		return nil, fmt.Errorf("invalid proxy protocol v1 destination address %q", fields[3])
+	}
+	switch {
+	case fields[1] == versionOneProtocolIPv4 && sourceAddress.Is4():
+	case fields[1] == versionOneProtocolIPv6 && sourceAddress.Is6():
+	default:
+		return nil, fmt.Errorf("proxy protocol v1 address %s does not match %s", sourceAddress, fields[1])
+	}
+	sourcePort, portErr := parseVersionOnePort(fields[4])
+	if portErr != nil {
+		return nil, portErr
+	}
+	if _, portErr = parseVersionOnePort(fields[5]); portErr != nil {
+		return nil, portErr
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(sourceAddress, sourcePort)), nil
+}
+
+func parseVersionOnePort(value string) (uint16, error) {
+	if value == "" || (len(value) > 1 && value[0] == '0') {
+		return 0, fmt.Errorf("invalid proxy protocol v1 port %q", value)
+	}
+	port, parseErr := strconv.ParseUint(value, 10, 16)
+	if parseErr != nil {
+		return 0, fmt.Errorf("invalid proxy protocol v1 port %q", value)
+	}
+	return uint16(port), nil
+}
+
+// readVersionTwoHeader parses the binary header: a 12 byte signature, the
+// version and command, the address family, the payload length, and the
+// payload holding the addresses followed by optional TLVs, which are skipped.
+func readVersionTwoHeader(reader *bufio.Reader) (*net.TCPAddr, error) {
+	header := make([]byte, versionTwoHeaderLength)
+	if _, readErr := io.ReadFull(reader, header); readErr != nil {
+		return nil, fmt.Errorf("read proxy protocol v2 header: %w", readErr)
+	}
+	if !bytes.Equal(header[:len(versionTwoSignature)], versionTwoSignature) {
+		return nil, ErrMissingHeader
+	}
+	versionAndCommand := header[12]
+	if versionAndCommand&0xF0 != versionTwoVersion {
+		return nil, fmt.Errorf("unsupported proxy protocol version %d", versionAndCommand>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, readErr := io.ReadFull(reader, payload); readErr != nil {
+		return nil, fmt.Errorf("read proxy protocol v2 addresses: %w", readErr)
+	}
+	switch versionAndCommand & 0x0F {
+	case versionTwoCommandLocal:
+		return nil, nil
+	case versionTwoCommandProxy:
+	default:
+		return nil, fmt.Errorf("unsupported proxy protocol v2 command %d", versionAndCommand&0x0F)
+	}
+	switch header[13] >> 4 {
+	case versionTwoFamilyIPv4:
+		if len(payload) < versionTwoAddressesIPv4 {
+			return nil, errors.New("proxy protocol v2 ipv4 addresses truncated")
+		}
+		sourceAddress := netip.AddrFrom4([4]byte(payload[0:4]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(sourceAddress, binary.BigEndian.Uint16(payload[8:10]))), nil
+	case versionTwoFamilyIPv6:
+		if len(payload) < versionTwoAddressesIPv6 {
+			return nil, errors.New("proxy protocol v2 ipv6 addresses truncated")
+		}
+		sourceAddress := netip.AddrFrom16([16]byte(payload[0:16])).Unmap()
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(sourceAddress, binary.BigEndian.Uint16(payload[32:34]))), nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,73 @@
+package proxyprotocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+func buildVersionTwoHeader(command byte, family byte, addresses []byte) []byte {
+	header := append([]byte{}, versionTwoSignature...)
+	header = append(header, versionTwoVersion|command, family<<4|0x1)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(addresses)))
+	return append(header, addresses...)
+}
+
+func TestReadHeader(t *testing.T) {
+	ipv4Addresses := []byte{203, 0, 113, 7, 10, 0, 0, 1, 0xC3, 0x50, 0x01, 0xBB}
+	ipv6Addresses := make([]byte, versionTwoAddressesIPv6)
+	copy(ipv6Addresses, []byte{0x20, 0x01, 0x0d, 0xb8})
+	ipv6Addresses[15] = 0x09
+	binary.BigEndian.PutUint16(ipv6Addresses[32:], 40000)
+	ipv4WithTLV := append(append([]byte{}, ipv4Addresses...), 0x04, 0x00, 0x01, 0xFF)
+
+	testCases := []struct {
+		name            string
+		stream          []byte
+		expectedAddress string
+		expectedError   bool
+	}{
+		{name: "v1 tcp4", stream: []byte("PROXY TCP4 198.51.100.22 10.0.0.1 35646 80\r\nGET"), expectedAddress: "198.51.100.22:35646"},
+		{name: "v1 tcp6", stream: []byte("PROXY TCP6 2001:db8::7 2001:db8::1 35646 443\r\nGET"), expectedAddress: "[2001:db8::7]:35646"},
+		{name: "v1 unknown", stream: []byte("PROXY UNKNOWN\r\nGET")},
+		{name: "v1 family mismatch", stream: []byte("PROXY TCP4 2001:db8::7 10.0.0.1 1 2\r\n"), expectedError: true},
+		{name: "v1 leading zero port", stream: []byte("PROXY TCP4 198.51.100.22 10.0.0.1 080 80\r\n"), expectedError: true},
+		{name: "v1 too long", stream: []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), expectedError: true},
+		{name: "v2 tcp4", stream: append(buildVersionTwoHeader(versionTwoCommandProxy, versionTwoFamilyIPv4, ipv4Addresses), "GET"...), expectedAddress: "203.0.113.7:50000"},
+		{name: "v2 tcp4 with tlv", stream: append(buildVersionTwoHeader(versionTwoCommandProxy, versionTwoFamilyIPv4, ipv4WithTLV), "GET"...), expectedAddress: "203.0.113.7:50000"},
+		{name: "v2 tcp6", stream: append(buildVersionTwoHeader(versionTwoCommandProxy, versionTwoFamilyIPv6, ipv6Addresses), "GET"...), expectedAddress: "[2001:db8::9]:40000"},
+		{name: "v2 local", stream: append(buildVersionTwoHeader(versionTwoCommandLocal, 0, nil), "GET"...)},
+		{name: "v2 truncated addresses", stream: buildVersionTwoHeader(versionTwoCommandProxy, versionTwoFamilyIPv4, ipv4Addresses[:4]), expectedError: true},
+		{name: "missing header", stream: []byte("GET / HTTP/1.1\r\n\r\n"), expectedError: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewReader(testCase.stream))
+			address, err := readHeader(reader)
+			if testCase.expectedError {
+				if err == nil {
+					t.Fatalf("expected error, got address %v", address)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if testCase.expectedAddress == "" {
+				if address != nil {
+					t.Fatalf("expected no address, got %v", address)
+				}
+			} else if address == nil || address.String() != testCase.expectedAddress {
+				t.Fatalf("expected address %s, got %v", testCase.expectedAddress, address)
+			}
+			remaining, _ := io.ReadAll(reader)
+			if string(remaining) != "GET" {
+				t.Fatalf("expected stream after header to be preserved, got %q", remaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package proxyprotocol
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// DefaultHeaderTimeout bounds how long a trusted peer may take to send its header.
+const DefaultHeaderTimeout = 5 * time.Second
+
+// Listener accepts connections whose remote address is replaced by the client
+// address announced in a PROXY protocol header. Only peers inside the trusted
+// networks may send a header; connections from other peers are passed through
+// unchanged, and a trusted peer that omits the header has its connection fail
+// on first read.
+type Listener struct {
+	net.Listener
+	trustedNetworks []netip.Prefix
+	headerTimeout   time.Duration
+}
+
+// NewListener wraps the listener. A zero header timeout selects DefaultHeaderTimeout.
+func NewListener(listener net.Listener, trustedNetworks []netip.Prefix, headerTimeout time.Duration) *Listener {
+	if headerTimeout <= 0 {
+		headerTimeout = DefaultHeaderTimeout
+	}
+	return &Listener{Listener: listener, trustedNetworks: trustedNetworks, headerTimeout: headerTimeout}
+}
+
+// Accept returns the next connection. The header is read lazily, on the first
+// call to Read or RemoteAddr, so that a slow peer never stalls the accept loop.
+func (listener *Listener) Accept() (net.Conn, error) {
+	connection, acceptErr := listener.Listener.Accept()
+	if acceptErr != nil {
+		return nil, acceptErr
+	}
+	if !listener.trusts(connection.RemoteAddr()) {
+		return connection, nil
+	}
+	return &Conn{Conn: connection, reader: bufio.NewReader(connection), headerTimeout: listener.headerTimeout}, nil
+}
+
+func (listener *Listener) trusts(address net.Addr) bool {
+	tcpAddress, isTCP := address.(*net.TCPAddr)
+	if !isTCP {
+		return false
+	}
+	peerAddress := tcpAddress.AddrPort().Addr().Unmap()
+	for _, trustedNetwork := range listener.trustedNetworks {
+		if trustedNetwork.Contains(peerAddress) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn is a connection from a trusted peer whose stream starts with a PROXY
+// protocol header.
+type Conn struct {
+	net.Conn
+	reader        *bufio.Reader
+	headerTimeout time.Duration
+	headerOnce    sync.Once
+	sourceAddress net.Addr
+	headerErr     error
+}
+
+func (connection *Conn) readHeader() {
+	connection.headerOnce.Do(func() {
+		_ = connection.Conn.SetReadDeadline(time.Now().Add(connection.headerTimeout))
+		sourceAddress, headerErr := readHeader(connection.reader)
+		_ = connection.Conn.SetReadDeadline(time.Time{})
+		connection.headerErr = headerErr
+		if sourceAddress != nil {
+			connection.sourceAddress = sourceAddress
+		}
+	})
+}
+
+// Read returns the stream following the header, or the header error.
+func (connection *Conn) Read(buffer []byte) (int, error) {
+	connection.readHeader()
+	if connection.headerErr != nil {
+		return 0, connection.headerErr
+	}
+	return connection.reader.Read(buffer)
+}
+
+// RemoteAddr returns the client address from the header, falling back to the
+// peer address for LOCAL and UNKNOWN headers.
+func (connection *Conn) RemoteAddr() net.Addr {
+	connection.readHeader()
+	if connection.sourceAddress != nil {
+		return connection.sourceAddress
+	}
+	return connection.Conn.RemoteAddr()
+}
+
+// ReadFrom keeps the sendfile path of the underlying connection available to
+// net/http when serving files.
+func (connection *Conn) ReadFrom(reader io.Reader) (int64, error) {
+	return io.Copy(connection.Conn, reader)
+}
@@ -0,0 +1,75 @@
+package proxyprotocol
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func serveRemoteAddresses(t *testing.T, trustedNetworks []netip.Prefix) string {
+	t.Helper()
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil {
+		t.Fatalf("listen: %v", listenErr)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		_, _ = io.WriteString(responseWriter, request.RemoteAddr)
+	})}
+	go func() {
+		_ = server.Serve(NewListener(listener, trustedNetworks, 0))
+	}()
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+	return listener.Addr().String()
+}
+
+func sendRequest(t *testing.T, address string, preamble string) string {
+	t.Helper()
+	connection, dialErr := net.Dial("tcp", address)
+	if dialErr != nil {
+		t.Fatalf("dial: %v", dialErr)
+	}
+	defer connection.Close()
+	if _, writeErr := io.WriteString(connection, preamble+"GET / HTTP/1.0\r\nHost: example\r\n\r\n"); writeErr != nil {
+		t.Fatalf("write: %v", writeErr)
+	}
+	response, _ := io.ReadAll(connection)
+	return string(response)
+}
+
+func TestListenerReplacesRemoteAddressForTrustedPeers(t *testing.T) {
+	address := serveRemoteAddresses(t, []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")})
+
+	response := sendRequest(t, address, "PROXY TCP4 198.51.100.22 10.0.0.1 35646 80\r\n")
+	if !hasBody(response, "198.51.100.22:35646") {
+		t.Fatalf("expected client address from header, got %q", response)
+	}
+
+	response = sendRequest(t, address, "")
+	if strings.HasPrefix(response, "HTTP/1.0 200") {
+		t.Fatalf("expected trusted peer without header to be rejected, got %q", response)
+	}
+}
+
+func TestListenerIgnoresHeadersFromUntrustedPeers(t *testing.T) {
+	address := serveRemoteAddresses(t, []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})
+
+	response := sendRequest(t, address, "")
+	if !hasBody(response, "127.0.0.1:") {
+		t.Fatalf("expected peer address for untrusted peer, got %q", response)
+	}
+
+	response = sendRequest(t, address, "PROXY TCP4 198.51.100.22 10.0.0.1 35646 80\r\n")
+	if hasBody(response, "198.51.100.22") {
+		t.Fatalf("expected header from untrusted peer to be ignored, got %q", response)
+	}
+}
+
+func hasBody(response string, prefix string) bool {
+	_, body, found := strings.Cut(response, "\r\n\r\n")
+	return found && strings.HasPrefix(body, prefix)
+}
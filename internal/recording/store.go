@@ -0,0 +1,169 @@
+// Package recording stores upstream HTTP responses as fixtures on disk and
+// reads them back for offline replay.
+package recording
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	fixtureFilePrefix    = "query-"
+	fixtureFileExtension = ".json"
+	queryHashLength      = 16
+	directoryPermissions = 0o755
+	fixturePermissions   = 0o644
+)
+
+// DefaultRedactedHeaders are never written to fixtures.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// ErrFixtureNotFound is returned by Store.Load when no recording matches.
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+// ErrInvalidMethod is returned for request methods that are not made of
+// letters only. Methods name a fixture directory, so a method such as ".."
+// must never reach the file system.
+var ErrInvalidMethod = errors.New("invalid method")
+
+// Exchange is one recorded response together with the request that
+// produced it. Bodies that are valid UTF-8 are stored as text so that
+// fixtures stay readable and diffable; other bodies are stored as base64.
+type Exchange struct {
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Query      string      `json:"query,omitempty"`
+	Status     int         `json:"status"`
+	Header     http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+// SetBody stores the body in the text or base64 field.
+func (exchange *Exchange) SetBody(body []byte) {
+	exchange.Body = ""
+	exchange.BodyBase64 = ""
+	if utf8.Valid(body) {
+		exchange.Body = string(body)
+		return
+	}
+	exchange.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+}
+
+// BodyBytes returns the stored body.
+func (exchange Exchange) BodyBytes() ([]byte, error) {
+	if exchange.BodyBase64 == "" {
+		return []byte(exchange.Body), nil
+	}
+	body, decodeErr := base64.StdEncoding.DecodeString(exchange.BodyBase64)
+	if decodeErr != nil {
+		return nil, fmt.Errorf("decode body: %w", decodeErr)
+	}
+	return body, nil
+}
+
+// Store lays fixtures out as <directory>/<METHOD>/<path>/query-<hash>.json,
+// where the hash covers the query with its parameters sorted.
+type Store struct {
+	Directory string
+}
+
+// FixturePath returns the file that holds the recording for the request.
+func (store Store) FixturePath(method string, requestPath string, rawQuery string) (string, error) {
+	if !isLettersOnly(method) {
+		return "", fmt.Errorf("%w %q", ErrInvalidMethod, method)
+	}
+	segments := []string{store.Directory, strings.ToUpper(method)}
+	for _, segment := range strings.Split(strings.Trim(pathpkg.Clean("/"+requestPath), "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		segments = append(segments, url.PathEscape(segment))
+	}
+	segments = append(segments, fixtureFilePrefix+queryHash(rawQuery)+fixtureFileExtension)
+	return filepath.Join(segments...), nil
+}
+
+func isLettersOnly(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, character := range value {
+		if (character < 'A' || character > 'Z') && (character < 'a' || character > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Save writes the exchange, replacing an earlier recording of the same request.
+func (store Store) Save(exchange Exchange) error {
+	fixturePath, pathErr := store.FixturePath(exchange.Method, exchange.Path, exchange.Query)
+	if pathErr != nil {
+		return pathErr
+	}
+	if mkdirErr := os.MkdirAll(filepath.Dir(fixturePath), directoryPermissions); mkdirErr != nil {
+		return fmt.Errorf("create fixture directory: %w", mkdirErr)
+	}
+	encoded, encodeErr := json.MarshalIndent(exchange, "", "  ")
+	if encodeErr != nil {
+		return fmt.Errorf("encode fixture: %w", encodeErr)
+	}
+	temporaryPath := fixturePath + ".tmp"
+	if writeErr := os.WriteFile(temporaryPath, append(encoded, '\n'), fixturePermissions); writeErr != nil {
+		return fmt.Errorf("write fixture: %w", writeErr)
+	}
+	if renameErr := os.Rename(temporaryPath, fixturePath); renameErr != nil {
+		return fmt.Errorf("write fixture: %w", renameErr)
+	}
+	return nil
+}
+
+// Load reads the recording for the request. Requests with methods that
+// cannot be recorded report ErrFixtureNotFound.
+func (store Store) Load(method string, requestPath string, rawQuery string) (Exchange, error) {
+	fixturePath, pathErr := store.FixturePath(method, requestPath, rawQuery)
+	if pathErr != nil {
+		return Exchange{}, ErrFixtureNotFound
+	}
+	encoded, readErr := os.ReadFile(fixturePath)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return Exchange{}, ErrFixtureNotFound
+	}
+	if readErr != nil {
+		return Exchange{}, fmt.Errorf("read fixture: %w", readErr)
+	}
+	var exchange Exchange
+	if decodeErr := json.Unmarshal(encoded, &exchange); decodeErr != nil {
+		return Exchange{}, fmt.Errorf("decode fixture %s: %w", fixturePath, decodeErr)
+	}
+	return exchange, nil
+}
+
+// Redact removes the named headers, compared case-insensitively.
+func Redact(header http.Header, headerNames []string) {
+	for _, headerName := range headerNames {
+		header.Del(headerName)
+	}
+}
+
+// queryHash identifies a query independently of parameter order.
+func queryHash(rawQuery string) string {
+	canonicalQuery := rawQuery
+	if parsedQuery, parseErr := url.ParseQuery(rawQuery); parseErr == nil {
+		canonicalQuery = parsedQuery.Encode()
+	}
+	digest := sha256.Sum256([]byte(canonicalQuery))
+	return hex.EncodeToString(digest[:])[:queryHashLength]
+}
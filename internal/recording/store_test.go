@@ -0,0 +1,103 @@
+package recording
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFixturePathIsDeterministic(t *testing.T) {
+	store := Store{Directory: "fixtures"}
+	fixturePath := func(method string, requestPath string, rawQuery string) string {
+		t.Helper()
+		resolvedPath, pathErr := store.FixturePath(method, requestPath, rawQuery)
+		if pathErr != nil {
+			t.Fatalf("fixture path: %v", pathErr)
+		}
+		return resolvedPath
+	}
+
+	firstPath := fixturePath("get", "/api/users", "page=2&sort=name")
+	secondPath := fixturePath(http.MethodGet, "/api/users/", "sort=name&page=2")
+	if firstPath != secondPath {
+		t.Fatalf("expected query order and trailing slash to be ignored, got %q and %q", firstPath, secondPath)
+	}
+	if filepath.Dir(firstPath) != filepath.Join("fixtures", "GET", "api", "users") {
+		t.Fatalf("unexpected fixture directory %q", filepath.Dir(firstPath))
+	}
+	if otherQueryPath := fixturePath(http.MethodGet, "/api/users", "page=3"); otherQueryPath == firstPath {
+		t.Fatalf("expected different queries to use different fixtures")
+	}
+	if escapingPath := fixturePath(http.MethodGet, "/../../etc/passwd", ""); filepath.Dir(escapingPath) != filepath.Join("fixtures", "GET", "etc", "passwd") {
+		t.Fatalf("expected fixture to stay inside the directory, got %q", escapingPath)
+	}
+}
+
+func TestStoreRejectsMethodsThatEscapeTheDirectory(t *testing.T) {
+	parentDirectory := t.TempDir()
+	store := Store{Directory: filepath.Join(parentDirectory, "fixtures")}
+
+	for _, method := range []string{"..", ".", "GET/..", ""} {
+		if _, pathErr := store.FixturePath(method, "/api", ""); !errors.Is(pathErr, ErrInvalidMethod) {
+			t.Fatalf("expected method %q to be rejected, got %v", method, pathErr)
+		}
+	}
+
+	exchange := Exchange{Method: "..", Path: "/api", Status: http.StatusOK}
+	if saveErr := store.Save(exchange); !errors.Is(saveErr, ErrInvalidMethod) {
+		t.Fatalf("expected save to reject the method, got %v", saveErr)
+	}
+	if entries, _ := os.ReadDir(parentDirectory); len(entries) != 0 {
+		t.Fatalf("expected nothing written next to the fixture directory, found %d entries", len(entries))
+	}
+
+	outsidePath := filepath.Join(parentDirectory, "api", fixtureFilePrefix+queryHash("")+fixtureFileExtension)
+	if mkdirErr := os.MkdirAll(filepath.Dir(outsidePath), 0o755); mkdirErr != nil {
+		t.Fatalf("mkdir: %v", mkdirErr)
+	}
+	if writeErr := os.WriteFile(outsidePath, []byte(`{"status":200}`), 0o644); writeErr != nil {
+		t.Fatalf("write: %v", writeErr)
+	}
+	if _, loadErr := store.Load("..", "/api", ""); !errors.Is(loadErr, ErrFixtureNotFound) {
+		t.Fatalf("expected load outside the directory to be refused, got %v", loadErr)
+	}
+}
+
+func TestStoreSavesAndLoadsExchanges(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	testCases := []struct {
+		name string
+		body []byte
+	}{
+		{name: "text body", body: []byte(`{"ok":true}`)},
+		{name: "binary body", body: []byte{0xff, 0x00, 0xfe}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			exchange := Exchange{Method: http.MethodPost, Path: "/api/items", Query: "id=1", Status: http.StatusCreated, Header: http.Header{"Content-Type": {"application/json"}}}
+			exchange.SetBody(testCase.body)
+			if saveErr := store.Save(exchange); saveErr != nil {
+				t.Fatalf("save: %v", saveErr)
+			}
+
+			loaded, loadErr := store.Load(http.MethodPost, "/api/items", "id=1")
+			if loadErr != nil {
+				t.Fatalf("load: %v", loadErr)
+			}
+			body, bodyErr := loaded.BodyBytes()
+			if bodyErr != nil {
+				t.Fatalf("body: %v", bodyErr)
+			}
+			if string(body) != string(testCase.body) || loaded.Status != http.StatusCreated || loaded.Header.Get("Content-Type") != "application/json" {
+				t.Fatalf("unexpected exchange %+v", loaded)
+			}
+		})
+	}
+
+	if _, loadErr := store.Load(http.MethodGet, "/api/items", ""); !errors.Is(loadErr, ErrFixtureNotFound) {
+		t.Fatalf("expected ErrFixtureNotFound, got %v", loadErr)
+	}
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temirov/ghttp/internal/watch"
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+const (
+	logMessageBuildStarted   = "build started"
+	logMessageBuildOutput    = "build output"
+	logMessageBuildSucceeded = "build succeeded"
+	logMessageBuildFailed    = "build failed"
+	logMessageBuildWatch     = "build watch failed"
+	logFieldCommand          = "command"
+	logFieldStream           = "stream"
+	logFieldLine             = "line"
+	buildStreamStdout        = "stdout"
+	buildStreamStderr        = "stderr"
+	buildOutputLineLimit     = 1024 * 1024
+	buildStderrRetainedBytes = 64 * 1024
+	buildRetryAfterSeconds   = "1"
+	retryAfterHeaderName     = "Retry-After"
+	htmlContentType          = "text/html; charset=utf-8"
+)
+
+// OnChangeConfiguration describes the command run when watched files change.
+type OnChangeConfiguration struct {
+	// Command is run through the platform shell.
+	Command string
+	// WorkingDirectory is both where the command runs and the root of the
+	// watched tree. When the served directory lies inside it, the served
+	// directory is not watched so that build output does not trigger builds;
+	// other output locations belong in ExcludePatterns.
+	WorkingDirectory string
+	// IncludePatterns, when not empty, restrict the files that trigger a build.
+	IncludePatterns []string
+	// ExcludePatterns name files and directories that never trigger a build.
+	ExcludePatterns []string
+	// Debounce is the quiet period after the last change before building.
+	Debounce time.Duration
+}
+
+// buildRunner runs the on-change command and records whether a build is in
+// progress and how the last one ended.
+type buildRunner struct {
+	configuration  OnChangeConfiguration
+	loggingService *logging.Service
+	mutex          sync.RWMutex
+	running        bool
+	rebuild        bool
+	failure        *buildFailure
+}
+
+type buildFailure struct {
+	err    error
+	stderr string
+}
+
+func newBuildRunner(configuration OnChangeConfiguration, loggingService *logging.Service) *buildRunner {
+	return &buildRunner{configuration: configuration, loggingService: loggingService}
+}
+
+// newWatcher watches the working directory for files that should trigger a
+// build according to the include and exclude patterns.
+func (runner *buildRunner) newWatcher(servedDirectoryPath string) (*watch.Watcher, error) {
+	servedRelativePath := ""
+	if relativePath, relativeErr := filepath.Rel(runner.configuration.WorkingDirectory, servedDirectoryPath); relativeErr == nil && relativePath != "." && !strings.HasPrefix(relativePath, "..") {
+		servedRelativePath = filepath.ToSlash(relativePath)
+	}
+	return watch.New(runner.configuration.WorkingDirectory, watch.Options{
+		Debounce: runner.configuration.Debounce,
+		Ignore: func(relativePath string, isDirectory bool) bool {
+			if servedRelativePath != "" && (relativePath == servedRelativePath || strings.HasPrefix(relativePath, servedRelativePath+"/")) {
+				return true
+			}
+			for _, pattern := range runner.configuration.ExcludePatterns {
+				if watch.MatchGlob(pattern, relativePath) {
+					return true
+				}
+			}
+			if isDirectory || len(runner.configuration.IncludePatterns) == 0 {
+				return false
+			}
+			for _, pattern := range runner.configuration.IncludePatterns {
+				if watch.MatchGlob(pattern, relativePath) {
+					return false
+				}
+			}
+			return true
+		},
+	})
+}
+
+// start runs a build in the background and calls onFinished after it ends.
+// Changes reported while a build runs are remembered, and one more build
+// follows so that the served output reflects them.
+func (runner *buildRunner) start(ctx context.Context, onFinished func()) {
+	runner.mutex.Lock()
+	if runner.running {
+		runner.rebuild = true
+		runner.mutex.Unlock()
+		return
+	}
+	runner.running = true
+	runner.mutex.Unlock()
+	go func() {
+		for {
+			runner.run(ctx)
+			onFinished()
+			runner.mutex.Lock()
+			if !runner.rebuild || runner.running || ctx.Err() != nil {
+				runner.mutex.Unlock()
+				return
+			}
+			runner.rebuild = false
+			runner.running = true
+			runner.mutex.Unlock()
+		}
+	}()
+}
+
+// run executes the command once, logging each line it prints, and marks the
+// build finished. Callers mark it running. Cancelling the context kills the
+// command.
+func (runner *buildRunner) run(ctx context.Context) {
+	runner.loggingService.Info(logMessageBuildStarted, logging.String(logFieldCommand, runner.configuration.Command))
+	startTime := time.Now()
+	stderr, runErr := runner.execute(ctx)
+	duration := time.Since(startTime)
+
+	runner.mutex.Lock()
+	runner.running = false
+	runner.failure = nil
+	if runErr != nil {
+		runner.failure = &buildFailure{err: runErr, stderr: stderr}
+	}
+	runner.mutex.Unlock()
+
+	if runErr != nil {
+		runner.loggingService.Error(logMessageBuildFailed, runErr, logging.Duration(logFieldDuration, duration))
+		return
+	}
+	runner.loggingService.Info(logMessageBuildSucceeded, logging.Duration(logFieldDuration, duration))
+}
+
+func (runner *buildRunner) execute(ctx context.Context) (string, error) {
+	command := shellCommand(ctx, runner.configuration.Command)
+	command.Dir = runner.configuration.WorkingDirectory
+	stdoutPipe, stdoutErr := command.StdoutPipe()
+	if stdoutErr != nil {
+		return "", fmt.Errorf("open build stdout: %w", stdoutErr)
+	}
+	stderrPipe, stderrErr := command.StderrPipe()
+	if stderrErr != nil {
+		return "", fmt.Errorf("open build stderr: %w", stderrErr)
+	}
+	if startErr := command.Start(); startErr != nil {
+		return "", fmt.Errorf("start build: %w", startErr)
+	}
+	stderrTail := &boundedTail{limit: buildStderrRetainedBytes}
+	var streams sync.WaitGroup
+	streams.Add(2)
+	go func() {
+		defer streams.Done()
+		runner.logOutput(stdoutPipe, buildStreamStdout, nil)
+	}()
+	go func() {
+		defer streams.Done()
+		runner.logOutput(stderrPipe, buildStreamStderr, stderrTail)
+	}()
+	streams.Wait()
+	if waitErr := command.Wait(); waitErr != nil {
+		return stderrTail.String(), fmt.Errorf("run %q: %w", runner.configuration.Command, waitErr)
+	}
+	return stderrTail.String(), nil
+}
+
+func (runner *buildRunner) logOutput(reader io.Reader, stream string, tail *boundedTail) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), buildOutputLineLimit)
+	for scanner.Scan() {
+		line := scanner.Text()
+		runner.loggingService.Info(logMessageBuildOutput, logging.String(logFieldStream, stream), logging.String(logFieldLine, line))
+		if tail != nil {
+			tail.writeLine(line)
+		}
+	}
+	_, _ = io.Copy(io.Discard, reader)
+}
+
+// state returns whether a build is running and the failure of the last build.
+func (runner *buildRunner) state() (bool, *buildFailure) {
+	runner.mutex.RLock()
+	defer runner.mutex.RUnlock()
+	return runner.running, runner.failure
+}
+
+func shellCommand(ctx context.Context, commandLine string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", commandLine)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", commandLine)
+}
+
+// boundedTail keeps the last lines written to it, up to a byte limit, so that
+// a noisy build cannot grow the failure page without bound.
+type boundedTail struct {
+	limit   int
+	builder strings.Builder
+}
+
+func (tail *boundedTail) writeLine(line string) {
+	tail.builder.WriteString(line)
+	tail.builder.WriteByte('\n')
+	if tail.builder.Len() <= tail.limit {
+		return
+	}
+	retained := tail.builder.String()
+	retained = retained[len(retained)-tail.limit:]
+	if newlineIndex := strings.IndexByte(retained, '\n'); newlineIndex >= 0 {
+		retained = retained[newlineIndex+1:]
+	}
+	tail.builder.Reset()
+	tail.builder.WriteString(retained)
+}
+
+func (tail *boundedTail) String() string {
+	return tail.builder.String()
+}
+
+// newBuildGateHandler refuses requests with 503 while a build is running and
+// shows the stderr of a failed build until the next build succeeds. The pages
+// refresh themselves, or carry the injected page scripts such as live reload.
+func newBuildGateHandler(next http.Handler, runner *buildRunner, pageSnippet string) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		running, failure := runner.state()
+		switch {
+		case running:
+			responseWriter.Header().Set(retryAfterHeaderName, buildRetryAfterSeconds)
+			writeBuildPage(responseWriter, http.StatusServiceUnavailable, "Build in progress", "<p>Running <code>"+html.EscapeString(runner.configuration.Command)+"</code>&hellip;</p>", `<meta http-equiv="refresh" content="`+buildRetryAfterSeconds+`">`)
+		case failure != nil:
+			failureBody := "<p>" + html.EscapeString(failure.err.Error()) + "</p><pre>" + html.EscapeString(failure.stderr) + "</pre>" + pageSnippet
+			writeBuildPage(responseWriter, http.StatusInternalServerError, "Build failed", failureBody, "")
+		default:
+			next.ServeHTTP(responseWriter, request)
+		}
+	})
+}
+
+func writeBuildPage(responseWriter http.ResponseWriter, statusCode int, title string, body string, head string) {
+	responseWriter.Header().Set(contentTypeHeaderName, htmlContentType)
+	responseWriter.Header().Set(cacheControlHeaderName, cacheControlNoCache)
+	responseWriter.WriteHeader(statusCode)
+	_, _ = io.WriteString(responseWriter, "<!DOCTYPE html><html lang=\"en\"><head><meta charset=\"utf-8\">"+head+"<title>"+title+"</title></head><body><h1>"+title+"</h1>"+body+"</body></html>")
+}
@@ -0,0 +1,157 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+func TestBuildGateHandlerReflectsBuildState(t *testing.T) {
+	workingDirectory := t.TempDir()
+	runner := newBuildRunner(OnChangeConfiguration{Command: "echo building; echo 'broken <thing>' >&2; exit 3", WorkingDirectory: workingDirectory}, logging.NewTestService(logging.TypeConsole))
+	handler := newBuildGateHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusNoContent)
+	}), runner, liveReloadSnippet)
+
+	initialRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(initialRecorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if initialRecorder.Code != http.StatusNoContent {
+		t.Fatalf("expected content before any build, got %d", initialRecorder.Code)
+	}
+
+	runner.mutex.Lock()
+	runner.running = true
+	runner.mutex.Unlock()
+	runningRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(runningRecorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if runningRecorder.Code != http.StatusServiceUnavailable || runningRecorder.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After during a build, got %d", runningRecorder.Code)
+	}
+
+	runner.run(context.Background())
+	failedRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(failedRecorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if failedRecorder.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 after a failed build, got %d", failedRecorder.Code)
+	}
+	failedBody := failedRecorder.Body.String()
+	if !strings.Contains(failedBody, "<pre>broken &lt;thing&gt;\n</pre>") {
+		t.Fatalf("expected escaped stderr only, got %q", failedBody)
+	}
+	if !strings.Contains(failedBody, liveReloadSnippet) {
+		t.Fatalf("expected the failure page to carry the live reload snippet")
+	}
+
+	runner.configuration.Command = "true"
+	runner.run(context.Background())
+	recoveredRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(recoveredRecorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if recoveredRecorder.Code != http.StatusNoContent {
+		t.Fatalf("expected content after a successful build, got %d", recoveredRecorder.Code)
+	}
+}
+
+func TestBuildRunnerWatcherSkipsServedDirectoryAndFilters(t *testing.T) {
+	workingDirectory := t.TempDir()
+	for _, directoryName := range []string{"src", "public", "node_modules"} {
+		if mkdirErr := os.Mkdir(filepath.Join(workingDirectory, directoryName), 0o755); mkdirErr != nil {
+			t.Fatalf("create %s: %v", directoryName, mkdirErr)
+		}
+	}
+	runner := newBuildRunner(OnChangeConfiguration{
+		Command:          "true",
+		WorkingDirectory: workingDirectory,
+		IncludePatterns:  []string{"src/**", "*.toml"},
+		ExcludePatterns:  []string{"node_modules"},
+		Debounce:         20 * time.Millisecond,
+	}, logging.NewTestService(logging.TypeConsole))
+	watcher, watchErr := runner.newWatcher(filepath.Join(workingDirectory, "public"))
+	if watchErr != nil {
+		t.Fatalf("watch: %v", watchErr)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batches := make(chan []string, 4)
+	go watcher.Run(ctx, func(changedPaths []string) { batches <- changedPaths }, nil)
+
+	for _, relativePath := range []string{"public/index.html", "node_modules/x.js", "notes.txt", "site.toml", "src/app.css"} {
+		if writeErr := os.WriteFile(filepath.Join(workingDirectory, relativePath), []byte("x"), 0o644); writeErr != nil {
+			t.Fatalf("write %s: %v", relativePath, writeErr)
+		}
+	}
+
+	select {
+	case changedPaths := <-batches:
+		if strings.Join(changedPaths, ",") != "site.toml,src/app.css" {
+			t.Fatalf("unexpected batch %v", changedPaths)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a batch")
+	}
+}
+
+func TestBuildRunnerRebuildsForChangesDuringABuild(t *testing.T) {
+	workingDirectory := t.TempDir()
+	servedDirectory := filepath.Join(workingDirectory, "public")
+	if mkdirErr := os.Mkdir(servedDirectory, 0o755); mkdirErr != nil {
+		t.Fatalf("create public: %v", mkdirErr)
+	}
+	runner := newBuildRunner(OnChangeConfiguration{
+		Command:          "touch public/started; sleep 0.3; echo built > public/index.html",
+		WorkingDirectory: workingDirectory,
+		Debounce:         20 * time.Millisecond,
+	}, logging.NewTestService(logging.TypeConsole))
+	watcher, watchErr := runner.newWatcher(servedDirectory)
+	if watchErr != nil {
+		t.Fatalf("watch: %v", watchErr)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	builds := make(chan struct{}, 4)
+	go watcher.Run(ctx, func(changedPaths []string) {
+		runner.start(ctx, func() { builds <- struct{}{} })
+	}, nil)
+
+	sourcePath := filepath.Join(workingDirectory, "source.md")
+	if writeErr := os.WriteFile(sourcePath, []byte("first"), 0o644); writeErr != nil {
+		t.Fatalf("write source: %v", writeErr)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, statErr := os.Stat(filepath.Join(servedDirectory, "started")); statErr == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the build to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if running, _ := runner.state(); !running {
+		t.Fatalf("expected the edit to land while the build runs")
+	}
+	if writeErr := os.WriteFile(sourcePath, []byte("second"), 0o644); writeErr != nil {
+		t.Fatalf("write source: %v", writeErr)
+	}
+
+	for build := 1; build <= 2; build++ {
+		select {
+		case <-builds:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for build %d", build)
+		}
+	}
+	select {
+	case <-builds:
+		t.Fatalf("expected the build output not to trigger a third build")
+	case <-time.After(500 * time.Millisecond):
+	}
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/temirov/ghttp/internal/watch"
+)
+
+const (
+	chaosHeaderName     = "X-Ghttp-Chaos"
+	chaosDefaultNameTag = "chaos-"
+	chaosRoutesPath     = internalRoutePrefix + "chaos"
+	chaosPercentScale   = 100
+)
+
+// ChaosRule makes responses for matching paths slow or faulty. Each request
+// that matches an enabled rule is delayed first and then suffers at most one
+// fault, chosen with the configured percentages.
+type ChaosRule struct {
+	// Name identifies the rule on the admin endpoint and in the X-Ghttp-Chaos
+	// header. Unnamed rules are called chaos-N for the N-th rule.
+	Name string
+	// PathGlob is matched against the request path without its leading slash,
+	// using the same syntax as --watch-include, so "/api/**" covers the API
+	// and "*.js" covers scripts at any depth.
+	PathGlob string
+	// Latency delays every matching request; up to LatencyJitter more is
+	// added at random.
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	// ErrorPercent of matching requests are answered with ErrorStatus.
+	ErrorStatus  int
+	ErrorPercent float64
+	// ResetPercent of matching requests are cut off after half of the body
+	// by closing the connection.
+	ResetPercent float64
+	// TruncatePercent of matching requests end normally after half of the
+	// body, without a Content-Length.
+	TruncatePercent float64
+	// Disabled rules start switched off and can be enabled at runtime.
+	Disabled bool
+}
+
+type chaosRuleState struct {
+	rule    ChaosRule
+	enabled atomic.Bool
+}
+
+// chaosController applies the chaos rules and serves the admin endpoints
+// that switch them on and off.
+type chaosController struct {
+	rules []*chaosRuleState
+}
+
+func newChaosController(rules []ChaosRule) *chaosController {
+	controller := &chaosController{}
+	for ruleIndex, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = chaosDefaultNameTag + strconv.Itoa(ruleIndex+1)
+		}
+		state := &chaosRuleState{rule: rule}
+		state.enabled.Store(!rule.Disabled)
+		controller.rules = append(controller.rules, state)
+	}
+	return controller
+}
+
+// wrap returns a handler that applies the first enabled matching rule before
+// and around next.
+func (controller *chaosController) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		state := controller.match(request.URL.Path)
+		if state == nil {
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		rule := state.rule
+		responseWriter.Header().Set(chaosHeaderName, rule.Name)
+		if delay := rule.Latency + randomDuration(rule.LatencyJitter); delay > 0 {
+			delayTimer := time.NewTimer(delay)
+			select {
+			case <-delayTimer.C:
+			case <-request.Context().Done():
+				delayTimer.Stop()
+				return
+			}
+		}
+		roll := rand.Float64() * chaosPercentScale
+		switch {
+		case roll < rule.ErrorPercent:
+			http.Error(responseWriter, http.StatusText(rule.ErrorStatus), rule.ErrorStatus)
+		case roll < rule.ErrorPercent+rule.ResetPercent:
+			bufferedWriter := &chaosBufferWriter{ResponseWriter: responseWriter, statusCode: http.StatusOK}
+			next.ServeHTTP(bufferedWriter, request)
+			resetMidBody(responseWriter, bufferedWriter)
+		case roll < rule.ErrorPercent+rule.ResetPercent+rule.TruncatePercent:
+			bufferedWriter := &chaosBufferWriter{ResponseWriter: responseWriter, statusCode: http.StatusOK}
+			next.ServeHTTP(bufferedWriter, request)
+			responseWriter.Header().Del(contentLengthHeaderName)
+			responseWriter.WriteHeader(bufferedWriter.statusCode)
+			_, _ = responseWriter.Write(bufferedWriter.body.Bytes()[:bufferedWriter.body.Len()/2])
+		default:
+			next.ServeHTTP(responseWriter, request)
+		}
+	})
+}
+
+func (controller *chaosController) match(requestPath string) *chaosRuleState {
+	relativePath := strings.TrimPrefix(requestPath, "/")
+	for _, state := range controller.rules {
+		if state.enabled.Load() && watch.MatchGlob(state.rule.PathGlob, relativePath) {
+			return state
+		}
+	}
+	return nil
+}
+
+// resetMidBody sends the headers and half of the buffered body with the full
+// Content-Length and then drops the connection, so clients see a network
+// error partway through the response. Connections that cannot be hijacked,
+// such as HTTP/2 streams, are aborted instead.
+func resetMidBody(responseWriter http.ResponseWriter, bufferedWriter *chaosBufferWriter) {
+	body := bufferedWriter.body.Bytes()
+	responseWriter.Header().Set(contentLengthHeaderName, strconv.Itoa(len(body)))
+	responseWriter.WriteHeader(bufferedWriter.statusCode)
+	_, _ = responseWriter.Write(body[:len(body)/2])
+	responseController := http.NewResponseController(responseWriter)
+	_ = responseController.Flush()
+	connection, _, hijackErr := responseController.Hijack()
+	if hijackErr != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcpConnection, isTCP := connection.(*net.TCPConn); isTCP {
+		_ = tcpConnection.SetLinger(0)
+	}
+	_ = connection.Close()
+}
+
+func randomDuration(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit)
+}
+
+// chaosBufferWriter holds a response back so that only part of it is sent.
+type chaosBufferWriter struct {
+	http.ResponseWriter
+	statusCode    int
+	headerWritten bool
+	body          bytes.Buffer
+}
+
+func (writer *chaosBufferWriter) WriteHeader(statusCode int) {
+	if writer.headerWritten {
+		return
+	}
+	writer.headerWritten = true
+	writer.statusCode = statusCode
+}
+
+func (writer *chaosBufferWriter) Write(content []byte) (int, error) {
+	writer.headerWritten = true
+	return writer.body.Write(content)
+}
+
+// Flush is a no-op so that streaming handlers cannot send the held back
+// headers early.
+func (writer *chaosBufferWriter) Flush() {}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (writer *chaosBufferWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// chaosRuleStatus is the admin endpoint view of a rule.
+type chaosRuleStatus struct {
+	Name            string  `json:"name"`
+	Path            string  `json:"path"`
+	Enabled         bool    `json:"enabled"`
+	Latency         string  `json:"latency,omitempty"`
+	LatencyJitter   string  `json:"latency_jitter,omitempty"`
+	ErrorStatus     int     `json:"error_status,omitempty"`
+	ErrorPercent    float64 `json:"error_percent,omitempty"`
+	ResetPercent    float64 `json:"reset_percent,omitempty"`
+	TruncatePercent float64 `json:"truncate_percent,omitempty"`
+}
+
+// registerChaosRoutes lists the rules at GET /__ghttp/chaos and switches a
+// rule with POST /__ghttp/chaos/{name}/enable or /disable.
+func registerChaosRoutes(routes *http.ServeMux, controller *chaosController) {
+	routes.HandleFunc("GET "+chaosRoutesPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		statuses := make([]chaosRuleStatus, 0, len(controller.rules))
+		for _, state := range controller.rules {
+			statuses = append(statuses, state.status())
+		}
+		writeJSON(responseWriter, http.StatusOK, statuses)
+	})
+	routes.HandleFunc("POST "+chaosRoutesPath+"/{name}/{action}", func(responseWriter http.ResponseWriter, request *http.Request) {
+		var enable bool
+		switch request.PathValue("action") {
+		case "enable":
+			enable = true
+		case "disable":
+		default:
+			http.NotFound(responseWriter, request)
+			return
+		}
+		for _, state := range controller.rules {
+			if state.rule.Name == request.PathValue("name") {
+				state.enabled.Store(enable)
+				writeJSON(responseWriter, http.StatusOK, state.status())
+				return
+			}
+		}
+		http.NotFound(responseWriter, request)
+	})
+}
+
+func (state *chaosRuleState) status() chaosRuleStatus {
+	status := chaosRuleStatus{
+		Name:            state.rule.Name,
+		Path:            state.rule.PathGlob,
+		Enabled:         state.enabled.Load(),
+		ErrorPercent:    state.rule.ErrorPercent,
+		ResetPercent:    state.rule.ResetPercent,
+		TruncatePercent: state.rule.TruncatePercent,
+	}
+	if state.rule.Latency > 0 {
+		status.Latency = state.rule.Latency.String()
+	}
+	if state.rule.LatencyJitter > 0 {
+		status.LatencyJitter = state.rule.LatencyJitter.String()
+	}
+	if state.rule.ErrorPercent > 0 {
+		status.ErrorStatus = state.rule.ErrorStatus
+	}
+	return status
+}
+
+func writeJSON(responseWriter http.ResponseWriter, statusCode int, value any) {
+	responseWriter.Header().Set(contentTypeHeaderName, jsonContentType)
+	responseWriter.Header().Set(cacheControlHeaderName, cacheControlNoCache)
+	responseWriter.WriteHeader(statusCode)
+	encoder := json.NewEncoder(responseWriter)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(value)
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const chaosTestBody = "0123456789"
+
+func newChaosTestHandler(rules []ChaosRule) (http.Handler, *chaosController) {
+	controller := newChaosController(rules)
+	internalRoutes := http.NewServeMux()
+	registerChaosRoutes(internalRoutes, controller)
+	content := http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set(contentLengthHeaderName, "10")
+		_, _ = io.WriteString(responseWriter, chaosTestBody)
+	})
+	return newInternalRoutesHandler(controller.wrap(content), internalRoutes), controller
+}
+
+func TestChaosRulesApplyFaults(t *testing.T) {
+	handler, _ := newChaosTestHandler([]ChaosRule{
+		{Name: "errors", PathGlob: "/api/**", ErrorStatus: http.StatusServiceUnavailable, ErrorPercent: 100},
+		{Name: "truncated", PathGlob: "*.js", TruncatePercent: 100},
+		{Name: "slow", PathGlob: "/slow", Latency: 20 * time.Millisecond},
+		{Name: "off", PathGlob: "/off", ErrorStatus: http.StatusInternalServerError, ErrorPercent: 100, Disabled: true},
+	})
+
+	testCases := []struct {
+		name            string
+		target          string
+		expectedStatus  int
+		expectedBody    string
+		expectedRule    string
+		expectedMinimum time.Duration
+	}{
+		{name: "error status", target: "/api/users", expectedStatus: http.StatusServiceUnavailable, expectedBody: "Service Unavailable\n", expectedRule: "errors"},
+		{name: "truncated body", target: "/static/app.js", expectedStatus: http.StatusOK, expectedBody: "01234", expectedRule: "truncated"},
+		{name: "latency only", target: "/slow", expectedStatus: http.StatusOK, expectedBody: chaosTestBody, expectedRule: "slow", expectedMinimum: 20 * time.Millisecond},
+		{name: "disabled rule", target: "/off", expectedStatus: http.StatusOK, expectedBody: chaosTestBody},
+		{name: "unmatched path", target: "/index.html", expectedStatus: http.StatusOK, expectedBody: chaosTestBody},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			startTime := time.Now()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testCase.target, nil))
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if recorder.Body.String() != testCase.expectedBody {
+				t.Fatalf("expected body %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+			if rule := recorder.Header().Get("X-Ghttp-Chaos"); rule != testCase.expectedRule {
+				t.Fatalf("expected chaos header %q, got %q", testCase.expectedRule, rule)
+			}
+			if testCase.name == "truncated body" && recorder.Header().Get(contentLengthHeaderName) != "" {
+				t.Fatalf("expected no content length on a truncated response")
+			}
+			if elapsed := time.Since(startTime); elapsed < testCase.expectedMinimum {
+				t.Fatalf("expected a delay of at least %s, got %s", testCase.expectedMinimum, elapsed)
+			}
+		})
+	}
+}
+
+func TestChaosResetClosesConnectionMidBody(t *testing.T) {
+	handler, _ := newChaosTestHandler([]ChaosRule{{Name: "reset", PathGlob: "**", ResetPercent: 100}})
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	response, requestErr := http.Get(testServer.URL + "/file.txt")
+	if requestErr != nil {
+		t.Fatalf("request: %v", requestErr)
+	}
+	defer response.Body.Close()
+	if response.ContentLength != int64(len(chaosTestBody)) {
+		t.Fatalf("expected the full content length, got %d", response.ContentLength)
+	}
+	body, readErr := io.ReadAll(response.Body)
+	if readErr == nil {
+		t.Fatalf("expected the body read to fail, got %q", body)
+	}
+	if string(body) != "01234" {
+		t.Fatalf("expected half of the body before the reset, got %q", body)
+	}
+}
+
+func TestChaosRoutesToggleRules(t *testing.T) {
+	handler, _ := newChaosTestHandler([]ChaosRule{{PathGlob: "/api/**", ErrorStatus: http.StatusBadGateway, ErrorPercent: 100}})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/__ghttp/chaos/chaos-1/disable", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected disable to succeed, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected a disabled rule to pass requests through, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/__ghttp/chaos", nil))
+	var statuses []chaosRuleStatus
+	if decodeErr := json.NewDecoder(recorder.Body).Decode(&statuses); decodeErr != nil {
+		t.Fatalf("decode rules: %v", decodeErr)
+	}
+	if len(statuses) != 1 || statuses[0].Name != "chaos-1" || statuses[0].Enabled || statuses[0].ErrorStatus != http.StatusBadGateway {
+		t.Fatalf("unexpected rule listing %+v", statuses)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/__ghttp/chaos/chaos-1/enable", nil))
+	if !strings.Contains(recorder.Body.String(), `"enabled": true`) {
+		t.Fatalf("expected the enabled rule in the response, got %q", recorder.Body.String())
+	}
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	if recorder.Code != http.StatusBadGateway {
+		t.Fatalf("expected the enabled rule to fail requests, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/__ghttp/chaos/missing/enable", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown rules to return 404, got %d", recorder.Code)
+	}
+}
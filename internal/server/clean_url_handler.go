@@ -0,0 +1,179 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	pathpkg "path"
+	"strings"
+)
+
+// Trailing slash policies accepted by FileServerConfiguration.TrailingSlash.
+const (
+	TrailingSlashAsIs   = "as-is"
+	TrailingSlashAlways = "always"
+	TrailingSlashNever  = "never"
+)
+
+const (
+	htmlFileExtension     = ".html"
+	markdownFileExtension = ".md"
+	indexDocumentName     = "index"
+)
+
+// resolvedTarget describes what a request path refers to on disk.
+type resolvedTarget struct {
+	// filePath is set for clean URLs and names the document to serve.
+	filePath    string
+	isDirectory bool
+	hasIndex    bool
+}
+
+type cleanURLHandler struct {
+	next           http.Handler
+	fileSystem     http.FileSystem
+	cleanURLs      bool
+	redirectHTML   bool
+	enableMarkdown bool
+	trailingSlash  string
+}
+
+// newCleanURLHandler serves /about from about.html, or about.md when markdown
+// is enabled, optionally redirects /about.html to /about, and applies the
+// trailing slash policy. Directories reach the next handler with a trailing
+// slash, which is how the browse and markdown handlers recognise them.
+// Directories without an index document keep their trailing slash under the
+// "never" policy because listings link to their entries relatively.
+func newCleanURLHandler(next http.Handler, fileSystem http.FileSystem, cleanURLs bool, redirectHTML bool, enableMarkdown bool, trailingSlash string) http.Handler {
+	if trailingSlash == "" {
+		trailingSlash = TrailingSlashAsIs
+	}
+	return cleanURLHandler{
+		next:           next,
+		fileSystem:     fileSystem,
+		cleanURLs:      cleanURLs,
+		redirectHTML:   redirectHTML,
+		enableMarkdown: enableMarkdown,
+		trailingSlash:  trailingSlash,
+	}
+}
+
+func (handler cleanURLHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	requestPath := request.URL.Path
+	if requestPath == "" || requestPath == initialFileRootRequestPath || !strings.HasPrefix(requestPath, "/") {
+		handler.next.ServeHTTP(responseWriter, request)
+		return
+	}
+	hasTrailingSlash := strings.HasSuffix(requestPath, "/")
+	trimmedPath := pathpkg.Clean(requestPath)
+
+	if handler.cleanURLs && handler.redirectHTML && !hasTrailingSlash && strings.HasSuffix(trimmedPath, htmlFileExtension) {
+		if redirectPath, redirect := handler.htmlRedirectTarget(trimmedPath); redirect {
+			handler.redirect(responseWriter, request, redirectPath)
+			return
+		}
+	}
+
+	target, found := handler.resolve(trimmedPath)
+	if !found {
+		handler.next.ServeHTTP(responseWriter, request)
+		return
+	}
+	switch handler.trailingSlash {
+	case TrailingSlashAlways:
+		if !hasTrailingSlash {
+			handler.redirect(responseWriter, request, trimmedPath+"/")
+			return
+		}
+	case TrailingSlashNever:
+		slashless := target.filePath != "" || target.hasIndex
+		if hasTrailingSlash && slashless {
+			handler.redirect(responseWriter, request, trimmedPath)
+			return
+		}
+		if !hasTrailingSlash && target.isDirectory && !slashless {
+			handler.redirect(responseWriter, request, trimmedPath+"/")
+			return
+		}
+	}
+	switch {
+	case target.filePath != "":
+		handler.next.ServeHTTP(responseWriter, rewriteRequestPath(request, target.filePath))
+	case target.isDirectory && !hasTrailingSlash && handler.trailingSlash == TrailingSlashNever:
+		handler.next.ServeHTTP(responseWriter, rewriteRequestPath(request, trimmedPath+"/"))
+	default:
+		handler.next.ServeHTTP(responseWriter, request)
+	}
+}
+
+// resolve reports whether the cleaned path names a directory or, for clean
+// URLs, an extensionless document. Existing files are left to the next handler.
+func (handler cleanURLHandler) resolve(trimmedPath string) (resolvedTarget, bool) {
+	if isDirectory, exists := handler.stat(trimmedPath); exists {
+		if !isDirectory {
+			return resolvedTarget{}, false
+		}
+		return resolvedTarget{isDirectory: true, hasIndex: directoryIndexExists(handler.fileSystem, trimmedPath)}, true
+	}
+	if !handler.cleanURLs || pathpkg.Ext(trimmedPath) != "" {
+		return resolvedTarget{}, false
+	}
+	candidateExtensions := []string{htmlFileExtension}
+	if handler.enableMarkdown {
+		candidateExtensions = append(candidateExtensions, markdownFileExtension)
+	}
+	for _, candidateExtension := range candidateExtensions {
+		if isDirectory, exists := handler.stat(trimmedPath + candidateExtension); exists && !isDirectory {
+			return resolvedTarget{filePath: trimmedPath + candidateExtension}, true
+		}
+	}
+	return resolvedTarget{}, false
+}
+
+// htmlRedirectTarget returns the clean URL for an existing .html document,
+// unless the document is a directory index or its clean URL names a directory.
+func (handler cleanURLHandler) htmlRedirectTarget(trimmedPath string) (string, bool) {
+	cleanPath := strings.TrimSuffix(trimmedPath, htmlFileExtension)
+	if pathpkg.Base(cleanPath) == indexDocumentName || cleanPath == "" {
+		return "", false
+	}
+	if isDirectory, exists := handler.stat(trimmedPath); !exists || isDirectory {
+		return "", false
+	}
+	if _, exists := handler.stat(cleanPath); exists {
+		return "", false
+	}
+	if handler.trailingSlash == TrailingSlashAlways {
+		cleanPath += "/"
+	}
+	return cleanPath, true
+}
+
+func (handler cleanURLHandler) stat(requestPath string) (bool, bool) {
+	file, openErr := handler.fileSystem.Open(requestPath)
+	if openErr != nil {
+		return false, false
+	}
+	defer file.Close()
+	fileInfo, statErr := file.Stat()
+	if statErr != nil {
+		return false, false
+	}
+	return fileInfo.IsDir(), true
+}
+
+func (handler cleanURLHandler) redirect(responseWriter http.ResponseWriter, request *http.Request, targetPath string) {
+	location := url.URL{Path: targetPath, RawQuery: request.URL.RawQuery}
+	http.Redirect(responseWriter, request, location.String(), http.StatusMovedPermanently)
+}
+
+// rewriteRequestPath returns a copy of the request addressed to another path
+// of the served tree.
+func rewriteRequestPath(request *http.Request, requestPath string) *http.Request {
+	clonedRequest := request.Clone(request.Context())
+	urlCopy := *request.URL
+	urlCopy.Path = requestPath
+	urlCopy.RawPath = ""
+	clonedRequest.URL = &urlCopy
+	clonedRequest.RequestURI = urlCopy.RequestURI()
+	return clonedRequest
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanURLHandlerAppliesPolicies(t *testing.T) {
+	directoryPath := t.TempDir()
+	for _, directoryName := range []string{"docs", "files"} {
+		if mkdirErr := os.Mkdir(filepath.Join(directoryPath, directoryName), 0o755); mkdirErr != nil {
+			t.Fatalf("create %s: %v", directoryName, mkdirErr)
+		}
+	}
+	writeTestFile(t, filepath.Join(directoryPath, "about.html"), "<p>about page</p>")
+	writeTestFile(t, filepath.Join(directoryPath, "guide.md"), "# Guide\n")
+	writeTestFile(t, filepath.Join(directoryPath, "docs", "index.html"), "<p>docs index</p>")
+	writeTestFile(t, filepath.Join(directoryPath, "files", "a.txt"), "a")
+
+	testCases := []struct {
+		name             string
+		trailingSlash    string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+	}{
+		{name: "clean html", trailingSlash: TrailingSlashAsIs, target: "/about", expectedStatus: http.StatusOK, expectedBody: "about page"},
+		{name: "clean markdown", trailingSlash: TrailingSlashAsIs, target: "/guide", expectedStatus: http.StatusOK, expectedBody: "<h1"},
+		{name: "html redirect keeps query", trailingSlash: TrailingSlashAsIs, target: "/about.html?tab=1", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/about?tab=1"},
+		{name: "missing stays missing", trailingSlash: TrailingSlashAsIs, target: "/contact", expectedStatus: http.StatusNotFound},
+		{name: "always adds slash to documents", trailingSlash: TrailingSlashAlways, target: "/about", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/about/"},
+		{name: "always serves documents with slash", trailingSlash: TrailingSlashAlways, target: "/about/", expectedStatus: http.StatusOK, expectedBody: "about page"},
+		{name: "always redirects html to slash", trailingSlash: TrailingSlashAlways, target: "/about.html", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/about/"},
+		{name: "always leaves assets alone", trailingSlash: TrailingSlashAlways, target: "/files/a.txt", expectedStatus: http.StatusOK, expectedBody: "a"},
+		{name: "never strips slash from documents", trailingSlash: TrailingSlashNever, target: "/about/", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/about"},
+		{name: "never strips slash from indexed directories", trailingSlash: TrailingSlashNever, target: "/docs/", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/docs"},
+		{name: "never serves indexed directories without slash", trailingSlash: TrailingSlashNever, target: "/docs", expectedStatus: http.StatusOK, expectedBody: "docs index"},
+		{name: "never keeps listings under a slash", trailingSlash: TrailingSlashNever, target: "/files", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/files/"},
+		{name: "never serves listings", trailingSlash: TrailingSlashNever, target: "/files/", expectedStatus: http.StatusOK, expectedBody: "a.txt"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := FileServer{}.buildFileHandler(FileServerConfiguration{
+				DirectoryPath:           directoryPath,
+				EnableMarkdown:          true,
+				CleanURLs:               true,
+				RedirectHTMLToCleanURLs: true,
+				TrailingSlash:           testCase.trailingSlash,
+			})
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testCase.target, nil))
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if location := recorder.Header().Get("Location"); location != testCase.expectedLocation {
+				t.Fatalf("expected location %q, got %q", testCase.expectedLocation, location)
+			}
+			if !strings.Contains(recorder.Body.String(), testCase.expectedBody) {
+				t.Fatalf("expected body to contain %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
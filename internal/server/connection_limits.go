@@ -0,0 +1,143 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultReadHeaderTimeout is the ReadHeaderTimeout used when none is set.
+	DefaultReadHeaderTimeout = 15 * time.Second
+	// DefaultShutdownTimeout is the ShutdownTimeout used when none is set.
+	DefaultShutdownTimeout = 3 * time.Second
+)
+
+const (
+	connectionLimitLogInterval       = 10 * time.Second
+	logMessageConnectionLimitReached = "connection limit reached"
+	logFieldMaximumConnections       = "max_connections"
+)
+
+// ServerLimits bounds the time and resources each connection may consume.
+// Zero ReadHeaderTimeout and ShutdownTimeout values select the defaults;
+// the remaining zero values keep the net/http defaults (no limit, or 1 MB of
+// headers), and zero MaxConnections leaves the connection count unbounded.
+type ServerLimits struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	MaxHeaderBytes    int
+	MaxConnections    int
+}
+
+func (limits ServerLimits) readHeaderTimeout() time.Duration {
+	if limits.ReadHeaderTimeout <= 0 {
+		return DefaultReadHeaderTimeout
+	}
+	return limits.ReadHeaderTimeout
+}
+
+func (limits ServerLimits) shutdownTimeout() time.Duration {
+	if limits.ShutdownTimeout <= 0 {
+		return DefaultShutdownTimeout
+	}
+	return limits.ShutdownTimeout
+}
+
+// apply copies the timeouts and header limit onto the server.
+func (limits ServerLimits) apply(server *http.Server) {
+	server.ReadHeaderTimeout = limits.readHeaderTimeout()
+	server.ReadTimeout = limits.ReadTimeout
+	server.WriteTimeout = limits.WriteTimeout
+	server.IdleTimeout = limits.IdleTimeout
+	server.MaxHeaderBytes = limits.MaxHeaderBytes
+}
+
+// connectionLimiter holds the slots shared by every listener of one server so
+// that the cap applies to the process rather than to each address.
+type connectionLimiter struct {
+	slots          chan struct{}
+	onLimitReached func()
+	lastReported   atomic.Int64
+}
+
+func newConnectionLimiter(maximumConnections int, onLimitReached func()) *connectionLimiter {
+	return &connectionLimiter{slots: make(chan struct{}, maximumConnections), onLimitReached: onLimitReached}
+}
+
+// wrap returns a listener whose Accept blocks while the cap is reached,
+// leaving further connections queued in the kernel backlog.
+func (limiter *connectionLimiter) wrap(listener net.Listener) net.Listener {
+	return &connectionLimitListener{Listener: listener, limiter: limiter, closed: make(chan struct{})}
+}
+
+// reportLimitReached notifies at most once per interval so that a sustained
+// flood of connections does not flood the log as well.
+func (limiter *connectionLimiter) reportLimitReached() {
+	now := time.Now().UnixNano()
+	last := limiter.lastReported.Load()
+	if last != 0 && now-last < int64(connectionLimitLogInterval) {
+		return
+	}
+	if limiter.lastReported.CompareAndSwap(last, now) && limiter.onLimitReached != nil {
+		limiter.onLimitReached()
+	}
+}
+
+type connectionLimitListener struct {
+	net.Listener
+	limiter   *connectionLimiter
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (listener *connectionLimitListener) Accept() (net.Conn, error) {
+	select {
+	case listener.limiter.slots <- struct{}{}:
+	default:
+		listener.limiter.reportLimitReached()
+		select {
+		case listener.limiter.slots <- struct{}{}:
+		case <-listener.closed:
+			return nil, net.ErrClosed
+		}
+	}
+	connection, acceptErr := listener.Listener.Accept()
+	if acceptErr != nil {
+		<-listener.limiter.slots
+		return nil, acceptErr
+	}
+	return &limitedConnection{Conn: connection, release: func() { <-listener.limiter.slots }}, nil
+}
+
+func (listener *connectionLimitListener) Close() error {
+	listener.closeOnce.Do(func() {
+		close(listener.closed)
+	})
+	return listener.Listener.Close()
+}
+
+// limitedConnection returns its slot exactly once, however often it is closed.
+type limitedConnection struct {
+	net.Conn
+	release     func()
+	releaseOnce sync.Once
+}
+
+func (connection *limitedConnection) Close() error {
+	closeErr := connection.Conn.Close()
+	connection.releaseOnce.Do(connection.release)
+	return closeErr
+}
+
+// ReadFrom keeps the sendfile path of the wrapped connection available to
+// net/http when serving files.
+func (connection *limitedConnection) ReadFrom(reader io.Reader) (int64, error) {
+	return io.Copy(connection.Conn, reader)
+}
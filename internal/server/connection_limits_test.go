@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConnectionLimitListenerBlocksUntilSlotIsReleased(t *testing.T) {
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil {
+		t.Fatalf("listen: %v", listenErr)
+	}
+	var limitReports atomic.Int32
+	limitedListener := newConnectionLimiter(1, func() { limitReports.Add(1) }).wrap(listener)
+	defer limitedListener.Close()
+
+	for range 2 {
+		clientConnection, dialErr := net.Dial("tcp", listener.Addr().String())
+		if dialErr != nil {
+			t.Fatalf("dial: %v", dialErr)
+		}
+		defer clientConnection.Close()
+	}
+
+	firstConnection, acceptErr := limitedListener.Accept()
+	if acceptErr != nil {
+		t.Fatalf("accept: %v", acceptErr)
+	}
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		secondConnection, secondErr := limitedListener.Accept()
+		if secondErr == nil {
+			accepted <- secondConnection
+		}
+	}()
+
+	select {
+	case <-accepted:
+		t.Fatalf("expected second accept to wait for a free slot")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if limitReports.Load() != 1 {
+		t.Fatalf("expected limit to be reported once, got %d", limitReports.Load())
+	}
+
+	_ = firstConnection.Close()
+	_ = firstConnection.Close()
+	select {
+	case secondConnection := <-accepted:
+		_ = secondConnection.Close()
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected second accept after the first connection closed")
+	}
+}
+
+func TestConnectionLimitListenerCloseUnblocksAccept(t *testing.T) {
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil {
+		t.Fatalf("listen: %v", listenErr)
+	}
+	limiter := newConnectionLimiter(1, nil)
+	limiter.slots <- struct{}{}
+	limitedListener := limiter.wrap(listener)
+
+	acceptErrors := make(chan error, 1)
+	go func() {
+		_, acceptErr := limitedListener.Accept()
+		acceptErrors <- acceptErr
+	}()
+	time.Sleep(50 * time.Millisecond)
+	_ = limitedListener.Close()
+
+	select {
+	case acceptErr := <-acceptErrors:
+		if acceptErr == nil {
+			t.Fatalf("expected accept to fail after close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected close to unblock accept")
+	}
+}
+
+func TestServerLimitsApplyDefaults(t *testing.T) {
+	server := &http.Server{}
+	ServerLimits{WriteTimeout: time.Minute, MaxHeaderBytes: 4096}.apply(server)
+	if server.ReadHeaderTimeout != DefaultReadHeaderTimeout {
+		t.Fatalf("expected default read header timeout, got %s", server.ReadHeaderTimeout)
+	}
+	if server.WriteTimeout != time.Minute || server.MaxHeaderBytes != 4096 {
+		t.Fatalf("unexpected server limits: write %s, header bytes %d", server.WriteTimeout, server.MaxHeaderBytes)
+	}
+	if timeout := (ServerLimits{}).shutdownTimeout(); timeout != DefaultShutdownTimeout {
+		t.Fatalf("expected default shutdown timeout, got %s", timeout)
+	}
+}
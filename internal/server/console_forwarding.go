@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+const (
+	consoleForwardPath        = internalRoutePrefix + "console"
+	consoleForwardScriptPath  = internalRoutePrefix + "console.js"
+	consoleForwardMaxBody     = 64 << 10
+	consoleLevelError         = "error"
+	consoleLevelWarn          = "warn"
+	logMessageBrowserConsole  = "browser console"
+	logFieldConsoleLevel      = "level"
+	logFieldConsoleMessage    = "message"
+	logFieldConsolePage       = "page"
+	logFieldConsoleStack      = "stack"
+	logFieldClientIP          = "client_ip"
+	logFieldUserAgent         = "user_agent"
+	userAgentHeaderName       = "User-Agent"
+	consoleForwardSnippetHTML = `<script src="` + consoleForwardScriptPath + `"></script>`
+)
+
+// consoleForwardScript reports console calls, uncaught errors and unhandled
+// promise rejections to consoleForwardPath. The original console methods
+// still run, and failures to report are swallowed so that the page never
+// sees them.
+const consoleForwardScript = `(function () {
+  if (!window.fetch || !window.JSON) {
+    return;
+  }
+  var sending = false;
+  function describe(value) {
+    if (value instanceof Error) {
+      return value.stack || String(value);
+    }
+    if (typeof value === "string") {
+      return value;
+    }
+    try {
+      return JSON.stringify(value);
+    } catch (error) {
+      return String(value);
+    }
+  }
+  function send(level, values, stack) {
+    if (sending) {
+      return;
+    }
+    sending = true;
+    try {
+      fetch("` + consoleForwardPath + `", {
+        method: "POST",
+        headers: {"Content-Type": "application/json"},
+        body: JSON.stringify({
+          level: level,
+          message: Array.prototype.map.call(values, describe).join(" "),
+          page: window.location.href,
+          stack: stack || ""
+        }),
+        keepalive: true
+      }).catch(function () {});
+    } catch (error) {
+    } finally {
+      sending = false;
+    }
+  }
+  ["log", "info", "warn", "error", "debug"].forEach(function (level) {
+    var original = console[level];
+    if (typeof original !== "function") {
+      return;
+    }
+    console[level] = function () {
+      send(level, arguments);
+      return original.apply(console, arguments);
+    };
+  });
+  window.addEventListener("error", function (event) {
+    var location = event.filename ? " (" + event.filename + ":" + event.lineno + ":" + event.colno + ")" : "";
+    send("error", ["Uncaught " + event.message + location], event.error && event.error.stack);
+  });
+  window.addEventListener("unhandledrejection", function (event) {
+    var reason = event.reason;
+    send("error", ["Unhandled rejection:", reason], reason && reason.stack);
+  });
+})();
+`
+
+// consoleEntry is one console call or error reported by the browser.
+type consoleEntry struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Page    string `json:"page"`
+	Stack   string `json:"stack"`
+}
+
+// registerConsoleForwardRoutes mounts the console script and the endpoint
+// that logs what it reports. Errors are logged at error level and warnings
+// at warning level; everything else is informational.
+func registerConsoleForwardRoutes(routes *http.ServeMux, loggingService *logging.Service) {
+	routes.HandleFunc("GET "+consoleForwardScriptPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set(contentTypeHeaderName, javaScriptContentType)
+		responseWriter.Header().Set(cacheControlHeaderName, cacheControlNoCache)
+		_, _ = responseWriter.Write([]byte(consoleForwardScript))
+	})
+	routes.HandleFunc("POST "+consoleForwardPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		var entry consoleEntry
+		if decodeErr := json.NewDecoder(http.MaxBytesReader(responseWriter, request.Body, consoleForwardMaxBody)).Decode(&entry); decodeErr != nil {
+			http.Error(responseWriter, "invalid console entry", http.StatusBadRequest)
+			return
+		}
+		if loggingService != nil {
+			level := strings.ToLower(strings.TrimSpace(entry.Level))
+			fields := []logging.Field{
+				logging.String(logFieldConsoleLevel, level),
+				logging.String(logFieldConsoleMessage, entry.Message),
+				logging.String(logFieldConsolePage, entry.Page),
+				logging.String(logFieldClientIP, clientIP(request)),
+				logging.String(logFieldUserAgent, request.Header.Get(userAgentHeaderName)),
+			}
+			if entry.Stack != "" {
+				fields = append(fields, logging.String(logFieldConsoleStack, entry.Stack))
+			}
+			switch level {
+			case consoleLevelError:
+				loggingService.Error(logMessageBrowserConsole, nil, fields...)
+			case consoleLevelWarn:
+				loggingService.Warn(logMessageBrowserConsole, fields...)
+			default:
+				loggingService.Info(logMessageBrowserConsole, fields...)
+			}
+		}
+		responseWriter.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+func TestConsoleForwardingLogsBrowserEntries(t *testing.T) {
+	testCases := []struct {
+		name            string
+		loggingType     string
+		body            string
+		expectedLevel   zapcore.Level
+		expectedMessage string
+		expectedFields  map[string]string
+	}{
+		{
+			name:            "json error",
+			loggingType:     logging.TypeJSON,
+			body:            `{"level":"error","message":"Uncaught TypeError: x is undefined","page":"http://phone.test/app/","stack":"at main (app.js:3:7)"}`,
+			expectedLevel:   zapcore.ErrorLevel,
+			expectedMessage: logMessageBrowserConsole,
+			expectedFields:  map[string]string{"level": "error", "client_ip": "192.0.2.1", "user_agent": "PhoneBrowser/1.0", "page": "http://phone.test/app/", "stack": "at main (app.js:3:7)"},
+		},
+		{
+			name:            "json warning",
+			loggingType:     logging.TypeJSON,
+			body:            `{"level":"warn","message":"deprecated","page":"http://phone.test/"}`,
+			expectedLevel:   zapcore.WarnLevel,
+			expectedMessage: logMessageBrowserConsole,
+			expectedFields:  map[string]string{"level": "warn", "message": "deprecated"},
+		},
+		{
+			name:            "console log",
+			loggingType:     logging.TypeConsole,
+			body:            `{"level":"log","message":"hello 42","page":"http://phone.test/"}`,
+			expectedLevel:   zapcore.InfoLevel,
+			expectedMessage: `browser console level="log" message="hello 42" page="http://phone.test/" client_ip="192.0.2.1" user_agent="PhoneBrowser/1.0"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			observedCore, observedLogs := observer.New(zapcore.DebugLevel)
+			loggingService, serviceErr := logging.NewServiceWithLogger(testCase.loggingType, zap.New(observedCore))
+			if serviceErr != nil {
+				t.Fatalf("logging service: %v", serviceErr)
+			}
+			routes := http.NewServeMux()
+			registerConsoleForwardRoutes(routes, loggingService)
+
+			request := httptest.NewRequest(http.MethodPost, "/__ghttp/console", strings.NewReader(testCase.body))
+			request.Header.Set("User-Agent", "PhoneBrowser/1.0")
+			recorder := httptest.NewRecorder()
+			routes.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusNoContent {
+				t.Fatalf("expected status 204, got %d", recorder.Code)
+			}
+			entries := observedLogs.All()
+			if len(entries) != 1 {
+				t.Fatalf("expected one log entry, got %d", len(entries))
+			}
+			if testCase.loggingType == logging.TypeJSON && entries[0].Level != testCase.expectedLevel {
+				t.Fatalf("expected level %s, got %s", testCase.expectedLevel, entries[0].Level)
+			}
+			if entries[0].Message != testCase.expectedMessage {
+				t.Fatalf("expected message %q, got %q", testCase.expectedMessage, entries[0].Message)
+			}
+			fields := entries[0].ContextMap()
+			for key, expectedValue := range testCase.expectedFields {
+				if fields[key] != expectedValue {
+					t.Fatalf("expected field %s=%q, got %v", key, expectedValue, fields[key])
+				}
+			}
+		})
+	}
+}
+
+func TestConsoleForwardingRejectsMalformedEntries(t *testing.T) {
+	routes := http.NewServeMux()
+	registerConsoleForwardRoutes(routes, logging.NewTestService(logging.TypeConsole))
+
+	recorder := httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/__ghttp/console", strings.NewReader("not json")))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/__ghttp/console.js", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "unhandledrejection") {
+		t.Fatalf("expected the console script, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+const (
+	contentSecurityPolicyHeaderName           = "Content-Security-Policy"
+	contentSecurityPolicyReportOnlyHeaderName = "Content-Security-Policy-Report-Only"
+	reportingEndpointsHeaderName              = "Reporting-Endpoints"
+	cspReportsPath                            = internalRoutePrefix + "csp-reports"
+	cspSummaryPath                            = internalRoutePrefix + "csp"
+	cspReportingEndpointName                  = "ghttp-csp"
+	cspReportToDirective                      = "report-to"
+	cspReportURIDirective                     = "report-uri"
+	cspLegacyReportMediaType                  = "application/csp-report"
+	cspReportViolationType                    = "csp-violation"
+	cspMaxReportBody                          = 256 << 10
+	// cspMaxDistinctViolations bounds the summary. Violations beyond it are
+	// logged every time instead of once.
+	cspMaxDistinctViolations = 1000
+	logMessageCSPViolation   = "csp violation"
+	logFieldDirective        = "directive"
+	logFieldBlockedURI       = "blocked_uri"
+	logFieldDocumentURI      = "document_uri"
+	logFieldSourceFile       = "source_file"
+	logFieldLineNumber       = "line"
+	logFieldDisposition      = "disposition"
+)
+
+// ContentSecurityPolicyConfiguration sets Content-Security-Policy headers and
+// collects the violation reports browsers send for them.
+type ContentSecurityPolicyConfiguration struct {
+	// Policy is enforced through Content-Security-Policy.
+	Policy string
+	// ReportOnlyPolicy is sent as Content-Security-Policy-Report-Only, so
+	// violations are reported without blocking anything.
+	ReportOnlyPolicy string
+}
+
+// cspViolation is a violation report reduced to the parts that identify it.
+type cspViolation struct {
+	Directive   string
+	BlockedURI  string
+	DocumentURI string
+	SourceFile  string
+	LineNumber  int
+	Disposition string
+}
+
+type cspViolationRecord struct {
+	violation cspViolation
+	count     int
+	lastSeen  time.Time
+}
+
+// cspCollector deduplicates violation reports, logging each distinct one
+// once, and keeps counts for the summary page.
+type cspCollector struct {
+	loggingService *logging.Service
+	mutex          sync.Mutex
+	records        map[cspViolation]*cspViolationRecord
+}
+
+func newCSPCollector(loggingService *logging.Service) *cspCollector {
+	return &cspCollector{loggingService: loggingService, records: map[cspViolation]*cspViolationRecord{}}
+}
+
+// newContentSecurityPolicyHandler adds the configured policies to every
+// response. Policies without their own reporting directives report to the
+// collector through both report-to, with a Reporting-Endpoints header, and
+// report-uri for browsers without the Reporting API.
+func newContentSecurityPolicyHandler(next http.Handler, configuration ContentSecurityPolicyConfiguration) http.Handler {
+	policy := withCSPReporting(configuration.Policy)
+	reportOnlyPolicy := withCSPReporting(configuration.ReportOnlyPolicy)
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		header := responseWriter.Header()
+		header.Set(reportingEndpointsHeaderName, cspReportingEndpointName+`="`+cspReportsPath+`"`)
+		if policy != "" {
+			header.Set(contentSecurityPolicyHeaderName, policy)
+		}
+		if reportOnlyPolicy != "" {
+			header.Set(contentSecurityPolicyReportOnlyHeaderName, reportOnlyPolicy)
+		}
+		next.ServeHTTP(responseWriter, request)
+	})
+}
+
+func withCSPReporting(policy string) string {
+	trimmedPolicy := strings.TrimRight(strings.TrimSpace(policy), "; ")
+	if trimmedPolicy == "" {
+		return ""
+	}
+	for _, directive := range strings.Split(trimmedPolicy, ";") {
+		directiveName, _, _ := strings.Cut(strings.TrimSpace(directive), " ")
+		if strings.EqualFold(directiveName, cspReportToDirective) || strings.EqualFold(directiveName, cspReportURIDirective) {
+			return trimmedPolicy
+		}
+	}
+	return trimmedPolicy + "; " + cspReportToDirective + " " + cspReportingEndpointName + "; " + cspReportURIDirective + " " + cspReportsPath
+}
+
+// registerCSPRoutes mounts the report endpoint and the summary page.
+func registerCSPRoutes(routes *http.ServeMux, collector *cspCollector) {
+	routes.HandleFunc("POST "+cspReportsPath, collector.serveReports)
+	routes.HandleFunc("GET "+cspSummaryPath, collector.serveSummary)
+}
+
+// serveReports accepts both the legacy report-uri format, a single
+// {"csp-report": {...}} object, and Reporting API batches of reports.
+func (collector *cspCollector) serveReports(responseWriter http.ResponseWriter, request *http.Request) {
+	body, readErr := io.ReadAll(http.MaxBytesReader(responseWriter, request.Body, cspMaxReportBody))
+	if readErr != nil {
+		http.Error(responseWriter, "report too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	violations, parseErr := parseCSPReports(request.Header.Get(contentTypeHeaderName), body)
+	if parseErr != nil {
+		http.Error(responseWriter, "invalid report", http.StatusBadRequest)
+		return
+	}
+	for _, violation := range violations {
+		collector.record(violation, request)
+	}
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+type cspLegacyReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+type cspReportingAPIReport struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+func parseCSPReports(contentType string, body []byte) ([]cspViolation, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == cspLegacyReportMediaType || !strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+		var legacyReport cspLegacyReport
+		if decodeErr := json.Unmarshal(body, &legacyReport); decodeErr != nil {
+			return nil, decodeErr
+		}
+		directive := legacyReport.Report.EffectiveDirective
+		if directive == "" {
+			directive, _, _ = strings.Cut(legacyReport.Report.ViolatedDirective, " ")
+		}
+		return []cspViolation{{
+			Directive:   directive,
+			BlockedURI:  legacyReport.Report.BlockedURI,
+			DocumentURI: legacyReport.Report.DocumentURI,
+			SourceFile:  legacyReport.Report.SourceFile,
+			LineNumber:  legacyReport.Report.LineNumber,
+			Disposition: legacyReport.Report.Disposition,
+		}}, nil
+	}
+	var reports []cspReportingAPIReport
+	if decodeErr := json.Unmarshal(body, &reports); decodeErr != nil {
+		return nil, decodeErr
+	}
+	violations := make([]cspViolation, 0, len(reports))
+	for _, report := range reports {
+		if report.Type != cspReportViolationType {
+			continue
+		}
+		documentURI := report.Body.DocumentURL
+		if documentURI == "" {
+			documentURI = report.URL
+		}
+		violations = append(violations, cspViolation{
+			Directive:   report.Body.EffectiveDirective,
+			BlockedURI:  report.Body.BlockedURL,
+			DocumentURI: documentURI,
+			SourceFile:  report.Body.SourceFile,
+			LineNumber:  report.Body.LineNumber,
+			Disposition: report.Body.Disposition,
+		})
+	}
+	return violations, nil
+}
+
+func (collector *cspCollector) record(violation cspViolation, request *http.Request) {
+	collector.mutex.Lock()
+	existingRecord, seen := collector.records[violation]
+	if seen {
+		existingRecord.count++
+		existingRecord.lastSeen = time.Now()
+	} else if len(collector.records) < cspMaxDistinctViolations {
+		collector.records[violation] = &cspViolationRecord{violation: violation, count: 1, lastSeen: time.Now()}
+	}
+	collector.mutex.Unlock()
+	if seen || collector.loggingService == nil {
+		return
+	}
+	collector.loggingService.Warn(logMessageCSPViolation,
+		logging.String(logFieldDirective, violation.Directive),
+		logging.String(logFieldBlockedURI, violation.BlockedURI),
+		logging.String(logFieldDocumentURI, violation.DocumentURI),
+		logging.String(logFieldSourceFile, violation.SourceFile),
+		logging.Int(logFieldLineNumber, violation.LineNumber),
+		logging.String(logFieldDisposition, violation.Disposition),
+		logging.String(logFieldClientIP, clientIP(request)),
+		logging.String(logFieldUserAgent, request.Header.Get(userAgentHeaderName)),
+	)
+}
+
+// cspSummaryGroup is every report for one directive and blocked URI.
+type cspSummaryGroup struct {
+	directive  string
+	blockedURI string
+	count      int
+	documents  map[string]bool
+	lastSeen   time.Time
+}
+
+// serveSummary renders the collected violations grouped by directive and
+// blocked URI, most frequent first.
+func (collector *cspCollector) serveSummary(responseWriter http.ResponseWriter, request *http.Request) {
+	groups := map[[2]string]*cspSummaryGroup{}
+	collector.mutex.Lock()
+	for _, record := range collector.records {
+		key := [2]string{record.violation.Directive, record.violation.BlockedURI}
+		group, found := groups[key]
+		if !found {
+			group = &cspSummaryGroup{directive: key[0], blockedURI: key[1], documents: map[string]bool{}}
+			groups[key] = group
+		}
+		group.count += record.count
+		group.documents[record.violation.DocumentURI] = true
+		if record.lastSeen.After(group.lastSeen) {
+			group.lastSeen = record.lastSeen
+		}
+	}
+	collector.mutex.Unlock()
+
+	sortedGroups := make([]*cspSummaryGroup, 0, len(groups))
+	for _, group := range groups {
+		sortedGroups = append(sortedGroups, group)
+	}
+	sort.Slice(sortedGroups, func(first int, second int) bool {
+		if sortedGroups[first].directive != sortedGroups[second].directive {
+			return sortedGroups[first].directive < sortedGroups[second].directive
+		}
+		if sortedGroups[first].count != sortedGroups[second].count {
+			return sortedGroups[first].count > sortedGroups[second].count
+		}
+		return sortedGroups[first].blockedURI < sortedGroups[second].blockedURI
+	})
+
+	var body strings.Builder
+	if len(sortedGroups) == 0 {
+		body.WriteString("<p>No violations reported yet.</p>")
+	} else {
+		body.WriteString("<table><thead><tr><th>Directive</th><th>Blocked URI</th><th>Reports</th><th>Documents</th><th>Last seen</th></tr></thead><tbody>")
+		for _, group := range sortedGroups {
+			documents := make([]string, 0, len(group.documents))
+			for document := range group.documents {
+				documents = append(documents, html.EscapeString(document))
+			}
+			sort.Strings(documents)
+			body.WriteString("<tr><td>" + html.EscapeString(group.directive) + "</td><td>" + html.EscapeString(group.blockedURI) + "</td><td>" + strconv.Itoa(group.count) + "</td><td>" + strings.Join(documents, "<br>") + "</td><td>" + group.lastSeen.Format(time.TimeOnly) + "</td></tr>")
+		}
+		body.WriteString("</tbody></table>")
+	}
+	responseWriter.Header().Set(contentTypeHeaderName, htmlContentType)
+	responseWriter.Header().Set(cacheControlHeaderName, cacheControlNoCache)
+	_, _ = io.WriteString(responseWriter, "<!DOCTYPE html><html lang=\"en\"><head><meta charset=\"utf-8\"><title>CSP violations</title></head><body><h1>CSP violations</h1>"+body.String()+"</body></html>")
+}
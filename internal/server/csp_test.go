@@ -0,0 +1,108 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+func TestContentSecurityPolicyHandlerSetsHeaders(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		configuration            ContentSecurityPolicyConfiguration
+		expectedPolicy           string
+		expectedReportOnlyPolicy string
+	}{
+		{
+			name:           "enforced policy gains reporting",
+			configuration:  ContentSecurityPolicyConfiguration{Policy: "default-src 'self';"},
+			expectedPolicy: "default-src 'self'; report-to ghttp-csp; report-uri /__ghttp/csp-reports",
+		},
+		{
+			name:                     "own reporting is kept",
+			configuration:            ContentSecurityPolicyConfiguration{Policy: "img-src *", ReportOnlyPolicy: "script-src 'self'; report-uri https://reports.test/csp"},
+			expectedPolicy:           "img-src *; report-to ghttp-csp; report-uri /__ghttp/csp-reports",
+			expectedReportOnlyPolicy: "script-src 'self'; report-uri https://reports.test/csp",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := newContentSecurityPolicyHandler(http.NotFoundHandler(), testCase.configuration)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if policy := recorder.Header().Get("Content-Security-Policy"); policy != testCase.expectedPolicy {
+				t.Fatalf("expected policy %q, got %q", testCase.expectedPolicy, policy)
+			}
+			if policy := recorder.Header().Get("Content-Security-Policy-Report-Only"); policy != testCase.expectedReportOnlyPolicy {
+				t.Fatalf("expected report-only policy %q, got %q", testCase.expectedReportOnlyPolicy, policy)
+			}
+			if endpoints := recorder.Header().Get("Reporting-Endpoints"); endpoints != `ghttp-csp="/__ghttp/csp-reports"` {
+				t.Fatalf("unexpected reporting endpoints %q", endpoints)
+			}
+		})
+	}
+}
+
+func TestCSPCollectorDeduplicatesAndSummarizesReports(t *testing.T) {
+	observedCore, observedLogs := observer.New(zapcore.DebugLevel)
+	loggingService, serviceErr := logging.NewServiceWithLogger(logging.TypeJSON, zap.New(observedCore))
+	if serviceErr != nil {
+		t.Fatalf("logging service: %v", serviceErr)
+	}
+	routes := http.NewServeMux()
+	registerCSPRoutes(routes, newCSPCollector(loggingService))
+
+	legacyReport := `{"csp-report":{"document-uri":"http://localhost/","violated-directive":"script-src-elem 'self'","blocked-uri":"https://cdn.test/lib.js","disposition":"enforce"}}`
+	reportingAPIBatch := `[{"type":"csp-violation","url":"http://localhost/about","body":{"documentURL":"http://localhost/about","effectiveDirective":"script-src-elem","blockedURL":"https://cdn.test/lib.js","disposition":"enforce"}},{"type":"deprecation","body":{}},{"type":"csp-violation","body":{"documentURL":"http://localhost/","effectiveDirective":"img-src","blockedURL":"data","disposition":"report"}}]`
+	submissions := []struct {
+		contentType string
+		body        string
+	}{
+		{contentType: "application/csp-report", body: legacyReport},
+		{contentType: "application/csp-report", body: legacyReport},
+		{contentType: "application/reports+json", body: reportingAPIBatch},
+	}
+	for _, submission := range submissions {
+		request := httptest.NewRequest(http.MethodPost, "/__ghttp/csp-reports", strings.NewReader(submission.body))
+		request.Header.Set("Content-Type", submission.contentType)
+		recorder := httptest.NewRecorder()
+		routes.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", recorder.Code)
+		}
+	}
+
+	entries := observedLogs.FilterMessage("csp violation").All()
+	if len(entries) != 3 {
+		t.Fatalf("expected three distinct violations to be logged, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["directive"] != "script-src-elem" || fields["blocked_uri"] != "https://cdn.test/lib.js" || fields["document_uri"] != "http://localhost/" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	recorder := httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/__ghttp/csp", nil))
+	summary := recorder.Body.String()
+	if !strings.Contains(summary, "<td>script-src-elem</td><td>https://cdn.test/lib.js</td><td>3</td><td>http://localhost/<br>http://localhost/about</td>") {
+		t.Fatalf("expected grouped script violations in summary, got %q", summary)
+	}
+	if !strings.Contains(summary, "<td>img-src</td><td>data</td><td>1</td>") {
+		t.Fatalf("expected image violation in summary, got %q", summary)
+	}
+
+	recorder = httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/__ghttp/csp-reports", strings.NewReader("{")))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected malformed reports to be rejected, got %d", recorder.Code)
+	}
+}
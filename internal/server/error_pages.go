@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	pathpkg "path"
+	"strconv"
+)
+
+const (
+	jsonMediaType              = "application/json"
+	jsonContentType            = "application/json; charset=utf-8"
+	errorPageExtension         = ".html"
+	errorPageClassSuffix       = "xx"
+	contentTypeOptionsHeader   = "X-Content-Type-Options"
+	minimumErrorPageStatusCode = 400
+)
+
+// ErrorPagesConfiguration enables custom error documents.
+type ErrorPagesConfiguration struct {
+	// Directory, when set, is searched for error documents before the served
+	// directory.
+	Directory string
+}
+
+// errorPageData is available to error document templates.
+type errorPageData struct {
+	Status     int    `json:"status"`
+	StatusText string `json:"error"`
+	Path       string `json:"path"`
+	Method     string `json:"-"`
+}
+
+// newErrorPageHandler replaces error responses with a JSON body when the
+// client prefers JSON, and otherwise with the first error document found for
+// the status, such as 404.html, then 4xx.html. Documents are read on every
+// error so that edits show up immediately, and are rendered as html/template
+// templates with errorPageData. Responses without a matching document keep
+// their original body.
+func newErrorPageHandler(next http.Handler, fileSystems []http.FileSystem) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		errorWriter := &errorPageWriter{ResponseWriter: responseWriter, request: request, fileSystems: fileSystems}
+		next.ServeHTTP(errorWriter, request)
+		errorWriter.finish()
+	})
+}
+
+type errorPageWriter struct {
+	http.ResponseWriter
+	request       *http.Request
+	fileSystems   []http.FileSystem
+	headerWritten bool
+	replacement   []byte
+	statusCode    int
+}
+
+func (writer *errorPageWriter) WriteHeader(statusCode int) {
+	if writer.headerWritten {
+		return
+	}
+	writer.headerWritten = true
+	if statusCode >= minimumErrorPageStatusCode {
+		if replacement, contentType, found := writer.render(statusCode); found {
+			writer.statusCode = statusCode
+			writer.replacement = replacement
+			writer.Header().Set(contentTypeHeaderName, contentType)
+			writer.Header().Del(contentLengthHeaderName)
+			writer.Header().Del(contentTypeOptionsHeader)
+			return
+		}
+	}
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *errorPageWriter) Write(content []byte) (int, error) {
+	if !writer.headerWritten {
+		writer.WriteHeader(http.StatusOK)
+	}
+	if writer.replacement != nil {
+		return len(content), nil
+	}
+	return writer.ResponseWriter.Write(content)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (writer *errorPageWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+func (writer *errorPageWriter) finish() {
+	if writer.replacement == nil {
+		return
+	}
+	writer.Header().Set(contentLengthHeaderName, strconv.Itoa(len(writer.replacement)))
+	writer.ResponseWriter.WriteHeader(writer.statusCode)
+	if writer.request.Method != http.MethodHead {
+		_, _ = writer.ResponseWriter.Write(writer.replacement)
+	}
+}
+
+func (writer *errorPageWriter) render(statusCode int) ([]byte, string, bool) {
+	data := errorPageData{
+		Status:     statusCode,
+		StatusText: http.StatusText(statusCode),
+		Path:       writer.request.URL.Path,
+		Method:     writer.request.Method,
+	}
+	if prefersMediaType(writer.request.Header.Get(acceptHeaderName), jsonMediaType) {
+		encoded, encodeErr := json.Marshal(data)
+		if encodeErr != nil {
+			return nil, "", false
+		}
+		return append(encoded, '\n'), jsonContentType, true
+	}
+	document, found := readErrorDocument(writer.fileSystems, statusCode)
+	if !found {
+		return nil, "", false
+	}
+	errorTemplate, parseErr := template.New(strconv.Itoa(statusCode)).Parse(string(document))
+	if parseErr != nil {
+		return nil, "", false
+	}
+	var rendered bytes.Buffer
+	if executeErr := errorTemplate.Execute(&rendered, data); executeErr != nil {
+		return nil, "", false
+	}
+	return rendered.Bytes(), htmlContentType, true
+}
+
+// readErrorDocument returns the most specific error document for the status
+// from the first file system that has one.
+func readErrorDocument(fileSystems []http.FileSystem, statusCode int) ([]byte, bool) {
+	statusName := strconv.Itoa(statusCode)
+	candidateNames := []string{statusName + errorPageExtension, statusName[:1] + errorPageClassSuffix + errorPageExtension}
+	for _, fileSystem := range fileSystems {
+		for _, candidateName := range candidateNames {
+			documentFile, openErr := fileSystem.Open(pathpkg.Join(initialFileRootRequestPath, candidateName))
+			if openErr != nil {
+				continue
+			}
+			documentInfo, statErr := documentFile.Stat()
+			if statErr != nil || documentInfo.IsDir() {
+				documentFile.Close()
+				continue
+			}
+			document, readErr := io.ReadAll(documentFile)
+			documentFile.Close()
+			if readErr == nil {
+				return document, true
+			}
+		}
+	}
+	return nil, false
+}
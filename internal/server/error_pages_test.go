@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorPageHandlerRendersDocuments(t *testing.T) {
+	servedDirectory := t.TempDir()
+	overrideDirectory := t.TempDir()
+	writeTestFile(t, filepath.Join(servedDirectory, "404.html"), "<h1>{{.Status}} {{.StatusText}}</h1><p>{{.Path}}</p>")
+	writeTestFile(t, filepath.Join(servedDirectory, "4xx.html"), "<h1>client error {{.Status}}</h1>")
+	writeTestFile(t, filepath.Join(overrideDirectory, "5xx.html"), "<h1>server error {{.Status}}</h1>")
+	errorPageFileSystems := []http.FileSystem{http.Dir(overrideDirectory), http.Dir(servedDirectory)}
+	statusHandler := http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/forbidden/":
+			http.Error(responseWriter, errorMessageDirectoryListingDisabled, http.StatusForbidden)
+		case "/broken":
+			http.Error(responseWriter, "boom", http.StatusBadGateway)
+		case "/ok":
+			_, _ = responseWriter.Write([]byte("fine"))
+		default:
+			http.NotFound(responseWriter, request)
+		}
+	})
+	handler := newErrorPageHandler(statusHandler, errorPageFileSystems)
+
+	testCases := []struct {
+		name           string
+		target         string
+		accept         string
+		expectedStatus int
+		expectedBody   string
+		expectedType   string
+	}{
+		{name: "status document with escaped path", target: "/missing/<b>", expectedStatus: http.StatusNotFound, expectedBody: "<h1>404 Not Found</h1><p>/missing/&lt;b&gt;</p>", expectedType: htmlContentType},
+		{name: "class document", target: "/forbidden/", expectedStatus: http.StatusForbidden, expectedBody: "<h1>client error 403</h1>", expectedType: htmlContentType},
+		{name: "configured directory", target: "/broken", expectedStatus: http.StatusBadGateway, expectedBody: "<h1>server error 502</h1>", expectedType: htmlContentType},
+		{name: "json preferred", target: "/missing", accept: "application/json", expectedStatus: http.StatusNotFound, expectedBody: `{"status":404,"error":"Not Found","path":"/missing"}` + "\n", expectedType: jsonContentType},
+		{name: "successful response untouched", target: "/ok", expectedStatus: http.StatusOK, expectedBody: "fine"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.URL.Path = testCase.target
+			if testCase.accept != "" {
+				request.Header.Set("Accept", testCase.accept)
+			}
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if recorder.Body.String() != testCase.expectedBody {
+				t.Fatalf("expected body %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+			if testCase.expectedType != "" && recorder.Header().Get("Content-Type") != testCase.expectedType {
+				t.Fatalf("expected content type %q, got %q", testCase.expectedType, recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestErrorPageHandlerKeepsResponsesWithoutDocuments(t *testing.T) {
+	handler := newErrorPageHandler(http.NotFoundHandler(), []http.FileSystem{http.Dir(t.TempDir())})
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if recorder.Code != http.StatusNotFound || !strings.Contains(recorder.Body.String(), "404 page not found") {
+		t.Fatalf("expected the default not found response, got %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+func writeTestFile(t *testing.T, filePath string, content string) {
+	t.Helper()
+	if writeErr := os.WriteFile(filePath, []byte(content), 0o644); writeErr != nil {
+		t.Fatalf("write %s: %v", filePath, writeErr)
+	}
+}
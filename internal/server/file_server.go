@@ -7,13 +7,19 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/temirov/ghttp/internal/proxyprotocol"
 	"github.com/temirov/ghttp/internal/serverdetails"
+	"github.com/temirov/ghttp/internal/watch"
 	"github.com/temirov/ghttp/pkg/logging"
 )
 
@@ -24,30 +30,45 @@ const (
 	connectionHeaderName                 = "Connection"
 	connectionCloseValue                 = "close"
 	httpProtocolVersionOneZero           = "HTTP/1.0"
+	httpProtocolVersionTwo               = "HTTP/2"
+	protocolLabelHTTP2TLS                = "h2"
+	protocolLabelHTTP2Cleartext          = "h2c"
 	errorMessageDirectoryListingDisabled = "Directory listing disabled"
 	consoleRequestTimeLayout             = "02/Jan/2006 15:04:05"
 	logFieldDirectory                    = "directory"
 	logFieldProtocol                     = "protocol"
 	logFieldURL                          = "url"
+	logFieldURLs                         = "urls"
+	logFieldRedirectTarget               = "redirect_target"
+	logFieldSocket                       = "socket"
+	logFieldPort                         = "port"
+	logFieldRequestedPort                = "requested_port"
+	portFilePermissions                  = 0o644
 	logFieldMethod                       = "method"
 	logFieldPath                         = "path"
 	logFieldRemote                       = "remote"
+	logFieldPeer                         = "peer"
 	logFieldDuration                     = "duration"
 	logFieldStatus                       = "status"
 	logFieldTimestamp                    = "timestamp"
 	logMessageServingHTTP                = "serving http"
 	logMessageServingHTTPS               = "serving https"
+	logMessageRedirectingHTTP            = "redirecting http"
+	logMessagePortFallback               = "port fallback"
+	logMessageHandoffRequested           = "listener handoff requested"
+	logMessageHandoffFailed              = "listener handoff failed"
+	logMessageHandoffCompleted           = "listeners handed off"
+	logFieldProcessID                    = "pid"
 	logMessageShutdownInitiated          = "shutdown initiated"
 	logMessageShutdownCompleted          = "shutdown completed"
 	logMessageShutdownFailed             = "shutdown failed"
 	logMessageServerError                = "server error"
 	logMessageRequestStarted             = "request started"
 	logMessageRequestCompleted           = "request completed"
-	shutdownGracePeriod                  = 3 * time.Second
 )
 
 type FileServerConfiguration struct {
-	BindAddress             string
+	BindAddresses           []string
 	Port                    string
 	DirectoryPath           string
 	ProtocolVersion         string
@@ -55,8 +76,99 @@ type FileServerConfiguration struct {
 	EnableMarkdown          bool
 	BrowseDirectories       bool
 	InitialFileRelativePath string
+	// SPAFallbackRelativePath, when set, names the document served for
+	// client-side routes that do not exist on disk.
+	SPAFallbackRelativePath string
+	// ErrorPages, when set, replaces error responses with custom documents
+	// or JSON bodies.
+	ErrorPages *ErrorPagesConfiguration
+	// CleanURLs serves extensionless paths from .html documents, and from
+	// .md documents when markdown is enabled.
+	CleanURLs bool
+	// RedirectHTMLToCleanURLs permanently redirects /page.html to /page.
+	RedirectHTMLToCleanURLs bool
+	// TrailingSlash is TrailingSlashAsIs, TrailingSlashAlways or TrailingSlashNever.
+	TrailingSlash string
+	// Rules redirect or rewrite requests before files are looked up.
+	Rules []Rule
+	// MockDirectory, when set, answers requests that resolve to mock files
+	// such as api/users.GET.json before the file handler sees them.
+	MockDirectory string
+	// HostingFiles applies the _redirects and _headers files of DirectoryPath,
+	// which are read again whenever they change.
+	HostingFiles bool
+	// ProxyMounts forward matching requests to upstream servers. They are
+	// not held back by on-change builds.
+	ProxyMounts []ProxyMount
+	// Record, when set, saves the responses of proxy mounts as fixtures.
+	Record *RecordConfiguration
+	// ReplayDirectory, when set, answers requests from fixtures saved by
+	// Record before they reach proxy mounts or the file handler.
+	ReplayDirectory string
+	// Chaos slows down or breaks matching responses from every source. The
+	// rules can be switched at runtime below /__ghttp/chaos.
+	Chaos []ChaosRule
+	// Throttle, when set, limits bandwidth and adds round-trip latency to
+	// every response.
+	Throttle *ThrottleConfiguration
+	// Inspect serves request-inspection endpoints such as /__ghttp/echo.
+	Inspect bool
+	// ForwardConsole injects a script into HTML pages that reports browser
+	// console output, uncaught errors and unhandled rejections to the log.
+	ForwardConsole bool
+	// ContentSecurityPolicy, when set, adds CSP headers to served responses,
+	// collects the violation reports browsers send to /__ghttp/csp-reports,
+	// and summarizes them at /__ghttp/csp.
+	ContentSecurityPolicy   *ContentSecurityPolicyConfiguration
 	LoggingType             string
 	TLS                     *TLSConfiguration
+	HTTPPort                string
+	RedirectHTTPToHTTPS     bool
+	StrictTransportSecurity *StrictTransportSecurityConfiguration
+	UnixSocketPath          string
+	UnixSocketPermissions   os.FileMode
+	InheritedListeners      []InheritedListener
+	PortFallback            bool
+	PortFilePath            string
+	Limits                  ServerLimits
+	// ProxyProtocolTrustedNetworks, when not empty, enables PROXY protocol
+	// parsing for connections from these networks.
+	ProxyProtocolTrustedNetworks []netip.Prefix
+	// TrustedProxies lists the reverse proxies whose Forwarded and
+	// X-Forwarded-* headers are honoured.
+	TrustedProxies []netip.Prefix
+	// OnReady, when set, is called once every listener is serving.
+	OnReady func()
+	// LiveReload watches DirectoryPath and tells browsers viewing HTML pages
+	// to reload, or to swap stylesheets when only CSS changed.
+	LiveReload bool
+	// OnChange, when set, runs a build command whenever watched files change
+	// and holds back content while it runs or after it fails.
+	OnChange *OnChangeConfiguration
+}
+
+// ErrListenersHandedOff is returned by Serve after the listening sockets were
+// passed to a re-executed process and in-flight requests have drained.
+var ErrListenersHandedOff = errors.New("listeners handed off to a new process")
+
+// ListenerRole identifies which endpoint an inherited listener serves.
+type ListenerRole string
+
+const (
+	// ListenerRolePrimary serves HTTPS when TLS is configured and HTTP otherwise.
+	ListenerRolePrimary ListenerRole = "primary"
+	// ListenerRoleHTTP serves plain HTTP, redirecting to HTTPS when configured.
+	ListenerRoleHTTP ListenerRole = "http"
+	// ListenerRoleHTTPS serves HTTPS and requires TLS to be configured.
+	ListenerRoleHTTPS ListenerRole = "https"
+)
+
+// InheritedListener is an already-open listener handed to the file server,
+// for example through systemd socket activation. Inherited listeners replace
+// the addresses the server would otherwise bind itself.
+type InheritedListener struct {
+	Role     ListenerRole
+	Listener net.Listener
 }
 
 // TLSConfiguration describes transport layer security configuration.
@@ -82,10 +194,6 @@ func (fileServer FileServer) Serve(ctx context.Context, configuration FileServer
 	if fileServer.loggingService == nil {
 		return errors.New("logging service not configured")
 	}
-	listeningAddress := net.JoinHostPort(configuration.BindAddress, configuration.Port)
-	displayAddress := fileServer.servingAddressFormatter.FormatHostAndPortForLogging(configuration.BindAddress, configuration.Port)
-	fileHandler := fileServer.buildFileHandler(configuration)
-	wrappedHandler := fileServer.wrapWithHeaders(fileHandler, configuration.ProtocolVersion)
 	loggingType := fileServer.loggingService.Type()
 	if configuration.LoggingType != "" {
 		loggingType = configuration.LoggingType
@@ -95,80 +203,556 @@ func (fileServer FileServer) Serve(ctx context.Context, configuration FileServer
 		return fmt.Errorf("normalize logging type: %w", normalizeErr)
 	}
 	loggingType = normalizedLoggingType
-	loggingHandler := fileServer.wrapWithLogging(wrappedHandler, loggingType)
 
-	server := &http.Server{
-		Addr:              listeningAddress,
-		Handler:           loggingHandler,
-		ReadHeaderTimeout: 15 * time.Second,
+	tlsConfiguration, configureErr := fileServer.loadTLSConfiguration(configuration.TLS)
+	if configureErr != nil {
+		return fmt.Errorf("configure tls: %w", configureErr)
+	}
+	certificateConfigured := tlsConfiguration != nil
+	endpoints, planErr := planServingEndpoints(configuration, certificateConfigured)
+	if planErr != nil {
+		return planErr
+	}
+
+	fileHandler := fileServer.buildFileHandler(configuration)
+	if configuration.MockDirectory != "" {
+		fileHandler = newMockHandler(fileHandler, configuration.MockDirectory, fileServer.loggingService)
+	}
+	if configuration.HostingFiles {
+		fileHandler = newHostingFilesHandler(fileHandler, configuration.DirectoryPath, fileServer.loggingService)
+	}
+	if len(configuration.Rules) > 0 {
+		fileHandler = newRulesHandler(fileHandler, configuration.Rules)
+	}
+	var reloadBroker *liveReloadBroker
+	var directoryWatcher *watch.Watcher
+	internalRoutes := http.NewServeMux()
+	internalRoutesRegistered := false
+	pageSnippet := ""
+	if configuration.LiveReload {
+		watcher, watchErr := watch.New(configuration.DirectoryPath, watch.Options{})
+		if watchErr != nil {
+			return fmt.Errorf("watch directory: %w", watchErr)
+		}
+		defer watcher.Close()
+		directoryWatcher = watcher
+		reloadBroker = newLiveReloadBroker()
+		registerLiveReloadRoutes(internalRoutes, reloadBroker)
+		internalRoutesRegistered = true
+		pageSnippet += liveReloadSnippet
+	}
+	if configuration.ForwardConsole {
+		registerConsoleForwardRoutes(internalRoutes, fileServer.loggingService)
+		internalRoutesRegistered = true
+		pageSnippet += consoleForwardSnippetHTML
+	}
+	if pageSnippet != "" {
+		fileHandler = newHTMLInjectionHandler(fileHandler, pageSnippet)
+	}
+	var builder *buildRunner
+	var buildWatcher *watch.Watcher
+	if configuration.OnChange != nil {
+		builder = newBuildRunner(*configuration.OnChange, fileServer.loggingService)
+		watcher, watchErr := builder.newWatcher(configuration.DirectoryPath)
+		if watchErr != nil {
+			return fmt.Errorf("watch build sources: %w", watchErr)
+		}
+		defer watcher.Close()
+		buildWatcher = watcher
+		fileHandler = newBuildGateHandler(fileHandler, builder, pageSnippet)
+	}
+	if len(configuration.ProxyMounts) > 0 {
+		fileHandler = newProxyMountsHandler(fileHandler, configuration.ProxyMounts, configuration.Record, fileServer.loggingService)
 	}
+	if configuration.ReplayDirectory != "" {
+		fileHandler = newReplayHandler(fileHandler, configuration.ReplayDirectory, fileServer.loggingService)
+	}
+	if configuration.Inspect {
+		registerInspectionRoutes(internalRoutes)
+		internalRoutesRegistered = true
+	}
+	if len(configuration.Chaos) > 0 {
+		chaos := newChaosController(configuration.Chaos)
+		registerChaosRoutes(internalRoutes, chaos)
+		internalRoutesRegistered = true
+		fileHandler = chaos.wrap(fileHandler)
+	}
+	if certificateConfigured && configuration.StrictTransportSecurity != nil {
+		fileHandler = newStrictTransportSecurityHandler(fileHandler, *configuration.StrictTransportSecurity)
+	}
+	if configuration.ContentSecurityPolicy != nil {
+		registerCSPRoutes(internalRoutes, newCSPCollector(fileServer.loggingService))
+		internalRoutesRegistered = true
+		fileHandler = newContentSecurityPolicyHandler(fileHandler, *configuration.ContentSecurityPolicy)
+	}
+	if internalRoutesRegistered {
+		fileHandler = newInternalRoutesHandler(fileHandler, internalRoutes)
+	}
+	var requestThrottle *throttle
+	if configuration.Throttle != nil {
+		requestThrottle = newThrottle(*configuration.Throttle)
+		fileHandler = requestThrottle.wrap(fileHandler)
+	}
+	contentHandler := fileServer.wrapWithLogging(fileServer.wrapWithHeaders(fileHandler, configuration.ProtocolVersion), loggingType)
 
-	if configuration.ProtocolVersion == httpProtocolVersionOneZero {
-		server.DisableGeneralOptionsHandler = true
-		server.SetKeepAlivesEnabled(false)
+	listeners, listenErr := openEndpointListeners(endpoints, configuration)
+	if listenErr != nil {
+		var inUseErr *addressInUseError
+		if errors.As(listenErr, &inUseErr) {
+			friendlyMessage := formatAddressInUseMessage(inUseErr.bindAddress, inUseErr.port)
+			fileServer.loggingService.Error(friendlyMessage, inUseErr.err)
+			return fmt.Errorf("address in use: %s", friendlyMessage)
+		}
+		fileServer.loggingService.Error(logMessageServerError, listenErr)
+		return fmt.Errorf("listen: %w", listenErr)
+	}
+	for index := range endpoints {
+		endpoints[index].applyListenerAddress(listeners[index].Addr())
 	}
+	httpsPort := secureEndpointPort(endpoints, configuration.Port)
+	fileServer.logPortFallback(configuration, endpoints, loggingType)
 
-	certificateConfigured, configureErr := fileServer.configureTLS(server, configuration.TLS)
-	if configureErr != nil {
-		return fmt.Errorf("configure tls: %w", configureErr)
+	servers := make([]*http.Server, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		endpointHandler := contentHandler
+		if endpoint.redirectToHTTPS {
+			redirectHandler := newHTTPSRedirectHandler(fileHandler, httpsPort)
+			endpointHandler = fileServer.wrapWithLogging(fileServer.wrapWithHeaders(redirectHandler, configuration.ProtocolVersion), loggingType)
+		}
+		if len(configuration.TrustedProxies) > 0 {
+			endpointHandler = newForwardedHeadersHandler(endpointHandler, configuration.TrustedProxies)
+		}
+		server := &http.Server{Handler: endpointHandler}
+		configuration.Limits.apply(server)
+		if requestThrottle != nil {
+			server.ConnContext = requestThrottle.connContext
+		}
+		if endpoint.secure {
+			server.TLSConfig = tlsConfiguration.Clone()
+		}
+		if configuration.ProtocolVersion == httpProtocolVersionOneZero {
+			server.DisableGeneralOptionsHandler = true
+			server.SetKeepAlivesEnabled(false)
+		}
+		fileServer.configureProtocols(server, configuration.ProtocolVersion, endpoint.secure)
+		if reloadBroker != nil {
+			server.RegisterOnShutdown(reloadBroker.close)
+		}
+		servers = append(servers, server)
+	}
+	fileServer.logServingEndpoints(configuration, endpoints, httpsPort, loggingType)
+	handedOff := false
+	if configuration.PortFilePath != "" {
+		if writeErr := fileServer.writePortFile(configuration.PortFilePath, endpoints); writeErr != nil {
+			for _, listener := range listeners {
+				_ = listener.Close()
+			}
+			return fmt.Errorf("write port file: %w", writeErr)
+		}
+		defer func() {
+			if !handedOff {
+				_ = os.Remove(configuration.PortFilePath)
+			}
+		}()
 	}
 
-	currentTime := time.Now().Format(defaultLogTimeLayout)
-	if loggingType == logging.TypeConsole {
-		startMessage := formatConsoleStartMessage(configuration, certificateConfigured, displayAddress)
-		fileServer.loggingService.Info(startMessage)
+	var limiter *connectionLimiter
+	if configuration.Limits.MaxConnections > 0 {
+		limiter = newConnectionLimiter(configuration.Limits.MaxConnections, func() {
+			fileServer.loggingService.Warn(logMessageConnectionLimitReached, logging.Int(logFieldMaximumConnections, configuration.Limits.MaxConnections))
+		})
+	}
+	serverErrors := make(chan error, len(servers))
+	for index := range servers {
+		server := servers[index]
+		listener := listeners[index]
+		if len(configuration.ProxyProtocolTrustedNetworks) > 0 {
+			listener = proxyprotocol.NewListener(listener, configuration.ProxyProtocolTrustedNetworks, configuration.Limits.readHeaderTimeout())
+		}
+		if limiter != nil {
+			listener = limiter.wrap(listener)
+		}
+		secure := endpoints[index].secure
+		go func() {
+			if secure {
+				serverErrors <- server.ServeTLS(listener, "", "")
+				return
+			}
+			serverErrors <- server.Serve(listener)
+		}()
+	}
+
+	defer func() {
+		if !handedOff {
+			removeUnixSockets(endpoints)
+		}
+	}()
+	if directoryWatcher != nil {
+		watchCtx, stopWatching := context.WithCancel(ctx)
+		defer stopWatching()
+		go directoryWatcher.Run(watchCtx, func(changedPaths []string) {
+			fileServer.loggingService.Info(logMessageLiveReloadChange, logging.Strings(logFieldChangedPaths, changedPaths))
+			reloadBroker.publishChanges(changedPaths)
+		}, func(watchErr error) {
+			fileServer.loggingService.Error(logMessageLiveReloadError, watchErr)
+		})
+	}
+	if buildWatcher != nil {
+		buildCtx, stopBuilding := context.WithCancel(ctx)
+		defer stopBuilding()
+		go buildWatcher.Run(buildCtx, func(changedPaths []string) {
+			builder.start(buildCtx, func() {
+				if reloadBroker != nil {
+					reloadBroker.publishReload()
+				}
+			})
+		}, func(watchErr error) {
+			fileServer.loggingService.Error(logMessageBuildWatch, watchErr)
+		})
+	}
+	handoffRequests, stopHandoffRequests := notifyListenerHandoff()
+	defer stopHandoffRequests()
+	if configuration.OnReady != nil {
+		configuration.OnReady()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			fileServer.loggingService.Info(logMessageShutdownInitiated)
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), configuration.Limits.shutdownTimeout())
+			shutdownErr := shutdownServers(shutdownCtx, servers)
+			cancel()
+			if shutdownErr != nil {
+				fileServer.loggingService.Error(logMessageShutdownFailed, shutdownErr)
+				return fmt.Errorf("shutdown server: %w", shutdownErr)
+			}
+			fileServer.loggingService.Info(logMessageShutdownCompleted)
+			return nil
+		case <-handoffRequests:
+			fileServer.loggingService.Info(logMessageHandoffRequested)
+			childProcessID, handoffErr := startHandoffProcess(endpoints, listeners)
+			if handoffErr != nil {
+				fileServer.loggingService.Error(logMessageHandoffFailed, handoffErr)
+				continue
+			}
+			handedOff = true
+			fileServer.loggingService.Info(logMessageHandoffCompleted, logging.Int(logFieldProcessID, childProcessID))
+			retainUnixSockets(listeners)
+			if drainErr := fileServer.drainHandedOffServers(ctx, servers, configuration.Limits.shutdownTimeout()); drainErr != nil {
+				return drainErr
+			}
+			return ErrListenersHandedOff
+		case serveErr := <-serverErrors:
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), configuration.Limits.shutdownTimeout())
+			_ = shutdownServers(shutdownCtx, servers)
+			cancel()
+			if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+				fileServer.loggingService.Error(logMessageServerError, serveErr)
+				return fmt.Errorf("serve http: %w", serveErr)
+			}
+			return nil
+		}
+	}
+}
+
+// drainHandedOffServers stops accepting connections and waits for in-flight
+// requests, such as long downloads, without a deadline. Cancelling the context
+// cuts the remaining requests short after the shutdown timeout.
+func (fileServer FileServer) drainHandedOffServers(ctx context.Context, servers []*http.Server, shutdownTimeout time.Duration) error {
+	fileServer.loggingService.Info(logMessageShutdownInitiated)
+	drainCtx, cancelDrain := context.WithCancel(context.Background())
+	defer cancelDrain()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-drainCtx.Done():
+			return
+		}
+		select {
+		case <-time.After(shutdownTimeout):
+			cancelDrain()
+		case <-drainCtx.Done():
+		}
+	}()
+	if shutdownErr := shutdownServers(drainCtx, servers); shutdownErr != nil {
+		fileServer.loggingService.Error(logMessageShutdownFailed, shutdownErr)
+		return fmt.Errorf("shutdown server: %w", shutdownErr)
+	}
+	fileServer.loggingService.Info(logMessageShutdownCompleted)
+	return nil
+}
+
+// handoffListenerRole names an endpoint so that the re-executed process
+// serves it the same way.
+func handoffListenerRole(endpoint servingEndpoint) ListenerRole {
+	if endpoint.secure {
+		return ListenerRoleHTTPS
+	}
+	return ListenerRoleHTTP
+}
+
+// servingEndpoint describes a single listener opened by the file server.
+type servingEndpoint struct {
+	bindAddress     string
+	port            string
+	requestedPort   string
+	unixSocketPath  string
+	secure          bool
+	redirectToHTTPS bool
+	listener        net.Listener
+}
+
+// applyListenerAddress records the port the listener actually bound, and the
+// full address of listeners that were inherited rather than opened here.
+func (endpoint *servingEndpoint) applyListenerAddress(address net.Addr) {
+	if address == nil {
+		return
+	}
+	if address.Network() == networkUnix {
+		if endpoint.listener != nil {
+			endpoint.unixSocketPath = address.String()
+		}
+		return
+	}
+	host, port, splitErr := net.SplitHostPort(address.String())
+	if splitErr != nil {
+		return
+	}
+	endpoint.port = port
+	if endpoint.listener != nil {
+		endpoint.bindAddress = host
+	}
+}
+
+func secureEndpointPort(endpoints []servingEndpoint, fallbackPort string) string {
+	for _, endpoint := range endpoints {
+		if endpoint.secure && endpoint.unixSocketPath == "" {
+			return endpoint.port
+		}
+	}
+	return fallbackPort
+}
+
+func planServingEndpoints(configuration FileServerConfiguration, certificateConfigured bool) ([]servingEndpoint, error) {
+	if len(configuration.InheritedListeners) > 0 {
+		return planInheritedEndpoints(configuration, certificateConfigured)
+	}
+	if configuration.HTTPPort == "" && configuration.RedirectHTTPToHTTPS {
+		return nil, errors.New("redirecting http requires an http port")
+	}
+	if configuration.HTTPPort != "" {
+		if !certificateConfigured {
+			return nil, errors.New("an additional http port requires tls")
+		}
+		if configuration.HTTPPort == configuration.Port {
+			return nil, fmt.Errorf("http port %s collides with https port", configuration.HTTPPort)
+		}
+	}
+	bindAddresses := configuration.BindAddresses
+	if len(bindAddresses) == 0 {
+		bindAddresses = []string{""}
+	}
+	var endpoints []servingEndpoint
+	if configuration.UnixSocketPath != "" {
+		endpoints = append(endpoints, servingEndpoint{
+			unixSocketPath: configuration.UnixSocketPath,
+			secure:         certificateConfigured,
+		})
 	} else {
-		fullURLScheme := "http"
-		activeMessage := logMessageServingHTTP
-		if certificateConfigured {
-			fullURLScheme = "https"
-			activeMessage = logMessageServingHTTPS
+		for _, bindAddress := range bindAddresses {
+			endpoints = append(endpoints, servingEndpoint{
+				bindAddress: bindAddress,
+				port:        configuration.Port,
+				secure:      certificateConfigured,
+			})
+		}
+	}
+	if configuration.HTTPPort == "" {
+		return endpoints, nil
+	}
+	for _, bindAddress := range bindAddresses {
+		endpoints = append(endpoints, servingEndpoint{
+			bindAddress:     bindAddress,
+			port:            configuration.HTTPPort,
+			redirectToHTTPS: configuration.RedirectHTTPToHTTPS,
+		})
+	}
+	return endpoints, nil
+}
+
+func planInheritedEndpoints(configuration FileServerConfiguration, certificateConfigured bool) ([]servingEndpoint, error) {
+	endpoints := make([]servingEndpoint, 0, len(configuration.InheritedListeners))
+	for _, inherited := range configuration.InheritedListeners {
+		endpoint := servingEndpoint{listener: inherited.Listener}
+		switch inherited.Role {
+		case ListenerRoleHTTPS:
+			if !certificateConfigured {
+				return nil, errors.New("inherited https listener requires tls")
+			}
+			endpoint.secure = true
+		case ListenerRoleHTTP:
+			endpoint.redirectToHTTPS = certificateConfigured && configuration.RedirectHTTPToHTTPS
+		default:
+			endpoint.secure = certificateConfigured
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// servingEndpointGroup collects endpoints that differ only by bind address so
+// that startup logs report every URL of one listener role together.
+type servingEndpointGroup struct {
+	servingEndpoint
+	bindAddresses []string
+}
+
+func groupServingEndpoints(endpoints []servingEndpoint) []servingEndpointGroup {
+	var groups []servingEndpointGroup
+	for _, endpoint := range endpoints {
+		grouped := false
+		for index := range groups {
+			group := &groups[index]
+			if endpoint.unixSocketPath == "" && group.unixSocketPath == "" && group.port == endpoint.port &&
+				group.secure == endpoint.secure && group.redirectToHTTPS == endpoint.redirectToHTTPS {
+				group.bindAddresses = append(group.bindAddresses, endpoint.bindAddress)
+				grouped = true
+				break
+			}
+		}
+		if !grouped {
+			groups = append(groups, servingEndpointGroup{servingEndpoint: endpoint, bindAddresses: []string{endpoint.bindAddress}})
+		}
+	}
+	return groups
+}
+
+func (fileServer FileServer) logServingEndpoints(configuration FileServerConfiguration, endpoints []servingEndpoint, httpsPort string, loggingType string) {
+	for _, group := range groupServingEndpoints(endpoints) {
+		fileServer.logServingEndpointGroup(configuration, group, httpsPort, loggingType)
+	}
+}
+
+func (fileServer FileServer) logServingEndpointGroup(configuration FileServerConfiguration, group servingEndpointGroup, httpsPort string, loggingType string) {
+	if group.unixSocketPath != "" {
+		fileServer.logServingUnixSocket(configuration, group.servingEndpoint, loggingType)
+		return
+	}
+	fullURLScheme := "http"
+	activeMessage := logMessageServingHTTP
+	if group.secure {
+		fullURLScheme = "https"
+		activeMessage = logMessageServingHTTPS
+	}
+	urls := fileServer.servingAddressFormatter.FormatURLsForLogging(fullURLScheme, group.bindAddresses, group.port)
+	redirectTarget := ""
+	if group.redirectToHTTPS {
+		redirectTarget = fileServer.servingAddressFormatter.FormatURLForLogging("https", group.bindAddresses[0], httpsPort)
+	}
+	if loggingType == logging.TypeConsole {
+		if group.redirectToHTTPS {
+			fileServer.loggingService.Info(formatConsoleRedirectMessage(group, urls, redirectTarget))
+			return
 		}
-		fullURL := fmt.Sprintf("%s://%s", fullURLScheme, displayAddress)
+		fileServer.loggingService.Info(formatConsoleStartMessage(group, configuration.ProtocolVersion, urls))
+		return
+	}
+	currentTime := time.Now().Format(defaultLogTimeLayout)
+	if group.redirectToHTTPS {
 		fileServer.loggingService.Info(
-			activeMessage,
-			logging.String(logFieldDirectory, configuration.DirectoryPath),
-			logging.String(logFieldProtocol, configuration.ProtocolVersion),
-			logging.String(logFieldURL, fullURL),
+			logMessageRedirectingHTTP,
+			logging.String(logFieldURL, urls[0]),
+			logging.Strings(logFieldURLs, urls),
+			logging.String(logFieldRedirectTarget, redirectTarget),
 			logging.String(logFieldTimestamp, currentTime),
 		)
+		return
 	}
+	fileServer.loggingService.Info(
+		activeMessage,
+		logging.String(logFieldDirectory, configuration.DirectoryPath),
+		logging.String(logFieldProtocol, describeProtocol(configuration.ProtocolVersion, group.secure)),
+		logging.String(logFieldURL, urls[0]),
+		logging.Strings(logFieldURLs, urls),
+		logging.String(logFieldTimestamp, currentTime),
+	)
+}
 
-	serverErrors := make(chan error, 1)
-	go func() {
-		var serveErr error
-		if certificateConfigured {
-			serveErr = server.ListenAndServeTLS("", "")
-		} else {
-			serveErr = server.ListenAndServe()
+func (fileServer FileServer) logServingUnixSocket(configuration FileServerConfiguration, endpoint servingEndpoint, loggingType string) {
+	if loggingType == logging.TypeConsole {
+		fileServer.loggingService.Info(formatConsoleUnixSocketMessage(endpoint, configuration.ProtocolVersion))
+		return
+	}
+	activeMessage := logMessageServingHTTP
+	if endpoint.secure {
+		activeMessage = logMessageServingHTTPS
+	}
+	fileServer.loggingService.Info(
+		activeMessage,
+		logging.String(logFieldDirectory, configuration.DirectoryPath),
+		logging.String(logFieldProtocol, describeProtocol(configuration.ProtocolVersion, endpoint.secure)),
+		logging.String(logFieldSocket, endpoint.unixSocketPath),
+		logging.String(logFieldTimestamp, time.Now().Format(defaultLogTimeLayout)),
+	)
+}
+
+func (fileServer FileServer) logPortFallback(configuration FileServerConfiguration, endpoints []servingEndpoint, loggingType string) {
+	reported := map[string]struct{}{}
+	for _, endpoint := range endpoints {
+		if endpoint.requestedPort == "" || endpoint.requestedPort == endpoint.port || endpoint.requestedPort == portKernelAssigned {
+			continue
 		}
-		serverErrors <- serveErr
-	}()
+		if _, exists := reported[endpoint.requestedPort]; exists {
+			continue
+		}
+		reported[endpoint.requestedPort] = struct{}{}
+		if loggingType == logging.TypeConsole {
+			fileServer.loggingService.Info(fmt.Sprintf("Port %s is in use, using port %s instead", endpoint.requestedPort, endpoint.port))
+			continue
+		}
+		fileServer.loggingService.Info(
+			logMessagePortFallback,
+			logging.String(logFieldRequestedPort, endpoint.requestedPort),
+			logging.String(logFieldPort, endpoint.port),
+		)
+	}
+}
 
-	select {
-	case <-ctx.Done():
-		fileServer.loggingService.Info(logMessageShutdownInitiated)
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
-		defer cancel()
-		shutdownErr := server.Shutdown(shutdownCtx)
-		if shutdownErr != nil {
-			fileServer.loggingService.Error(logMessageShutdownFailed, shutdownErr)
-			return fmt.Errorf("shutdown server: %w", shutdownErr)
-		}
-		fileServer.loggingService.Info(logMessageShutdownCompleted)
-		return nil
-	case serveErr := <-serverErrors:
-		if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
-			if isAddressInUse(serveErr) {
-				friendlyMessage := formatAddressInUseMessage(configuration)
-				fileServer.loggingService.Error(friendlyMessage, serveErr)
-				return fmt.Errorf("address in use: %s", friendlyMessage)
-			}
-			fileServer.loggingService.Error(logMessageServerError, serveErr)
-			return fmt.Errorf("serve http: %w", serveErr)
+// writePortFile records the URL of the primary endpoint so that scripts can
+// discover a server started on a fallback or kernel-assigned port. The file is
+// renamed into place so that a reader never sees it partially written.
+func (fileServer FileServer) writePortFile(portFilePath string, endpoints []servingEndpoint) error {
+	primaryEndpoint := endpoints[0]
+	content := "unix:" + primaryEndpoint.unixSocketPath
+	if primaryEndpoint.unixSocketPath == "" {
+		scheme := "http"
+		if primaryEndpoint.secure {
+			scheme = "https"
+		}
+		content = fileServer.servingAddressFormatter.FormatURLForLogging(scheme, primaryEndpoint.bindAddress, primaryEndpoint.port)
+	}
+	temporaryPath := portFilePath + ".tmp"
+	if writeErr := os.WriteFile(temporaryPath, []byte(content+"\n"), portFilePermissions); writeErr != nil {
+		return writeErr
+	}
+	return os.Rename(temporaryPath, portFilePath)
+}
+
+func shutdownServers(shutdownCtx context.Context, servers []*http.Server) error {
+	shutdownErrors := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			shutdownErrors <- server.Shutdown(shutdownCtx)
+		}()
+	}
+	var combined []error
+	for range servers {
+		if shutdownErr := <-shutdownErrors; shutdownErr != nil {
+			combined = append(combined, shutdownErr)
 		}
-		return nil
 	}
+	return errors.Join(combined...)
 }
 
 func (fileServer FileServer) buildFileHandler(configuration FileServerConfiguration) http.Handler {
@@ -183,9 +767,22 @@ func (fileServer FileServer) buildFileHandler(configuration FileServerConfigurat
 	if configuration.BrowseDirectories {
 		handler = newBrowseHandler(handler, fileSystem)
 	}
+	if configuration.SPAFallbackRelativePath != "" {
+		handler = newSPAFallbackHandler(handler, fileSystem, configuration.SPAFallbackRelativePath)
+	}
+	if configuration.CleanURLs || (configuration.TrailingSlash != "" && configuration.TrailingSlash != TrailingSlashAsIs) {
+		handler = newCleanURLHandler(handler, fileSystem, configuration.CleanURLs, configuration.RedirectHTMLToCleanURLs, configuration.EnableMarkdown, configuration.TrailingSlash)
+	}
 	if configuration.InitialFileRelativePath != "" && !configuration.BrowseDirectories {
 		handler = newInitialFileHandler(handler, configuration.InitialFileRelativePath)
 	}
+	if configuration.ErrorPages != nil {
+		errorPageFileSystems := []http.FileSystem{fileSystem}
+		if configuration.ErrorPages.Directory != "" {
+			errorPageFileSystems = []http.FileSystem{http.Dir(configuration.ErrorPages.Directory), fileSystem}
+		}
+		handler = newErrorPageHandler(handler, errorPageFileSystems)
+	}
 	return handler
 }
 
@@ -210,46 +807,102 @@ func (fileServer FileServer) wrapWithLogging(handler http.Handler, loggingType s
 			startTime := time.Now()
 			handler.ServeHTTP(recordedWriter, request)
 			message := formatConsoleRequestLog(request, recordedWriter.statusCode, recordedWriter.bytesWritten, startTime)
+			if recordedWriter.upstream != "" {
+				message += " -> " + recordedWriter.upstream
+			}
 			fileServer.loggingService.Info(message)
 		})
 	default:
 		return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
 			recordedWriter := newStatusRecorder(responseWriter)
 			startTime := time.Now()
-			fileServer.loggingService.Info(
-				logMessageRequestStarted,
+			fileServer.loggingService.Info(logMessageRequestStarted, appendPeerField(request,
 				logging.String(logFieldMethod, request.Method),
 				logging.String(logFieldPath, request.URL.Path),
 				logging.String(logFieldProtocol, request.Proto),
 				logging.String(logFieldRemote, request.RemoteAddr),
-			)
+			)...)
 			handler.ServeHTTP(recordedWriter, request)
 			duration := time.Since(startTime)
-			fileServer.loggingService.Info(
-				logMessageRequestCompleted,
+			completedFields := appendPeerField(request,
 				logging.String(logFieldMethod, request.Method),
 				logging.String(logFieldPath, request.URL.Path),
 				logging.Int(logFieldStatus, recordedWriter.statusCode),
 				logging.Duration(logFieldDuration, duration),
 				logging.String(logFieldRemote, request.RemoteAddr),
 			)
+			if recordedWriter.upstream != "" {
+				completedFields = append(completedFields, logging.String(logFieldUpstream, recordedWriter.upstream))
+			}
+			fileServer.loggingService.Info(logMessageRequestCompleted, completedFields...)
 		})
 	}
 }
 
-func formatConsoleStartMessage(configuration FileServerConfiguration, certificateConfigured bool, displayAddress string) string {
-	bindAddress := configuration.BindAddress
-	if strings.TrimSpace(bindAddress) == "" {
-		bindAddress = "0.0.0.0"
+// appendPeerField records the connection peer next to the remote client when
+// a trusted proxy forwarded the request.
+func appendPeerField(request *http.Request, fields ...logging.Field) []logging.Field {
+	if peerAddress := peerAddressFromRequest(request); peerAddress != "" {
+		fields = append(fields, logging.String(logFieldPeer, peerAddress))
+	}
+	return fields
+}
+
+func formatConsoleStartMessage(group servingEndpointGroup, protocolVersion string, urls []string) string {
+	scheme := "HTTP"
+	if group.secure {
+		scheme = "HTTPS"
+	}
+	if protocolVersion == httpProtocolVersionTwo {
+		scheme = fmt.Sprintf("%s (%s)", scheme, describeProtocol(protocolVersion, group.secure))
+	}
+	return fmt.Sprintf("Serving %s on %s port %s (%s) ...", scheme, formatConsoleBindAddresses(group.bindAddresses), group.port, formatConsoleURLs(urls))
+}
+
+func formatConsoleBindAddresses(bindAddresses []string) string {
+	displayAddresses := make([]string, 0, len(bindAddresses))
+	for _, bindAddress := range bindAddresses {
+		if strings.TrimSpace(bindAddress) == "" {
+			bindAddress = "0.0.0.0"
+		}
+		displayAddresses = append(displayAddresses, bindAddress)
 	}
-	port := configuration.Port
-	scheme := "http"
+	return strings.Join(displayAddresses, ", ")
+}
+
+func formatConsoleURLs(urls []string) string {
+	displayURLs := make([]string, 0, len(urls))
+	for _, listeningURL := range urls {
+		displayURLs = append(displayURLs, listeningURL+"/")
+	}
+	return strings.Join(displayURLs, ", ")
+}
+
+func formatConsoleUnixSocketMessage(endpoint servingEndpoint, protocolVersion string) string {
 	schemeLabel := "HTTP"
-	if certificateConfigured {
-		scheme = "https"
+	if endpoint.secure {
 		schemeLabel = "HTTPS"
 	}
-	return fmt.Sprintf("Serving %s on %s port %s (%s://%s/) ...", schemeLabel, bindAddress, port, scheme, displayAddress)
+	if protocolVersion == httpProtocolVersionTwo {
+		schemeLabel = fmt.Sprintf("%s (%s)", schemeLabel, describeProtocol(protocolVersion, endpoint.secure))
+	}
+	return fmt.Sprintf("Serving %s on unix socket %s ...", schemeLabel, endpoint.unixSocketPath)
+}
+
+func formatConsoleRedirectMessage(group servingEndpointGroup, urls []string, redirectTarget string) string {
+	return fmt.Sprintf("Redirecting HTTP on %s port %s (%s) to %s/ ...", formatConsoleBindAddresses(group.bindAddresses), group.port, formatConsoleURLs(urls), redirectTarget)
+}
+
+// describeProtocol returns the protocol label reported in startup logs. HTTP/2
+// is qualified with the ALPN identifier that clients negotiate.
+func describeProtocol(protocolVersion string, certificateConfigured bool) string {
+	if protocolVersion != httpProtocolVersionTwo {
+		return protocolVersion
+	}
+	if certificateConfigured {
+		return protocolLabelHTTP2TLS
+	}
+	return protocolLabelHTTP2Cleartext
 }
 
 func formatConsoleRequestLog(request *http.Request, statusCode int, bytesWritten int, startTime time.Time) string {
@@ -267,32 +920,58 @@ func formatConsoleRequestLog(request *http.Request, statusCode int, bytesWritten
 	if bytesWritten > 0 {
 		sizeField = strconv.Itoa(bytesWritten)
 	}
-	return fmt.Sprintf("%s - - [%s] \"%s\" %d %s", clientAddress, timestamp, requestLine, statusCode, sizeField)
+	message := fmt.Sprintf("%s - - [%s] \"%s\" %d %s", clientAddress, timestamp, requestLine, statusCode, sizeField)
+	if peerAddress := peerAddressFromRequest(request); peerAddress != "" {
+		if host, _, err := net.SplitHostPort(peerAddress); err == nil {
+			peerAddress = host
+		}
+		message += " via " + peerAddress
+	}
+	return message
 }
 
-func (fileServer FileServer) configureTLS(server *http.Server, configuration *TLSConfiguration) (bool, error) {
+// configureProtocols restricts the server to the configured protocol version.
+// HTTP/2 is negotiated through ALPN when TLS is active and served as h2c, with
+// both prior knowledge and the HTTP/1.1 Upgrade mechanism, over plaintext.
+func (fileServer FileServer) configureProtocols(server *http.Server, protocolVersion string, certificateConfigured bool) {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if protocolVersion != httpProtocolVersionTwo {
+		server.Protocols = protocols
+		return
+	}
+	if certificateConfigured {
+		protocols.SetHTTP2(true)
+		server.Protocols = protocols
+		return
+	}
+	server.Protocols = protocols
+	server.Handler = h2c.NewHandler(server.Handler, &http2.Server{})
+}
+
+func (fileServer FileServer) loadTLSConfiguration(configuration *TLSConfiguration) (*tls.Config, error) {
 	if configuration == nil {
-		return false, nil
+		return nil, nil
 	}
 	if configuration.LoadedCertificate != nil {
-		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{*configuration.LoadedCertificate}}
-		return true, nil
+		return &tls.Config{Certificates: []tls.Certificate{*configuration.LoadedCertificate}}, nil
 	}
 	if configuration.CertificatePath == "" || configuration.PrivateKeyPath == "" {
-		return false, errors.New("both certificate and private key paths must be provided")
+		return nil, errors.New("both certificate and private key paths must be provided")
 	}
 	certificate, err := tls.LoadX509KeyPair(configuration.CertificatePath, configuration.PrivateKeyPath)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
-	return true, nil
+	return &tls.Config{Certificates: []tls.Certificate{certificate}}, nil
 }
 
 type statusRecorder struct {
 	http.ResponseWriter
 	statusCode   int
 	bytesWritten int
+	// upstream names the backend a proxy mount forwarded the request to.
+	upstream string
 }
 
 func (recorder *statusRecorder) WriteHeader(statusCode int) {
@@ -306,17 +985,22 @@ func (recorder *statusRecorder) Write(content []byte) (int, error) {
 	return written, err
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController so that
+// streaming handlers can flush and clear deadlines through the recorder.
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
 func newStatusRecorder(responseWriter http.ResponseWriter) *statusRecorder {
 	recorder := &statusRecorder{ResponseWriter: responseWriter, statusCode: http.StatusOK}
 	return recorder
 }
 
-func formatAddressInUseMessage(configuration FileServerConfiguration) string {
-	bindAddress := configuration.BindAddress
+func formatAddressInUseMessage(bindAddress string, port string) string {
 	if strings.TrimSpace(bindAddress) == "" {
 		bindAddress = "0.0.0.0"
 	}
-	return fmt.Sprintf("Address already in use: %s:%s", bindAddress, configuration.Port)
+	return fmt.Sprintf("Address already in use: %s:%s", bindAddress, port)
 }
 
 func isAddressInUse(err error) bool {
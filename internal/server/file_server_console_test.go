@@ -5,28 +5,50 @@ import (
 	"net/url"
 	"testing"
 	"time"
+
+	"github.com/temirov/ghttp/internal/serverdetails"
 )
 
 func TestFormatConsoleStartMessage(t *testing.T) {
-	configuration := FileServerConfiguration{
-		BindAddress: "",
-		Port:        "8000",
+	group := servingEndpointGroup{
+		servingEndpoint: servingEndpoint{port: "8000"},
+		bindAddresses:   []string{""},
 	}
-	message := formatConsoleStartMessage(configuration, false, "localhost:8000")
+	message := formatConsoleStartMessage(group, "HTTP/1.1", []string{"http://localhost:8000"})
 	expected := "Serving HTTP on 0.0.0.0 port 8000 (http://localhost:8000/) ..."
 	if message != expected {
 		t.Fatalf("expected %s, got %s", expected, message)
 	}
 
-	configuration.BindAddress = "127.0.0.1"
-	configuration.Port = "8443"
-	message = formatConsoleStartMessage(configuration, true, "127.0.0.1:8443")
+	group = servingEndpointGroup{
+		servingEndpoint: servingEndpoint{bindAddress: "127.0.0.1", port: "8443", secure: true},
+		bindAddresses:   []string{"127.0.0.1"},
+	}
+	message = formatConsoleStartMessage(group, "HTTP/1.1", []string{"https://127.0.0.1:8443"})
 	expected = "Serving HTTPS on 127.0.0.1 port 8443 (https://127.0.0.1:8443/) ..."
 	if message != expected {
 		t.Fatalf("expected %s, got %s", expected, message)
 	}
 }
 
+func TestFormatConsoleStartMessageListsEveryBindAddress(t *testing.T) {
+	endpoints := []servingEndpoint{
+		{bindAddress: "127.0.0.1", port: "8000"},
+		{bindAddress: "::1", port: "8000"},
+		{bindAddress: "192.168.1.5", port: "8000"},
+	}
+	groups := groupServingEndpoints(endpoints)
+	if len(groups) != 1 {
+		t.Fatalf("expected a single endpoint group, got %d", len(groups))
+	}
+	urls := serverdetails.NewServingAddressFormatter().FormatURLsForLogging("http", groups[0].bindAddresses, groups[0].port)
+	message := formatConsoleStartMessage(groups[0], "HTTP/1.1", urls)
+	expected := "Serving HTTP on 127.0.0.1, ::1, 192.168.1.5 port 8000 (http://localhost:8000/, http://[::1]:8000/, http://192.168.1.5:8000/) ..."
+	if message != expected {
+		t.Fatalf("expected %s, got %s", expected, message)
+	}
+}
+
 func TestFormatConsoleRequestLog(t *testing.T) {
 	request := httptestNewRequest("GET", "/docs/index.html?version=1", "HTTP/1.1", "127.0.0.1:54321")
 	startTime := time.Date(2025, time.October, 8, 12, 30, 0, 0, time.UTC)
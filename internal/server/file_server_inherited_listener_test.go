@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/temirov/ghttp/internal/serverdetails"
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+func TestIntegrationFileServerServesInheritedListener(t *testing.T) {
+	servedDirectory := t.TempDir()
+	writeFile(t, filepath.Join(servedDirectory, "inherited.txt"), "inherited")
+
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil {
+		t.Fatalf("listen: %v", listenErr)
+	}
+
+	fileServer := NewFileServer(logging.NewTestService(logging.TypeJSON), serverdetails.NewServingAddressFormatter())
+	configuration := FileServerConfiguration{
+		Port:               "1",
+		DirectoryPath:      servedDirectory,
+		ProtocolVersion:    "HTTP/1.1",
+		LoggingType:        logging.TypeJSON,
+		InheritedListeners: []InheritedListener{{Role: ListenerRolePrimary, Listener: listener}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErrors := make(chan error, 1)
+	go func() {
+		serveErrors <- fileServer.Serve(ctx, configuration)
+	}()
+
+	response, requestErr := http.Get("http://" + listener.Addr().String() + "/inherited.txt")
+	if requestErr != nil {
+		t.Fatalf("request: %v", requestErr)
+	}
+	bodyBytes, readErr := io.ReadAll(response.Body)
+	response.Body.Close()
+	if readErr != nil {
+		t.Fatalf("read body: %v", readErr)
+	}
+	if string(bodyBytes) != "inherited" {
+		t.Fatalf("unexpected body %s", string(bodyBytes))
+	}
+
+	cancel()
+	if serveErr := <-serveErrors; serveErr != nil {
+		t.Fatalf("serve: %v", serveErr)
+	}
+}
+
+func TestPlanServingEndpointsRejectsInheritedHTTPSWithoutTLS(t *testing.T) {
+	configuration := FileServerConfiguration{
+		InheritedListeners: []InheritedListener{{Role: ListenerRoleHTTPS}},
+	}
+	if _, err := planServingEndpoints(configuration, false); err == nil {
+		t.Fatalf("expected error for inherited https listener without tls")
+	}
+
+	configuration = FileServerConfiguration{
+		RedirectHTTPToHTTPS: true,
+		InheritedListeners: []InheritedListener{
+			{Role: ListenerRoleHTTPS},
+			{Role: ListenerRoleHTTP},
+		},
+	}
+	endpoints, err := planServingEndpoints(configuration, true)
+	if err != nil {
+		t.Fatalf("plan endpoints: %v", err)
+	}
+	if len(endpoints) != 2 || !endpoints[0].secure || !endpoints[1].redirectToHTTPS {
+		t.Fatalf("unexpected endpoints %+v", endpoints)
+	}
+}
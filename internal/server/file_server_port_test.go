@@ -2,7 +2,11 @@ package server
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"net"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -22,7 +26,7 @@ func TestIntegrationFileServerReturnsFriendlyErrorWhenPortInUse(t *testing.T) {
 
 	fileServer := NewFileServer(logging.NewTestService(logging.TypeConsole), serverdetails.NewServingAddressFormatter())
 	configuration := FileServerConfiguration{
-		BindAddress:             "127.0.0.1",
+		BindAddresses:           []string{"127.0.0.1"},
 		Port:                    portString,
 		DirectoryPath:           t.TempDir(),
 		ProtocolVersion:         "HTTP/1.1",
@@ -46,3 +50,58 @@ func TestIntegrationFileServerReturnsFriendlyErrorWhenPortInUse(t *testing.T) {
 
 	listener.Close()
 }
+
+func TestIntegrationFileServerFallsBackToNextFreePort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	busyPort := listener.Addr().(*net.TCPAddr).Port
+	portFilePath := filepath.Join(t.TempDir(), "ghttp.url")
+
+	fileServer := NewFileServer(logging.NewTestService(logging.TypeConsole), serverdetails.NewServingAddressFormatter())
+	configuration := FileServerConfiguration{
+		BindAddresses:   []string{"127.0.0.1"},
+		Port:            strconv.Itoa(busyPort),
+		DirectoryPath:   t.TempDir(),
+		ProtocolVersion: "HTTP/1.1",
+		LoggingType:     logging.TypeConsole,
+		PortFallback:    true,
+		PortFilePath:    portFilePath,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErrors := make(chan error, 1)
+	go func() {
+		serveErrors <- fileServer.Serve(ctx, configuration)
+	}()
+
+	var portFileContent []byte
+	for attempt := 0; attempt < 50; attempt++ {
+		portFileContent, err = os.ReadFile(portFilePath)
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("read port file: %v", err)
+	}
+	if _, statErr := os.Stat(portFilePath + ".tmp"); !errors.Is(statErr, fs.ErrNotExist) {
+		t.Fatalf("expected the port file to be renamed into place, got %v", statErr)
+	}
+	reportedURL := strings.TrimSpace(string(portFileContent))
+	busyURL := "http://localhost:" + strconv.Itoa(busyPort)
+	if reportedURL == busyURL || !strings.HasPrefix(reportedURL, "http://localhost:") {
+		t.Fatalf("expected a fallback url, got %s", reportedURL)
+	}
+
+	cancel()
+	if serveErr := <-serveErrors; serveErr != nil {
+		t.Fatalf("serve: %v", serveErr)
+	}
+	if _, statErr := os.Stat(portFilePath); !errors.Is(statErr, fs.ErrNotExist) {
+		t.Fatalf("expected port file to be removed on shutdown, got %v", statErr)
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/temirov/ghttp/internal/serverdetails"
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+func TestIntegrationFileServerServesCleartextHTTP2WithPriorKnowledge(t *testing.T) {
+	fileServer := NewFileServer(logging.NewTestService(logging.TypeConsole), serverdetails.NewServingAddressFormatter())
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+			_, _ = io.WriteString(responseWriter, request.Proto)
+		}),
+	}
+	fileServer.configureProtocols(server, httpProtocolVersionTwo, false)
+
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil {
+		t.Fatalf("listen: %v", listenErr)
+	}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Close()
+
+	clientProtocols := new(http.Protocols)
+	clientProtocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: clientProtocols}}
+	response, requestErr := client.Get("http://" + listener.Addr().String() + "/")
+	if requestErr != nil {
+		t.Fatalf("request: %v", requestErr)
+	}
+	defer response.Body.Close()
+	bodyBytes, readErr := io.ReadAll(response.Body)
+	if readErr != nil {
+		t.Fatalf("read body: %v", readErr)
+	}
+	if string(bodyBytes) != "HTTP/2.0" {
+		t.Fatalf("expected HTTP/2.0 request protocol, got %s", string(bodyBytes))
+	}
+}
+
+func TestConfigureProtocolsRestrictsTLSToHTTP1ForHTTP11(t *testing.T) {
+	fileServer := NewFileServer(logging.NewTestService(logging.TypeConsole), serverdetails.NewServingAddressFormatter())
+	server := &http.Server{}
+	fileServer.configureProtocols(server, "HTTP/1.1", true)
+	if server.Protocols == nil {
+		t.Fatalf("expected protocols to be configured")
+	}
+	if server.Protocols.HTTP2() {
+		t.Fatalf("expected HTTP/2 to be disabled for HTTP/1.1")
+	}
+	if !server.Protocols.HTTP1() {
+		t.Fatalf("expected HTTP/1 to be enabled")
+	}
+
+	server = &http.Server{}
+	fileServer.configureProtocols(server, httpProtocolVersionTwo, true)
+	if !server.Protocols.HTTP2() {
+		t.Fatalf("expected HTTP/2 to be enabled over TLS")
+	}
+}
+
+func TestFormatConsoleStartMessageReportsHTTP2(t *testing.T) {
+	group := servingEndpointGroup{servingEndpoint: servingEndpoint{port: "8443", secure: true}, bindAddresses: []string{""}}
+	message := formatConsoleStartMessage(group, httpProtocolVersionTwo, []string{"https://localhost:8443"})
+	expected := "Serving HTTPS (h2) on 0.0.0.0 port 8443 (https://localhost:8443/) ..."
+	if message != expected {
+		t.Fatalf("expected %s, got %s", expected, message)
+	}
+
+	group = servingEndpointGroup{servingEndpoint: servingEndpoint{port: "8000"}, bindAddresses: []string{""}}
+	message = formatConsoleStartMessage(group, httpProtocolVersionTwo, []string{"http://localhost:8000"})
+	expected = "Serving HTTP (h2c) on 0.0.0.0 port 8000 (http://localhost:8000/) ..."
+	if message != expected {
+		t.Fatalf("expected %s, got %s", expected, message)
+	}
+}
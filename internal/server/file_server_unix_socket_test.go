@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/temirov/ghttp/internal/serverdetails"
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+func TestIntegrationFileServerServesOverUnixSocket(t *testing.T) {
+	servedDirectory := t.TempDir()
+	writeFile(t, filepath.Join(servedDirectory, "hello.txt"), "hello over unix")
+	socketPath := filepath.Join(t.TempDir(), "ghttp.sock")
+
+	staleListener, staleErr := net.Listen(networkUnix, socketPath)
+	if staleErr != nil {
+		t.Fatalf("create stale socket: %v", staleErr)
+	}
+	staleListener.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = staleListener.Close()
+
+	fileServer := NewFileServer(logging.NewTestService(logging.TypeConsole), serverdetails.NewServingAddressFormatter())
+	configuration := FileServerConfiguration{
+		DirectoryPath:         servedDirectory,
+		ProtocolVersion:       "HTTP/1.1",
+		LoggingType:           logging.TypeConsole,
+		UnixSocketPath:        socketPath,
+		UnixSocketPermissions: 0o600,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErrors := make(chan error, 1)
+	go func() {
+		serveErrors <- fileServer.Serve(ctx, configuration)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(dialCtx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(dialCtx, networkUnix, socketPath)
+		},
+	}}
+	var response *http.Response
+	var requestErr error
+	for attempt := 0; attempt < 50; attempt++ {
+		response, requestErr = client.Get("http://unix/hello.txt")
+		if requestErr == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if requestErr != nil {
+		t.Fatalf("request over unix socket: %v", requestErr)
+	}
+	bodyBytes, readErr := io.ReadAll(response.Body)
+	response.Body.Close()
+	if readErr != nil {
+		t.Fatalf("read body: %v", readErr)
+	}
+	if string(bodyBytes) != "hello over unix" {
+		t.Fatalf("unexpected body %s", string(bodyBytes))
+	}
+
+	socketInfo, statErr := os.Stat(socketPath)
+	if statErr != nil {
+		t.Fatalf("stat socket: %v", statErr)
+	}
+	if socketInfo.Mode().Perm() != 0o600 {
+		t.Fatalf("expected socket permissions 0600, got %o", socketInfo.Mode().Perm())
+	}
+
+	cancel()
+	if serveErr := <-serveErrors; serveErr != nil {
+		t.Fatalf("serve: %v", serveErr)
+	}
+	if _, statErr := os.Lstat(socketPath); !errors.Is(statErr, fs.ErrNotExist) {
+		t.Fatalf("expected socket to be removed on shutdown, got %v", statErr)
+	}
+}
+
+func TestRemoveStaleUnixSocketRefusesRegularFiles(t *testing.T) {
+	regularFilePath := filepath.Join(t.TempDir(), "not-a-socket")
+	writeFile(t, regularFilePath, "data")
+
+	if err := removeStaleUnixSocket(regularFilePath); err == nil {
+		t.Fatalf("expected error for regular file")
+	}
+	if _, statErr := os.Stat(regularFilePath); statErr != nil {
+		t.Fatalf("expected regular file to be preserved: %v", statErr)
+	}
+}
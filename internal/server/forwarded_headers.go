@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+const (
+	forwardedHeaderName         = "Forwarded"
+	forwardedForHeaderName      = "X-Forwarded-For"
+	forwardedProtoHeaderName    = "X-Forwarded-Proto"
+	forwardedHostHeaderName     = "X-Forwarded-Host"
+	forwardedParameterFor       = "for"
+	forwardedParameterProto     = "proto"
+	forwardedParameterHost      = "host"
+	forwardedElementSeparator   = ','
+	forwardedParameterSeparator = ';'
+	forwardedQuote              = '"'
+	forwardedSchemeHTTPS        = "https"
+	forwardedSchemeHTTP         = "http"
+)
+
+type forwardedRequestContextKey struct{}
+
+// forwardedRequest records what a trusted proxy reported about a request and
+// the connection peer it arrived from.
+type forwardedRequest struct {
+	peerAddress string
+	scheme      string
+	host        string
+}
+
+// newForwardedHeadersHandler resolves the client address, scheme and host
+// reported by trusted reverse proxies. The RFC 7239 Forwarded header takes
+// precedence over the X-Forwarded-* headers. The resolved client replaces
+// RemoteAddr so that logging and address-based policies see it, while the
+// original peer stays available through peerAddressFromRequest. Requests from
+// untrusted peers are passed through untouched, whatever headers they carry.
+func newForwardedHeadersHandler(next http.Handler, trustedProxies []netip.Prefix) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if !isTrustedProxy(request.RemoteAddr, trustedProxies) {
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		forwardedChain, forwardedScheme, forwardedHost := readForwardedHeaders(request.Header)
+		if len(forwardedChain) == 0 && forwardedScheme == "" && forwardedHost == "" {
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		forwarded := forwardedRequest{peerAddress: request.RemoteAddr, host: forwardedHost}
+		if forwardedScheme == forwardedSchemeHTTPS || forwardedScheme == forwardedSchemeHTTP {
+			forwarded.scheme = forwardedScheme
+		}
+		forwardedRequestCopy := request.WithContext(context.WithValue(request.Context(), forwardedRequestContextKey{}, forwarded))
+		if clientAddress := resolveClientAddress(forwardedChain, trustedProxies, request.RemoteAddr); clientAddress != "" {
+			forwardedRequestCopy.RemoteAddr = clientAddress
+		}
+		if forwardedHost != "" {
+			forwardedRequestCopy.Host = forwardedHost
+		}
+		next.ServeHTTP(responseWriter, forwardedRequestCopy)
+	})
+}
+
+// peerAddressFromRequest returns the connection peer when it differs from the
+// client recorded in RemoteAddr, and an empty string otherwise.
+func peerAddressFromRequest(request *http.Request) string {
+	forwarded, found := request.Context().Value(forwardedRequestContextKey{}).(forwardedRequest)
+	if !found || forwarded.peerAddress == request.RemoteAddr {
+		return ""
+	}
+	return forwarded.peerAddress
+}
+
+// requestScheme reports the scheme the client used, honouring a scheme
+// forwarded by a trusted proxy that terminated TLS.
+func requestScheme(request *http.Request) string {
+	if forwarded, found := request.Context().Value(forwardedRequestContextKey{}).(forwardedRequest); found && forwarded.scheme != "" {
+		return forwarded.scheme
+	}
+	if request.TLS != nil {
+		return forwardedSchemeHTTPS
+	}
+	return forwardedSchemeHTTP
+}
+
+// isForwardedHost reports whether a trusted proxy supplied the request host.
+func isForwardedHost(request *http.Request) bool {
+	forwarded, found := request.Context().Value(forwardedRequestContextKey{}).(forwardedRequest)
+	return found && forwarded.host != ""
+}
+
+// isTrustedProxy reports whether the peer may supply forwarding headers. Peers
+// without an IP address connect through a Unix domain socket, which only local
+// processes with access to the socket file can reach, and are trusted whenever
+// trusted proxies are configured.
+func isTrustedProxy(remoteAddress string, trustedProxies []netip.Prefix) bool {
+	if len(trustedProxies) == 0 {
+		return false
+	}
+	peerAddress, parsed := parseForwardedAddress(remoteAddress)
+	if !parsed {
+		return true
+	}
+	return networksContain(trustedProxies, peerAddress)
+}
+
+func networksContain(networks []netip.Prefix, address netip.Addr) bool {
+	for _, network := range networks {
+		if network.Contains(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveClientAddress walks the forwarding chain from the closest hop and
+// returns the first address that is not a trusted proxy. When every hop is
+// trusted the originating address is used. Hops that are not IP addresses,
+// such as RFC 7239 obfuscated identifiers, end the walk because nothing beyond
+// them can be verified. The result is a host:port pair like RemoteAddr, using
+// the forwarded port when the hop carries one and the peer's port otherwise.
+func resolveClientAddress(forwardedChain []string, trustedProxies []netip.Prefix, peerAddress string) string {
+	resolvedAddress := ""
+	for index := len(forwardedChain) - 1; index >= 0; index-- {
+		hopAddress, parsed := parseForwardedAddress(forwardedChain[index])
+		if !parsed {
+			break
+		}
+		resolvedAddress = net.JoinHostPort(hopAddress.String(), forwardedPort(forwardedChain[index], peerAddress))
+		if !networksContain(trustedProxies, hopAddress) {
+			break
+		}
+	}
+	return resolvedAddress
+}
+
+// forwardedPort returns the numeric port of a forwarding hop, falling back to
+// the port of the connection peer, or "0" for peers without one such as Unix
+// domain socket clients.
+func forwardedPort(hop string, peerAddress string) string {
+	for _, candidate := range []string{strings.TrimSpace(hop), peerAddress} {
+		if _, port, splitErr := net.SplitHostPort(candidate); splitErr == nil {
+			if _, parseErr := strconv.ParseUint(port, 10, 16); parseErr == nil {
+				return port
+			}
+		}
+	}
+	return "0"
+}
+
+// parseForwardedAddress accepts the address forms used by RemoteAddr,
+// X-Forwarded-For and Forwarded: a bare IP, host:port, and bracketed IPv6
+// with or without a port.
+func parseForwardedAddress(rawValue string) (netip.Addr, bool) {
+	trimmedValue := strings.TrimSpace(rawValue)
+	if host, _, splitErr := net.SplitHostPort(trimmedValue); splitErr == nil {
+		trimmedValue = host
+	}
+	trimmedValue = strings.TrimSuffix(strings.TrimPrefix(trimmedValue, "["), "]")
+	address, parseErr := netip.ParseAddr(trimmedValue)
+	if parseErr != nil {
+		return netip.Addr{}, false
+	}
+	return address.Unmap(), true
+}
+
+// readForwardedHeaders returns the forwarding chain ordered from the client
+// to the closest proxy, along with the scheme and host reported by the
+// outermost proxy.
+func readForwardedHeaders(header http.Header) ([]string, string, string) {
+	if forwardedValues := header.Values(forwardedHeaderName); len(forwardedValues) > 0 {
+		var forwardedChain []string
+		forwardedScheme := ""
+		forwardedHost := ""
+		for elementIndex, element := range splitForwardedList(strings.Join(forwardedValues, ","), forwardedElementSeparator) {
+			for _, parameter := range splitForwardedList(element, forwardedParameterSeparator) {
+				name, value, found := strings.Cut(parameter, "=")
+				if !found {
+					continue
+				}
+				value = unquoteForwardedValue(strings.TrimSpace(value))
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case forwardedParameterFor:
+					forwardedChain = append(forwardedChain, value)
+				case forwardedParameterProto:
+					if elementIndex == 0 {
+						forwardedScheme = strings.ToLower(value)
+					}
+				case forwardedParameterHost:
+					if elementIndex == 0 {
+						forwardedHost = value
+					}
+				}
+			}
+		}
+		return forwardedChain, forwardedScheme, forwardedHost
+	}
+	var forwardedChain []string
+	for _, hop := range strings.Split(strings.Join(header.Values(forwardedForHeaderName), ","), ",") {
+		if trimmedHop := strings.TrimSpace(hop); trimmedHop != "" {
+			forwardedChain = append(forwardedChain, trimmedHop)
+		}
+	}
+	return forwardedChain, strings.ToLower(firstListValue(header.Get(forwardedProtoHeaderName))), firstListValue(header.Get(forwardedHostHeaderName))
+}
+
+func firstListValue(rawValue string) string {
+	firstValue, _, _ := strings.Cut(rawValue, ",")
+	return strings.TrimSpace(firstValue)
+}
+
+// splitForwardedList splits on the separator outside quoted strings.
+func splitForwardedList(rawValue string, separator byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for index := 0; index < len(rawValue); index++ {
+		switch rawValue[index] {
+		case '\\':
+			if inQuotes {
+				index++
+			}
+		case forwardedQuote:
+			inQuotes = !inQuotes
+		case separator:
+			if !inQuotes {
+				parts = append(parts, strings.TrimSpace(rawValue[start:index]))
+				start = index + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(rawValue[start:]))
+}
+
+func unquoteForwardedValue(value string) string {
+	if len(value) < 2 || value[0] != forwardedQuote || value[len(value)-1] != forwardedQuote {
+		return value
+	}
+	var builder strings.Builder
+	for index := 1; index < len(value)-1; index++ {
+		if value[index] == '\\' && index+1 < len(value)-1 {
+			index++
+		}
+		builder.WriteByte(value[index])
+	}
+	return builder.String()
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+type forwardedObservation struct {
+	remoteAddress string
+	host          string
+	scheme        string
+	peerAddress   string
+}
+
+func observeForwardedRequest(t *testing.T, remoteAddress string, headers map[string]string) forwardedObservation {
+	t.Helper()
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")}
+	var observation forwardedObservation
+	handler := newForwardedHeadersHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		observation = forwardedObservation{
+			remoteAddress: request.RemoteAddr,
+			host:          request.Host,
+			scheme:        requestScheme(request),
+			peerAddress:   peerAddressFromRequest(request),
+		}
+	}), trustedProxies)
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = remoteAddress
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	return observation
+}
+
+func TestForwardedHeadersHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		remoteAddress string
+		headers       map[string]string
+		expected      forwardedObservation
+	}{
+		{
+			name:          "untrusted peer headers ignored",
+			remoteAddress: "198.51.100.9:4000",
+			headers:       map[string]string{forwardedForHeaderName: "203.0.113.5", forwardedProtoHeaderName: "https", forwardedHostHeaderName: "spoofed.test"},
+			expected:      forwardedObservation{remoteAddress: "198.51.100.9:4000", host: "example.com", scheme: "http"},
+		},
+		{
+			name:          "x-forwarded headers from trusted peer",
+			remoteAddress: "10.0.0.2:4000",
+			headers:       map[string]string{forwardedForHeaderName: "203.0.113.5, 10.0.0.7", forwardedProtoHeaderName: "https", forwardedHostHeaderName: "docs.example.com"},
+			expected:      forwardedObservation{remoteAddress: "203.0.113.5:4000", host: "docs.example.com", scheme: "https", peerAddress: "10.0.0.2:4000"},
+		},
+		{
+			name:          "spoofed leftmost entry skipped",
+			remoteAddress: "10.0.0.2:4000",
+			headers:       map[string]string{forwardedForHeaderName: "192.0.2.66, 203.0.113.5"},
+			expected:      forwardedObservation{remoteAddress: "203.0.113.5:4000", host: "example.com", scheme: "http", peerAddress: "10.0.0.2:4000"},
+		},
+		{
+			name:          "rfc 7239 forwarded takes precedence",
+			remoteAddress: "[2001:db8::2]:4000",
+			headers: map[string]string{
+				forwardedHeaderName:    `for="[2001:db8:cafe::17]:4711";proto=https;host="docs.example.com", for=10.0.0.7`,
+				forwardedForHeaderName: "192.0.2.66",
+			},
+			expected: forwardedObservation{remoteAddress: "[2001:db8:cafe::17]:4711", host: "docs.example.com", scheme: "https", peerAddress: "[2001:db8::2]:4000"},
+		},
+		{
+			name:          "obfuscated hop stops the walk",
+			remoteAddress: "10.0.0.2:4000",
+			headers:       map[string]string{forwardedHeaderName: `for=203.0.113.5, for=_hidden, for=10.0.0.7`},
+			expected:      forwardedObservation{remoteAddress: "10.0.0.7:4000", host: "example.com", scheme: "http", peerAddress: "10.0.0.2:4000"},
+		},
+		{
+			name:          "unix socket peer trusted",
+			remoteAddress: "@",
+			headers:       map[string]string{forwardedForHeaderName: "203.0.113.5"},
+			expected:      forwardedObservation{remoteAddress: "203.0.113.5:0", host: "example.com", scheme: "http", peerAddress: "@"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			observation := observeForwardedRequest(t, testCase.remoteAddress, testCase.headers)
+			if observation != testCase.expected {
+				t.Fatalf("expected %+v, got %+v", testCase.expected, observation)
+			}
+		})
+	}
+}
+
+func TestHTTPSRedirectHandlerServesRequestsForwardedOverHTTPS(t *testing.T) {
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	content := http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusNoContent)
+	})
+	handler := newForwardedHeadersHandler(newHTTPSRedirectHandler(content, "8443"), trustedProxies)
+
+	request := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	request.RemoteAddr = "10.0.0.2:4000"
+	request.Header.Set(forwardedProtoHeaderName, "https")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("expected content for forwarded https request, got %d", recorder.Code)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/docs", nil)
+	request.RemoteAddr = "10.0.0.2:4000"
+	request.Header.Set(forwardedHostHeaderName, "docs.example.com:8080")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if location := recorder.Header().Get("Location"); location != "https://docs.example.com/docs" {
+		t.Fatalf("expected redirect to forwarded host, got %q", location)
+	}
+}
+
+func TestFormatConsoleRequestLogReportsForwardingPeer(t *testing.T) {
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	startTime := time.Date(2025, time.October, 8, 12, 30, 0, 0, time.UTC)
+	var message string
+	handler := newForwardedHeadersHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		message = formatConsoleRequestLog(request, http.StatusOK, 512, startTime)
+	}), trustedProxies)
+
+	request := httptestNewRequest("GET", "/index.html", "HTTP/1.1", "10.0.0.2:54321")
+	request.Header = http.Header{}
+	request.Header.Set(forwardedForHeaderName, "203.0.113.5")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	expected := "203.0.113.5 - - [08/Oct/2025 12:30:00] \"GET /index.html HTTP/1.1\" 200 512 via 10.0.0.2"
+	if message != expected {
+		t.Fatalf("expected %s, got %s", expected, message)
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temirov/ghttp/internal/hostingfiles"
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+const (
+	logMessageHostingFileInvalid = "hosting file ignored"
+	logMessageHostingLineInvalid = "hosting file line ignored"
+	logFieldFile                 = "file"
+)
+
+// hostingFile caches the parsed contents of _redirects or _headers and parses
+// the file again whenever its modification time or size changes. Lines that
+// fail to parse are logged and skipped, and a file that cannot be read leaves
+// the previous contents in effect.
+type hostingFile[Entry any] struct {
+	filePath       string
+	parse          func(io.Reader) ([]Entry, []hostingfiles.LineError, error)
+	loggingService *logging.Service
+
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	exists  bool
+	entries []Entry
+}
+
+func (file *hostingFile[Entry]) current() []Entry {
+	fileInfo, statErr := os.Stat(file.filePath)
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	if statErr != nil || fileInfo.IsDir() {
+		file.exists = false
+		file.entries = nil
+		return nil
+	}
+	if file.exists && fileInfo.ModTime().Equal(file.modTime) && fileInfo.Size() == file.size {
+		return file.entries
+	}
+	file.exists = true
+	file.modTime = fileInfo.ModTime()
+	file.size = fileInfo.Size()
+	entries, lineErrors, loadErr := file.load()
+	if loadErr != nil {
+		if file.loggingService != nil {
+			file.loggingService.Error(logMessageHostingFileInvalid, loadErr, logging.String(logFieldFile, file.filePath))
+		}
+		return file.entries
+	}
+	if file.loggingService != nil {
+		for _, lineError := range lineErrors {
+			file.loggingService.Warn(logMessageHostingLineInvalid,
+				logging.String(logFieldFile, file.filePath),
+				logging.Int(logFieldLineNumber, lineError.Line),
+				logging.ErrorField(lineError.Err),
+			)
+		}
+	}
+	file.entries = entries
+	return entries
+}
+
+func (file *hostingFile[Entry]) load() ([]Entry, []hostingfiles.LineError, error) {
+	openedFile, openErr := os.Open(file.filePath)
+	if openErr != nil {
+		return nil, nil, fmt.Errorf("open %s: %w", file.filePath, openErr)
+	}
+	defer openedFile.Close()
+	return file.parse(openedFile)
+}
+
+type hostingFilesHandler struct {
+	next       http.Handler
+	fileSystem http.FileSystem
+	redirects  *hostingFile[hostingfiles.Redirect]
+	headers    *hostingFile[hostingfiles.HeaderRule]
+}
+
+// newHostingFilesHandler applies the _redirects and _headers files found in
+// the served directory the way static hosts do. Headers are matched against
+// the requested path. Redirect lines are evaluated in order and the first
+// match fires; unless forced with "!", a line does not apply when the path
+// names an existing file or a directory with an index document. The files
+// themselves are not served.
+func newHostingFilesHandler(next http.Handler, directoryPath string, loggingService *logging.Service) http.Handler {
+	return hostingFilesHandler{
+		next:       next,
+		fileSystem: http.Dir(directoryPath),
+		redirects: &hostingFile[hostingfiles.Redirect]{
+			filePath:       filepath.Join(directoryPath, hostingfiles.RedirectsFileName),
+			parse:          hostingfiles.ParseRedirects,
+			loggingService: loggingService,
+		},
+		headers: &hostingFile[hostingfiles.HeaderRule]{
+			filePath:       filepath.Join(directoryPath, hostingfiles.HeadersFileName),
+			parse:          hostingfiles.ParseHeaders,
+			loggingService: loggingService,
+		},
+	}
+}
+
+func (handler hostingFilesHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	requestPath := request.URL.Path
+	for name, values := range hostingfiles.HeadersFor(handler.headers.current(), requestPath) {
+		responseWriter.Header()[name] = values
+	}
+	switch pathpkg.Clean(requestPath) {
+	case "/" + hostingfiles.RedirectsFileName, "/" + hostingfiles.HeadersFileName:
+		http.NotFound(responseWriter, request)
+		return
+	}
+	for _, redirect := range handler.redirects.current() {
+		target, matched := redirect.Match(requestPath, request.URL.Query())
+		if !matched {
+			continue
+		}
+		if !redirect.Force && handler.pathExists(requestPath) {
+			break
+		}
+		responseWriter.Header().Set(ruleHeaderName, fmt.Sprintf("%s:%d", hostingfiles.RedirectsFileName, redirect.Line))
+		if !redirect.IsRewrite() {
+			http.Redirect(responseWriter, request, target, redirect.Status)
+			return
+		}
+		targetURL, parseErr := url.Parse(target)
+		if parseErr != nil {
+			break
+		}
+		targetPath := targetURL.Path
+		if pathpkg.Base(targetPath) == directoryIndexCandidates[0] {
+			// http.FileServer redirects requests for index.html to the
+			// directory, so address the directory instead.
+			targetPath = strings.TrimSuffix(targetPath, directoryIndexCandidates[0])
+		}
+		rewrittenRequest := rewriteRequestPath(request, targetPath)
+		rewrittenRequest.URL.RawQuery = targetURL.RawQuery
+		rewrittenRequest.RequestURI = rewrittenRequest.URL.RequestURI()
+		if redirect.Status != http.StatusOK {
+			responseWriter = &statusOverrideWriter{ResponseWriter: responseWriter, statusCode: redirect.Status}
+		}
+		handler.next.ServeHTTP(responseWriter, rewrittenRequest)
+		return
+	}
+	handler.next.ServeHTTP(responseWriter, request)
+}
+
+// pathExists reports whether the path would be served from disk, which
+// shadows redirect lines that are not forced.
+func (handler hostingFilesHandler) pathExists(requestPath string) bool {
+	file, openErr := handler.fileSystem.Open(requestPath)
+	if openErr != nil {
+		return false
+	}
+	fileInfo, statErr := file.Stat()
+	file.Close()
+	if statErr != nil {
+		return false
+	}
+	if fileInfo.IsDir() {
+		return directoryIndexExists(handler.fileSystem, requestPath)
+	}
+	return true
+}
+
+// statusOverrideWriter replaces a successful status with the status of the
+// redirect line, so that "/* /404.html 404" serves the page as a 404.
+type statusOverrideWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+func (writer *statusOverrideWriter) WriteHeader(statusCode int) {
+	if writer.wroteHeader {
+		return
+	}
+	writer.wroteHeader = true
+	if statusCode == http.StatusOK {
+		statusCode = writer.statusCode
+	}
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *statusOverrideWriter) Write(content []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.WriteHeader(http.StatusOK)
+	}
+	return writer.ResponseWriter.Write(content)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (writer *statusOverrideWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+func TestHostingFilesHandlerAppliesRedirectsAndHeaders(t *testing.T) {
+	directoryPath := t.TempDir()
+	writeTestFile(t, filepath.Join(directoryPath, "index.html"), "home")
+	writeTestFile(t, filepath.Join(directoryPath, "about.html"), "about")
+	writeTestFile(t, filepath.Join(directoryPath, "404.html"), "missing")
+	if mkdirErr := os.Mkdir(filepath.Join(directoryPath, "app"), 0o755); mkdirErr != nil {
+		t.Fatalf("mkdir: %v", mkdirErr)
+	}
+	writeTestFile(t, filepath.Join(directoryPath, "app", "index.html"), "app shell")
+	writeTestFile(t, filepath.Join(directoryPath, "_redirects"), `/about         /elsewhere        302
+/old/:slug     /new/:slug
+/forced        /about.html       200!
+/app/*         /app/index.html   200
+/*             /404.html         404
+`)
+	writeTestFile(t, filepath.Join(directoryPath, "_headers"), `/*
+  X-Frame-Options: DENY
+/app/*
+  Cache-Control: no-store
+`)
+	handler := newHostingFilesHandler(http.FileServer(http.Dir(directoryPath)), directoryPath, nil)
+
+	testCases := []struct {
+		name                 string
+		target               string
+		expectedStatus       int
+		expectedLocation     string
+		expectedBody         string
+		expectedCacheControl string
+	}{
+		{name: "existing file shadows rule", target: "/about.html", expectedStatus: http.StatusOK, expectedBody: "about"},
+		{name: "redirect with placeholder", target: "/old/post?ref=feed", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/new/post?ref=feed"},
+		{name: "forced rewrite", target: "/forced", expectedStatus: http.StatusOK, expectedBody: "about"},
+		{name: "splat rewrite", target: "/app/settings/profile", expectedStatus: http.StatusOK, expectedBody: "app shell", expectedCacheControl: "no-store"},
+		{name: "not found rewrite keeps status", target: "/nope", expectedStatus: http.StatusNotFound, expectedBody: "missing"},
+		{name: "hosting files are hidden", target: "/_redirects", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testCase.target, nil))
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if location := recorder.Header().Get("Location"); location != testCase.expectedLocation {
+				t.Fatalf("expected location %q, got %q", testCase.expectedLocation, location)
+			}
+			if testCase.expectedBody != "" && recorder.Body.String() != testCase.expectedBody {
+				t.Fatalf("expected body %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+			if recorder.Header().Get("X-Frame-Options") != "DENY" {
+				t.Fatalf("expected site wide header, got %v", recorder.Header())
+			}
+			if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != testCase.expectedCacheControl {
+				t.Fatalf("expected cache control %q, got %q", testCase.expectedCacheControl, cacheControl)
+			}
+		})
+	}
+}
+
+func TestHostingFilesHandlerReloadsChangedFiles(t *testing.T) {
+	directoryPath := t.TempDir()
+	redirectsPath := filepath.Join(directoryPath, "_redirects")
+	writeTestFile(t, redirectsPath, "/go /first\n")
+	handler := newHostingFilesHandler(http.NotFoundHandler(), directoryPath, nil)
+
+	assertLocation := func(expectedLocation string) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/go", nil))
+		if location := recorder.Header().Get("Location"); location != expectedLocation {
+			t.Fatalf("expected location %q, got %q", expectedLocation, location)
+		}
+	}
+	touch := func(content string, modTime time.Time) {
+		t.Helper()
+		writeTestFile(t, redirectsPath, content)
+		if chtimesErr := os.Chtimes(redirectsPath, modTime, modTime); chtimesErr != nil {
+			t.Fatalf("chtimes: %v", chtimesErr)
+		}
+	}
+
+	assertLocation("/first")
+	touch("/go /second\n", time.Now().Add(time.Minute))
+	assertLocation("/second")
+	touch("/go /third 999\n/go /fourth\n", time.Now().Add(2*time.Minute))
+	assertLocation("/fourth")
+	if removeErr := os.Remove(redirectsPath); removeErr != nil {
+		t.Fatalf("remove: %v", removeErr)
+	}
+	assertLocation("")
+}
+
+func TestHostingFilesHandlerLogsSkippedLines(t *testing.T) {
+	observedCore, observedLogs := observer.New(zapcore.DebugLevel)
+	loggingService, serviceErr := logging.NewServiceWithLogger(logging.TypeJSON, zap.New(observedCore))
+	if serviceErr != nil {
+		t.Fatalf("logging service: %v", serviceErr)
+	}
+	directoryPath := t.TempDir()
+	writeTestFile(t, filepath.Join(directoryPath, "_redirects"), "/api/* https://backend.test/:splat 200\n/go /kept\n")
+	handler := newHostingFilesHandler(http.NotFoundHandler(), directoryPath, loggingService)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/go", nil))
+	if location := recorder.Header().Get("Location"); location != "/kept" {
+		t.Fatalf("expected the valid line to apply, got location %q", location)
+	}
+	entries := observedLogs.FilterMessage("hosting file line ignored").All()
+	if len(entries) != 1 || entries[0].ContextMap()["line"] != int64(1) {
+		t.Fatalf("expected line 1 to be logged once, got %v", entries)
+	}
+}
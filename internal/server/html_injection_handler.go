@@ -0,0 +1,103 @@
+package server
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+const (
+	contentTypeHeaderName   = "Content-Type"
+	contentLengthHeaderName = "Content-Length"
+	htmlMediaType           = "text/html"
+)
+
+var htmlBodyClosingTag = []byte("</body>")
+
+// newHTMLInjectionHandler inserts the snippet before the closing body tag of
+// successful HTML responses, or appends it when the document has none. Other
+// responses, including partial content and 304s, stream through unchanged.
+func newHTMLInjectionHandler(next http.Handler, snippet string) http.Handler {
+	snippetBytes := []byte(snippet)
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet && request.Method != http.MethodHead {
+			next.ServeHTTP(responseWriter, request)
+			return
+		}
+		injectionWriter := &htmlInjectionWriter{ResponseWriter: responseWriter, snippet: snippetBytes, headOnly: request.Method == http.MethodHead}
+		next.ServeHTTP(injectionWriter, request)
+		injectionWriter.finish()
+	})
+}
+
+// htmlInjectionWriter buffers HTML bodies so that the snippet can be inserted
+// and Content-Length recomputed before anything reaches the client.
+type htmlInjectionWriter struct {
+	http.ResponseWriter
+	snippet       []byte
+	headOnly      bool
+	headerWritten bool
+	buffering     bool
+	statusCode    int
+	body          bytes.Buffer
+}
+
+func (writer *htmlInjectionWriter) WriteHeader(statusCode int) {
+	if writer.headerWritten {
+		return
+	}
+	writer.headerWritten = true
+	writer.statusCode = statusCode
+	if statusCode == http.StatusOK && isHTMLContentType(writer.Header().Get(contentTypeHeaderName)) {
+		writer.buffering = true
+		writer.Header().Del(contentLengthHeaderName)
+		return
+	}
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (writer *htmlInjectionWriter) Write(content []byte) (int, error) {
+	if !writer.headerWritten {
+		writer.WriteHeader(http.StatusOK)
+	}
+	if writer.buffering {
+		return writer.body.Write(content)
+	}
+	return writer.ResponseWriter.Write(content)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (writer *htmlInjectionWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+func (writer *htmlInjectionWriter) finish() {
+	if !writer.buffering {
+		return
+	}
+	if writer.headOnly {
+		writer.ResponseWriter.WriteHeader(writer.statusCode)
+		return
+	}
+	document := injectBeforeBodyEnd(writer.body.Bytes(), writer.snippet)
+	writer.Header().Set(contentLengthHeaderName, strconv.Itoa(len(document)))
+	writer.ResponseWriter.WriteHeader(writer.statusCode)
+	_, _ = writer.ResponseWriter.Write(document)
+}
+
+func injectBeforeBodyEnd(document []byte, snippet []byte) []byte {
+	closingIndex := bytes.LastIndex(bytes.ToLower(document), htmlBodyClosingTag)
+	if closingIndex < 0 {
+		return append(document, snippet...)
+	}
+	injected := make([]byte, 0, len(document)+len(snippet))
+	injected = append(injected, document[:closingIndex]...)
+	injected = append(injected, snippet...)
+	return append(injected, document[closingIndex:]...)
+}
+
+func isHTMLContentType(contentType string) bool {
+	mediaType, _, parseErr := mime.ParseMediaType(contentType)
+	return parseErr == nil && mediaType == htmlMediaType
+}
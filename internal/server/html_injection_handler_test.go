@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestHTMLInjectionHandlerInsertsSnippet(t *testing.T) {
+	testCases := []struct {
+		name         string
+		contentType  string
+		statusCode   int
+		body         string
+		expectedBody string
+	}{
+		{
+			name:         "before closing body tag",
+			contentType:  "text/html; charset=utf-8",
+			statusCode:   http.StatusOK,
+			body:         "<html><body><p>hi</p></BODY></html>",
+			expectedBody: "<html><body><p>hi</p><!--x--></BODY></html>",
+		},
+		{
+			name:         "appended without body tag",
+			contentType:  "text/html",
+			statusCode:   http.StatusOK,
+			body:         "<p>fragment</p>",
+			expectedBody: "<p>fragment</p><!--x-->",
+		},
+		{
+			name:         "non html untouched",
+			contentType:  "text/css",
+			statusCode:   http.StatusOK,
+			body:         "body{}",
+			expectedBody: "body{}",
+		},
+		{
+			name:         "error untouched",
+			contentType:  "text/html",
+			statusCode:   http.StatusNotFound,
+			body:         "<body>missing</body>",
+			expectedBody: "<body>missing</body>",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := newHTMLInjectionHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+				responseWriter.Header().Set("Content-Type", testCase.contentType)
+				responseWriter.Header().Set("Content-Length", strconv.Itoa(len(testCase.body)))
+				responseWriter.WriteHeader(testCase.statusCode)
+				_, _ = responseWriter.Write([]byte(testCase.body))
+			}), "<!--x-->")
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if recorder.Code != testCase.statusCode {
+				t.Fatalf("expected status %d, got %d", testCase.statusCode, recorder.Code)
+			}
+			if recorder.Body.String() != testCase.expectedBody {
+				t.Fatalf("expected body %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+			if contentLength := recorder.Header().Get("Content-Length"); contentLength != strconv.Itoa(len(testCase.expectedBody)) {
+				t.Fatalf("expected content length %d, got %s", len(testCase.expectedBody), contentLength)
+			}
+		})
+	}
+}
+
+func TestHTMLInjectionHandlerCoversRenderedMarkdown(t *testing.T) {
+	directoryPath := t.TempDir()
+	if writeErr := os.WriteFile(filepath.Join(directoryPath, "notes.md"), []byte("# Notes\n"), 0o644); writeErr != nil {
+		t.Fatalf("write markdown: %v", writeErr)
+	}
+	fileSystem := http.Dir(directoryPath)
+	handler := newHTMLInjectionHandler(newMarkdownHandler(http.FileServer(fileSystem), fileSystem, false, true), liveReloadSnippet)
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/notes.md", nil))
+
+	expectedSuffix := liveReloadSnippet + "</body></html>"
+	body := recorder.Body.String()
+	if len(body) < len(expectedSuffix) || body[len(body)-len(expectedSuffix):] != expectedSuffix {
+		t.Fatalf("expected rendered markdown to end with the live reload script, got %q", body)
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHTTPSPort                  = "443"
+	strictTransportSecurityHeaderName = "Strict-Transport-Security"
+	strictTransportSecurityMaxAge     = "max-age="
+	strictTransportSecuritySubdomains = "includeSubDomains"
+	strictTransportSecurityPreload    = "preload"
+)
+
+// StrictTransportSecurityConfiguration describes the Strict-Transport-Security
+// header emitted on HTTPS responses.
+type StrictTransportSecurityConfiguration struct {
+	MaxAge            time.Duration
+	IncludeSubdomains bool
+	Preload           bool
+}
+
+// HeaderValue renders the configuration as a Strict-Transport-Security value.
+func (configuration StrictTransportSecurityConfiguration) HeaderValue() string {
+	directives := []string{strictTransportSecurityMaxAge + strconv.FormatInt(int64(configuration.MaxAge/time.Second), 10)}
+	if configuration.IncludeSubdomains {
+		directives = append(directives, strictTransportSecuritySubdomains)
+	}
+	if configuration.Preload {
+		directives = append(directives, strictTransportSecurityPreload)
+	}
+	return strings.Join(directives, "; ")
+}
+
+func newStrictTransportSecurityHandler(next http.Handler, configuration StrictTransportSecurityConfiguration) http.Handler {
+	headerValue := configuration.HeaderValue()
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if requestScheme(request) == forwardedSchemeHTTPS {
+			responseWriter.Header().Set(strictTransportSecurityHeaderName, headerValue)
+		}
+		next.ServeHTTP(responseWriter, request)
+	})
+}
+
+// httpsRedirectHandler answers plain HTTP requests with a redirect to HTTPS.
+// Requests that a trusted proxy reports as already made over HTTPS are served
+// by the next handler instead, which avoids redirect loops behind proxies that
+// terminate TLS.
+type httpsRedirectHandler struct {
+	next      http.Handler
+	httpsPort string
+}
+
+func newHTTPSRedirectHandler(next http.Handler, httpsPort string) http.Handler {
+	return httpsRedirectHandler{next: next, httpsPort: httpsPort}
+}
+
+func (handler httpsRedirectHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if requestScheme(request) == forwardedSchemeHTTPS {
+		handler.next.ServeHTTP(responseWriter, request)
+		return
+	}
+	http.Redirect(responseWriter, request, handler.targetURL(request), http.StatusPermanentRedirect)
+}
+
+func (handler httpsRedirectHandler) targetURL(request *http.Request) string {
+	host := request.Host
+	httpsPort := handler.httpsPort
+	if isForwardedHost(request) {
+		// The public HTTPS port of a proxied site is unknown; assume the default.
+		httpsPort = defaultHTTPSPort
+	}
+	if splitHost, _, splitErr := net.SplitHostPort(host); splitErr == nil {
+		host = splitHost
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	authority := host
+	if strings.Contains(host, ":") {
+		authority = "[" + host + "]"
+	}
+	if httpsPort != "" && httpsPort != defaultHTTPSPort {
+		authority = net.JoinHostPort(host, httpsPort)
+	}
+	return "https://" + authority + request.URL.RequestURI()
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPSRedirectHandlerRedirectsToHTTPSPort(t *testing.T) {
+	testCases := []struct {
+		name             string
+		httpsPort        string
+		host             string
+		target           string
+		expectedLocation string
+	}{
+		{
+			name:             "custom port with query",
+			httpsPort:        "8443",
+			host:             "localhost:8080",
+			target:           "/docs/index.html?version=1",
+			expectedLocation: "https://localhost:8443/docs/index.html?version=1",
+		},
+		{
+			name:             "default https port",
+			httpsPort:        "443",
+			host:             "example.test",
+			target:           "/",
+			expectedLocation: "https://example.test/",
+		},
+		{
+			name:             "ipv6 host",
+			httpsPort:        "8443",
+			host:             "[::1]:8080",
+			target:           "/a",
+			expectedLocation: "https://[::1]:8443/a",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := newHTTPSRedirectHandler(http.NotFoundHandler(), testCase.httpsPort)
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, testCase.target, nil)
+			request.Host = testCase.host
+
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusPermanentRedirect {
+				t.Fatalf("expected 308 status, got %d", recorder.Code)
+			}
+			if location := recorder.Header().Get("Location"); location != testCase.expectedLocation {
+				t.Fatalf("expected location %s, got %s", testCase.expectedLocation, location)
+			}
+		})
+	}
+}
+
+func TestStrictTransportSecurityHandlerSetsHeaderOnlyOverTLS(t *testing.T) {
+	configuration := StrictTransportSecurityConfiguration{MaxAge: 24 * time.Hour, IncludeSubdomains: true, Preload: true}
+	handler := newStrictTransportSecurityHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {}), configuration)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.TLS = &tls.ConnectionState{}
+	handler.ServeHTTP(recorder, request)
+	expected := "max-age=86400; includeSubDomains; preload"
+	if headerValue := recorder.Header().Get(strictTransportSecurityHeaderName); headerValue != expected {
+		t.Fatalf("expected %s, got %s", expected, headerValue)
+	}
+
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(recorder, request)
+	if headerValue := recorder.Header().Get(strictTransportSecurityHeaderName); headerValue != "" {
+		t.Fatalf("expected no header over plain HTTP, got %s", headerValue)
+	}
+}
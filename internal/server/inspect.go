@@ -0,0 +1,252 @@
+package server
+
+import (
+	"crypto/tls"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	inspectEchoPath         = internalRoutePrefix + "echo"
+	inspectHeadersPath      = internalRoutePrefix + "headers"
+	inspectIPPath           = internalRoutePrefix + "ip"
+	inspectTLSPath          = internalRoutePrefix + "tls"
+	inspectStatusPath       = internalRoutePrefix + "status/{code}"
+	inspectStreamBytesPath  = internalRoutePrefix + "stream-bytes/{count}"
+	inspectDelayPath        = internalRoutePrefix + "delay/{duration}"
+	inspectCookiesPath      = internalRoutePrefix + "cookies"
+	inspectSetCookiesPath   = internalRoutePrefix + "cookies/set"
+	inspectClearCookiesPath = internalRoutePrefix + "cookies/delete"
+	inspectRedirectPath     = internalRoutePrefix + "redirect/{count}"
+	inspectRedirectPrefix   = internalRoutePrefix + "redirect/"
+	inspectChunkSizeQuery   = "chunk_size"
+	// inspectMaxEchoBody bounds how much of a request body is echoed back.
+	inspectMaxEchoBody = 1 << 20
+	// inspectMaxStreamBytes bounds stream-bytes responses.
+	inspectMaxStreamBytes   = 100 << 20
+	inspectDefaultChunkSize = 10 * 1024
+	inspectMaxRedirects     = 100
+	locationHeaderName      = "Location"
+	octetStreamContentType  = "application/octet-stream"
+)
+
+// inspectionEcho describes a request as the server received it.
+type inspectionEcho struct {
+	Method   string              `json:"method"`
+	URL      string              `json:"url"`
+	Protocol string              `json:"protocol"`
+	Host     string              `json:"host"`
+	ClientIP string              `json:"client_ip"`
+	Peer     string              `json:"peer,omitempty"`
+	Headers  map[string][]string `json:"headers"`
+	Body     string              `json:"body,omitempty"`
+	TLS      *inspectionTLS      `json:"tls,omitempty"`
+}
+
+// inspectionTLS describes the TLS handshake of the connection a request
+// arrived on.
+type inspectionTLS struct {
+	Version            string                  `json:"version"`
+	CipherSuite        string                  `json:"cipher_suite"`
+	ServerName         string                  `json:"server_name"`
+	NegotiatedProtocol string                  `json:"negotiated_protocol"`
+	Resumed            bool                    `json:"resumed"`
+	ClientCertificates []inspectionCertificate `json:"client_certificates,omitempty"`
+}
+
+type inspectionCertificate struct {
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// registerInspectionRoutes mounts httpbin-style endpoints that describe the
+// request or produce a response on demand:
+//
+//	/__ghttp/echo                 method, URL, protocol, client IP, headers, body and TLS state
+//	/__ghttp/headers              request headers
+//	/__ghttp/ip                   client IP
+//	/__ghttp/tls                  TLS version, cipher suite, SNI, ALPN and client certificates
+//	/__ghttp/status/{code}        the given status
+//	/__ghttp/stream-bytes/{count} count random bytes, chunked, in ?chunk_size= pieces
+//	/__ghttp/delay/{duration}     the echo after a delay such as 2s or 1.5
+//	/__ghttp/cookies              request cookies
+//	/__ghttp/cookies/set?k=v      sets cookies and redirects to /__ghttp/cookies
+//	/__ghttp/cookies/delete?k     expires cookies and redirects to /__ghttp/cookies
+//	/__ghttp/redirect/{count}     redirects count times before landing on the echo
+func registerInspectionRoutes(routes *http.ServeMux) {
+	routes.HandleFunc(inspectEchoPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, describeRequest(request))
+	})
+	routes.HandleFunc(inspectHeadersPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, map[string]http.Header{"headers": requestHeaders(request)})
+	})
+	routes.HandleFunc(inspectIPPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, map[string]string{"client_ip": clientIP(request)})
+	})
+	routes.HandleFunc(inspectTLSPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		writeJSON(responseWriter, http.StatusOK, map[string]*inspectionTLS{"tls": describeTLS(request.TLS)})
+	})
+	routes.HandleFunc(inspectStatusPath, serveInspectionStatus)
+	routes.HandleFunc(inspectStreamBytesPath, serveInspectionStreamBytes)
+	routes.HandleFunc(inspectDelayPath, serveInspectionDelay)
+	routes.HandleFunc(inspectCookiesPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		cookies := map[string]string{}
+		for _, cookie := range request.Cookies() {
+			cookies[cookie.Name] = cookie.Value
+		}
+		writeJSON(responseWriter, http.StatusOK, map[string]map[string]string{"cookies": cookies})
+	})
+	routes.HandleFunc(inspectSetCookiesPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		for name, values := range request.URL.Query() {
+			http.SetCookie(responseWriter, &http.Cookie{Name: name, Value: values[0], Path: "/"})
+		}
+		http.Redirect(responseWriter, request, inspectCookiesPath, http.StatusFound)
+	})
+	routes.HandleFunc(inspectClearCookiesPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+		for name := range request.URL.Query() {
+			http.SetCookie(responseWriter, &http.Cookie{Name: name, Path: "/", MaxAge: -1})
+		}
+		http.Redirect(responseWriter, request, inspectCookiesPath, http.StatusFound)
+	})
+	routes.HandleFunc(inspectRedirectPath, serveInspectionRedirect)
+}
+
+func describeRequest(request *http.Request) inspectionEcho {
+	echo := inspectionEcho{
+		Method:   request.Method,
+		URL:      request.URL.RequestURI(),
+		Protocol: request.Proto,
+		Host:     request.Host,
+		ClientIP: clientIP(request),
+		Peer:     peerAddressFromRequest(request),
+		Headers:  requestHeaders(request),
+		TLS:      describeTLS(request.TLS),
+	}
+	if request.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(request.Body, inspectMaxEchoBody))
+		echo.Body = string(body)
+	}
+	return echo
+}
+
+// requestHeaders returns the request headers with Host added, since
+// net/http moves it out of the header map.
+func requestHeaders(request *http.Request) http.Header {
+	headers := request.Header.Clone()
+	headers.Set("Host", request.Host)
+	return headers
+}
+
+func clientIP(request *http.Request) string {
+	host, _, splitErr := net.SplitHostPort(request.RemoteAddr)
+	if splitErr != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+func describeTLS(state *tls.ConnectionState) *inspectionTLS {
+	if state == nil {
+		return nil
+	}
+	description := &inspectionTLS{
+		Version:            tls.VersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		Resumed:            state.DidResume,
+	}
+	for _, certificate := range state.PeerCertificates {
+		description.ClientCertificates = append(description.ClientCertificates, inspectionCertificate{
+			Subject:  certificate.Subject.String(),
+			Issuer:   certificate.Issuer.String(),
+			NotAfter: certificate.NotAfter,
+		})
+	}
+	return description
+}
+
+func serveInspectionStatus(responseWriter http.ResponseWriter, request *http.Request) {
+	statusCode, parseErr := strconv.Atoi(request.PathValue("code"))
+	if parseErr != nil || statusCode < 200 || statusCode > 599 {
+		http.Error(responseWriter, "status must be a number between 200 and 599", http.StatusBadRequest)
+		return
+	}
+	if statusCode >= 300 && statusCode < 400 && statusCode != http.StatusNotModified {
+		responseWriter.Header().Set(locationHeaderName, inspectEchoPath)
+	}
+	responseWriter.WriteHeader(statusCode)
+}
+
+func serveInspectionStreamBytes(responseWriter http.ResponseWriter, request *http.Request) {
+	byteCount, parseErr := strconv.Atoi(request.PathValue("count"))
+	if parseErr != nil || byteCount < 0 || byteCount > inspectMaxStreamBytes {
+		http.Error(responseWriter, "count must be a number between 0 and "+strconv.Itoa(inspectMaxStreamBytes), http.StatusBadRequest)
+		return
+	}
+	chunkSize := inspectDefaultChunkSize
+	if rawChunkSize := request.URL.Query().Get(inspectChunkSizeQuery); rawChunkSize != "" {
+		parsedChunkSize, chunkErr := strconv.Atoi(rawChunkSize)
+		if chunkErr != nil || parsedChunkSize <= 0 {
+			http.Error(responseWriter, "chunk_size must be a positive number", http.StatusBadRequest)
+			return
+		}
+		chunkSize = parsedChunkSize
+	}
+	responseWriter.Header().Set(contentTypeHeaderName, octetStreamContentType)
+	responseWriter.WriteHeader(http.StatusOK)
+	responseController := http.NewResponseController(responseWriter)
+	chunk := make([]byte, min(chunkSize, byteCount))
+	for remaining := byteCount; remaining > 0; remaining -= len(chunk) {
+		chunk = chunk[:min(len(chunk), remaining)]
+		for index := range chunk {
+			chunk[index] = byte(rand.UintN(256))
+		}
+		if _, writeErr := responseWriter.Write(chunk); writeErr != nil {
+			return
+		}
+		_ = responseController.Flush()
+	}
+}
+
+func serveInspectionDelay(responseWriter http.ResponseWriter, request *http.Request) {
+	rawDuration := request.PathValue("duration")
+	delay, parseErr := time.ParseDuration(rawDuration)
+	if parseErr != nil {
+		seconds, secondsErr := strconv.ParseFloat(rawDuration, 64)
+		if secondsErr != nil {
+			delay = -1
+		} else {
+			delay = time.Duration(seconds * float64(time.Second))
+		}
+	}
+	if delay < 0 {
+		http.Error(responseWriter, "duration must be a number of seconds or a duration such as 500ms", http.StatusBadRequest)
+		return
+	}
+	if sleepContext(request.Context(), delay) != nil {
+		return
+	}
+	writeJSON(responseWriter, http.StatusOK, describeRequest(request))
+}
+
+func serveInspectionRedirect(responseWriter http.ResponseWriter, request *http.Request) {
+	redirectCount, parseErr := strconv.Atoi(request.PathValue("count"))
+	if parseErr != nil || redirectCount < 1 || redirectCount > inspectMaxRedirects {
+		http.Error(responseWriter, "count must be a number between 1 and "+strconv.Itoa(inspectMaxRedirects), http.StatusBadRequest)
+		return
+	}
+	target := inspectEchoPath
+	if redirectCount > 1 {
+		target = inspectRedirectPrefix + strconv.Itoa(redirectCount-1)
+	}
+	if request.URL.RawQuery != "" {
+		target += "?" + request.URL.RawQuery
+	}
+	http.Redirect(responseWriter, request, target, http.StatusFound)
+}
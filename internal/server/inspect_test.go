@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newInspectionTestHandler() http.Handler {
+	internalRoutes := http.NewServeMux()
+	registerInspectionRoutes(internalRoutes)
+	return newInternalRoutesHandler(http.NotFoundHandler(), internalRoutes)
+}
+
+func TestInspectionEchoDescribesTLSRequests(t *testing.T) {
+	testServer := httptest.NewUnstartedServer(newInspectionTestHandler())
+	testServer.EnableHTTP2 = true
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	request, _ := http.NewRequest(http.MethodPost, testServer.URL+"/__ghttp/echo?debug=1", strings.NewReader("payload"))
+	request.Header.Set("X-Probe", "one")
+	response, requestErr := testServer.Client().Do(request)
+	if requestErr != nil {
+		t.Fatalf("request: %v", requestErr)
+	}
+	defer response.Body.Close()
+
+	var echo inspectionEcho
+	if decodeErr := json.NewDecoder(response.Body).Decode(&echo); decodeErr != nil {
+		t.Fatalf("decode echo: %v", decodeErr)
+	}
+	if echo.Method != http.MethodPost || echo.URL != "/__ghttp/echo?debug=1" || echo.Body != "payload" || echo.ClientIP != "127.0.0.1" {
+		t.Fatalf("unexpected echo %+v", echo)
+	}
+	if echo.Protocol != "HTTP/2.0" || len(echo.Headers["X-Probe"]) != 1 {
+		t.Fatalf("unexpected protocol or headers %+v", echo)
+	}
+	if echo.TLS == nil || echo.TLS.NegotiatedProtocol != "h2" || !strings.HasPrefix(echo.TLS.Version, "TLS") || echo.TLS.CipherSuite == "" {
+		t.Fatalf("unexpected tls state %+v", echo.TLS)
+	}
+}
+
+func TestInspectionRoutesProduceResponses(t *testing.T) {
+	handler := newInspectionTestHandler()
+	testCases := []struct {
+		name             string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+		expectedLength   int
+	}{
+		{name: "status", target: "/__ghttp/status/418", expectedStatus: http.StatusTeapot},
+		{name: "invalid status", target: "/__ghttp/status/42", expectedStatus: http.StatusBadRequest},
+		{name: "stream bytes", target: "/__ghttp/stream-bytes/2500?chunk_size=1000", expectedStatus: http.StatusOK, expectedLength: 2500},
+		{name: "too many bytes", target: "/__ghttp/stream-bytes/999999999999", expectedStatus: http.StatusBadRequest},
+		{name: "delay", target: "/__ghttp/delay/10ms", expectedStatus: http.StatusOK, expectedBody: `"method": "GET"`},
+		{name: "delay in seconds", target: "/__ghttp/delay/0.01", expectedStatus: http.StatusOK},
+		{name: "invalid delay", target: "/__ghttp/delay/soon", expectedStatus: http.StatusBadRequest},
+		{name: "redirect chain", target: "/__ghttp/redirect/3?x=1", expectedStatus: http.StatusFound, expectedLocation: "/__ghttp/redirect/2?x=1"},
+		{name: "last redirect", target: "/__ghttp/redirect/1", expectedStatus: http.StatusFound, expectedLocation: "/__ghttp/echo"},
+		{name: "set cookies", target: "/__ghttp/cookies/set?flavor=oat", expectedStatus: http.StatusFound, expectedLocation: "/__ghttp/cookies"},
+		{name: "ip", target: "/__ghttp/ip", expectedStatus: http.StatusOK, expectedBody: `"client_ip": "192.0.2.1"`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testCase.target, nil))
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if location := recorder.Header().Get("Location"); location != testCase.expectedLocation {
+				t.Fatalf("expected location %q, got %q", testCase.expectedLocation, location)
+			}
+			if !strings.Contains(recorder.Body.String(), testCase.expectedBody) {
+				t.Fatalf("expected body containing %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+			if testCase.expectedLength > 0 && recorder.Body.Len() != testCase.expectedLength {
+				t.Fatalf("expected %d bytes, got %d", testCase.expectedLength, recorder.Body.Len())
+			}
+		})
+	}
+}
+
+func TestInspectionCookiesRoundTrip(t *testing.T) {
+	handler := newInspectionTestHandler()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/__ghttp/cookies/set?flavor=oat", nil))
+	setCookies := recorder.Result().Cookies()
+	if len(setCookies) != 1 || setCookies[0].Name != "flavor" || setCookies[0].Value != "oat" {
+		t.Fatalf("unexpected cookies %v", setCookies)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/__ghttp/cookies", nil)
+	request.AddCookie(setCookies[0])
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	body, _ := io.ReadAll(recorder.Body)
+	if !strings.Contains(string(body), `"flavor": "oat"`) {
+		t.Fatalf("expected the cookie in the listing, got %q", body)
+	}
+}
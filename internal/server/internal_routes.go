@@ -0,0 +1,23 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// internalRoutePrefix reserves a path namespace for endpoints served by ghttp
+// itself rather than from the served directory.
+const internalRoutePrefix = "/__ghttp/"
+
+// newInternalRoutesHandler dispatches requests below internalRoutePrefix to
+// the internal routes and everything else to next. Unknown internal paths
+// answer 404 instead of falling through to the file system.
+func newInternalRoutesHandler(next http.Handler, routes *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if strings.HasPrefix(request.URL.Path, internalRoutePrefix) {
+			routes.ServeHTTP(responseWriter, request)
+			return
+		}
+		next.ServeHTTP(responseWriter, request)
+	})
+}
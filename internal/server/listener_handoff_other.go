@@ -0,0 +1,20 @@
+//go:build !unix
+
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// notifyListenerHandoff never delivers on platforms without SIGUSR2.
+func notifyListenerHandoff() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
+
+func startHandoffProcess(endpoints []servingEndpoint, listeners []net.Listener) (int, error) {
+	return 0, errors.New("listener handoff is not supported on this platform")
+}
+
+func retainUnixSockets(listeners []net.Listener) {}
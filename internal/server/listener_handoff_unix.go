@@ -0,0 +1,135 @@
+//go:build unix
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/temirov/ghttp/internal/socketactivation"
+)
+
+const handoffReadyTimeout = 30 * time.Second
+
+// fileListener is implemented by listeners backed by an operating system
+// descriptor, such as *net.TCPListener and *net.UnixListener.
+type fileListener interface {
+	File() (*os.File, error)
+}
+
+// notifyListenerHandoff delivers SIGUSR2, which asks the server to re-execute
+// its binary and hand the listening sockets to the new process.
+func notifyListenerHandoff() (<-chan os.Signal, func()) {
+	handoffRequests := make(chan os.Signal, 1)
+	signal.Notify(handoffRequests, syscall.SIGUSR2)
+	return handoffRequests, func() {
+		signal.Stop(handoffRequests)
+	}
+}
+
+// startHandoffProcess re-executes the running binary with the same arguments,
+// passing every listener as an inherited descriptor, and waits until the new
+// process reports that it is serving them. The new process outlives this one.
+func startHandoffProcess(endpoints []servingEndpoint, listeners []net.Listener) (int, error) {
+	executablePath, executableErr := os.Executable()
+	if executableErr != nil {
+		return 0, fmt.Errorf("locate executable: %w", executableErr)
+	}
+	listenerFiles := make([]*os.File, 0, len(listeners)+1)
+	defer func() {
+		for _, listenerFile := range listenerFiles {
+			_ = listenerFile.Close()
+		}
+	}()
+	names := make([]string, 0, len(listeners))
+	for index, listener := range listeners {
+		describedListener, supported := listener.(fileListener)
+		if !supported {
+			return 0, fmt.Errorf("listener %s cannot be handed off", listener.Addr())
+		}
+		listenerFile, fileErr := describedListener.File()
+		if fileErr != nil {
+			return 0, fmt.Errorf("duplicate listener %s: %w", listener.Addr(), fileErr)
+		}
+		listenerFiles = append(listenerFiles, listenerFile)
+		names = append(names, string(handoffListenerRole(endpoints[index])))
+	}
+	readyReader, readyWriter, pipeErr := os.Pipe()
+	if pipeErr != nil {
+		return 0, fmt.Errorf("create readiness pipe: %w", pipeErr)
+	}
+	defer readyReader.Close()
+	listenerFiles = append(listenerFiles, readyWriter)
+
+	command := exec.Command(executablePath, os.Args[1:]...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.ExtraFiles = listenerFiles
+	command.Env = append(handoffParentEnvironment(), socketactivation.HandoffEnvironment(names)...)
+	if startErr := command.Start(); startErr != nil {
+		return 0, fmt.Errorf("start %s: %w", executablePath, startErr)
+	}
+	childProcessID := command.Process.Pid
+	_ = readyWriter.Close()
+	if readyErr := awaitHandoffReady(readyReader); readyErr != nil {
+		_ = command.Process.Kill()
+		_ = command.Wait()
+		return 0, readyErr
+	}
+	_ = command.Process.Release()
+	return childProcessID, nil
+}
+
+// awaitHandoffReady blocks until the new process writes to the readiness pipe.
+// End of file means the process exited before it started serving.
+func awaitHandoffReady(readyReader *os.File) error {
+	_ = readyReader.SetReadDeadline(time.Now().Add(handoffReadyTimeout))
+	readyBuffer := make([]byte, 1)
+	_, readErr := readyReader.Read(readyBuffer)
+	switch {
+	case readErr == nil:
+		return nil
+	case errors.Is(readErr, io.EOF):
+		return errors.New("new process exited before serving")
+	case errors.Is(readErr, os.ErrDeadlineExceeded):
+		return fmt.Errorf("new process not ready after %s", handoffReadyTimeout)
+	default:
+		return fmt.Errorf("await new process: %w", readErr)
+	}
+}
+
+// handoffParentEnvironment drops variables describing descriptors this
+// process inherited so that the new process only sees its own handoff.
+func handoffParentEnvironment() []string {
+	environment := os.Environ()
+	filtered := make([]string, 0, len(environment))
+	for _, assignment := range environment {
+		key, _, _ := strings.Cut(assignment, "=")
+		switch key {
+		case socketactivation.EnvironmentHandoffFDs, socketactivation.EnvironmentHandoffFDNames, socketactivation.EnvironmentHandoffReadyFD,
+			socketactivation.EnvironmentListenPID, socketactivation.EnvironmentListenFDs, socketactivation.EnvironmentListenFDNames:
+			continue
+		}
+		filtered = append(filtered, assignment)
+	}
+	return filtered
+}
+
+// retainUnixSockets stops closing listeners from unlinking socket files that
+// the new process now serves.
+func retainUnixSockets(listeners []net.Listener) {
+	for _, listener := range listeners {
+		if unixListener, isUnix := listener.(*net.UnixListener); isUnix {
+			unixListener.SetUnlinkOnClose(false)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+//go:build unix
+
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAwaitHandoffReadyReportsReadinessAndEarlyExit(t *testing.T) {
+	readyReader, readyWriter, pipeErr := os.Pipe()
+	if pipeErr != nil {
+		t.Fatalf("pipe: %v", pipeErr)
+	}
+	if _, writeErr := readyWriter.Write([]byte{1}); writeErr != nil {
+		t.Fatalf("write: %v", writeErr)
+	}
+	_ = readyWriter.Close()
+	if readyErr := awaitHandoffReady(readyReader); readyErr != nil {
+		t.Fatalf("expected readiness, got %v", readyErr)
+	}
+	_ = readyReader.Close()
+
+	exitedReader, exitedWriter, pipeErr := os.Pipe()
+	if pipeErr != nil {
+		t.Fatalf("pipe: %v", pipeErr)
+	}
+	_ = exitedWriter.Close()
+	defer exitedReader.Close()
+	if readyErr := awaitHandoffReady(exitedReader); readyErr == nil {
+		t.Fatalf("expected an error when the new process exits before serving")
+	}
+}
+
+func TestRetainUnixSocketsKeepsSocketFileAfterClose(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ghttp.sock")
+	listener, listenErr := net.Listen(networkUnix, socketPath)
+	if listenErr != nil {
+		t.Fatalf("listen: %v", listenErr)
+	}
+	retainUnixSockets([]net.Listener{listener})
+	_ = listener.Close()
+	if _, statErr := os.Lstat(socketPath); statErr != nil {
+		t.Fatalf("expected socket file to remain for the new process: %v", statErr)
+	}
+}
+
+func TestHandoffListenerRoleMatchesEndpoint(t *testing.T) {
+	if role := handoffListenerRole(servingEndpoint{secure: true}); role != ListenerRoleHTTPS {
+		t.Fatalf("expected https role, got %s", role)
+	}
+	if role := handoffListenerRole(servingEndpoint{redirectToHTTPS: true}); role != ListenerRoleHTTP {
+		t.Fatalf("expected http role, got %s", role)
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	networkTCP             = "tcp"
+	networkTCPVersion4     = "tcp4"
+	networkTCPVersion6     = "tcp6"
+	networkUnix            = "unix"
+	staleSocketDialTimeout = 250 * time.Millisecond
+	portKernelAssigned     = "0"
+	portFallbackAttempts   = 100
+	maximumPortNumber      = 65535
+)
+
+// addressInUseError reports the address that could not be bound because
+// another process already listens on it.
+type addressInUseError struct {
+	bindAddress string
+	port        string
+	err         error
+}
+
+func (inUseErr *addressInUseError) Error() string {
+	return fmt.Sprintf("address in use %s: %v", net.JoinHostPort(inUseErr.bindAddress, inUseErr.port), inUseErr.err)
+}
+
+func (inUseErr *addressInUseError) Unwrap() error {
+	return inUseErr.err
+}
+
+// openEndpointListeners binds every endpoint, returning listeners in endpoint
+// order. TCP endpoints sharing a configured port are bound as a group so that
+// a kernel-assigned or fallback port is identical across bind addresses.
+func openEndpointListeners(endpoints []servingEndpoint, configuration FileServerConfiguration) ([]net.Listener, error) {
+	listeners := make([]net.Listener, len(endpoints))
+	closeListeners := func() {
+		for _, listener := range listeners {
+			if listener != nil {
+				_ = listener.Close()
+			}
+		}
+	}
+	portGroups := map[string][]int{}
+	var portOrder []string
+	for index, endpoint := range endpoints {
+		if endpoint.listener != nil || endpoint.unixSocketPath != "" {
+			listener, listenErr := openEndpointListener(endpoint, configuration.UnixSocketPermissions)
+			if listenErr != nil {
+				closeListeners()
+				return nil, listenErr
+			}
+			listeners[index] = listener
+			continue
+		}
+		if _, exists := portGroups[endpoint.port]; !exists {
+			portOrder = append(portOrder, endpoint.port)
+		}
+		portGroups[endpoint.port] = append(portGroups[endpoint.port], index)
+	}
+	for _, port := range portOrder {
+		if groupErr := openPortGroup(endpoints, listeners, portGroups[port], port, configuration.PortFallback); groupErr != nil {
+			closeListeners()
+			return nil, groupErr
+		}
+	}
+	return listeners, nil
+}
+
+func openPortGroup(endpoints []servingEndpoint, listeners []net.Listener, indices []int, requestedPort string, portFallback bool) error {
+	attempts := 1
+	if portFallback && requestedPort != portKernelAssigned {
+		attempts = portFallbackAttempts
+	}
+	candidatePort := requestedPort
+	for attempt := 1; ; attempt++ {
+		boundPort := candidatePort
+		inUseErr := bindPortGroup(endpoints, listeners, indices, &boundPort)
+		if inUseErr == nil {
+			for _, index := range indices {
+				endpoints[index].requestedPort = requestedPort
+				endpoints[index].port = boundPort
+			}
+			return nil
+		}
+		var addressErr *addressInUseError
+		if attempt >= attempts || !errors.As(inUseErr, &addressErr) {
+			return inUseErr
+		}
+		nextPort, nextErr := nextPortCandidate(candidatePort)
+		if nextErr != nil {
+			return inUseErr
+		}
+		candidatePort = nextPort
+	}
+}
+
+// bindPortGroup listens on the port for every endpoint in the group. A
+// kernel-assigned port is resolved by the first listener and reused by the
+// rest. On failure the listeners opened so far are closed.
+func bindPortGroup(endpoints []servingEndpoint, listeners []net.Listener, indices []int, port *string) error {
+	for position, index := range indices {
+		endpoint := endpoints[index]
+		endpoint.port = *port
+		listener, listenErr := openEndpointListener(endpoint, 0)
+		if listenErr != nil {
+			for _, openedIndex := range indices[:position] {
+				_ = listeners[openedIndex].Close()
+				listeners[openedIndex] = nil
+			}
+			if isAddressInUse(listenErr) {
+				return &addressInUseError{bindAddress: endpoint.bindAddress, port: *port, err: listenErr}
+			}
+			return listenErr
+		}
+		listeners[index] = listener
+		if *port == portKernelAssigned {
+			if _, assignedPort, splitErr := net.SplitHostPort(listener.Addr().String()); splitErr == nil {
+				*port = assignedPort
+			}
+		}
+	}
+	return nil
+}
+
+func nextPortCandidate(port string) (string, error) {
+	portNumber, parseErr := strconv.Atoi(port)
+	if parseErr != nil {
+		return "", parseErr
+	}
+	if portNumber >= maximumPortNumber {
+		return "", fmt.Errorf("no ports available above %d", portNumber)
+	}
+	return strconv.Itoa(portNumber + 1), nil
+}
+
+// openEndpointListener binds the listener described by the endpoint.
+func openEndpointListener(endpoint servingEndpoint, unixSocketPermissions os.FileMode) (net.Listener, error) {
+	if endpoint.listener != nil {
+		return endpoint.listener, nil
+	}
+	if endpoint.unixSocketPath == "" {
+		return net.Listen(tcpNetworkForBindAddress(endpoint.bindAddress), net.JoinHostPort(endpoint.bindAddress, endpoint.port))
+	}
+	if err := removeStaleUnixSocket(endpoint.unixSocketPath); err != nil {
+		return nil, err
+	}
+	listener, listenErr := net.Listen(networkUnix, endpoint.unixSocketPath)
+	if listenErr != nil {
+		return nil, listenErr
+	}
+	if unixSocketPermissions != 0 {
+		if chmodErr := os.Chmod(endpoint.unixSocketPath, unixSocketPermissions); chmodErr != nil {
+			_ = listener.Close()
+			return nil, fmt.Errorf("set unix socket permissions: %w", chmodErr)
+		}
+	}
+	return listener, nil
+}
+
+// tcpNetworkForBindAddress pins IP literals to their address family. Empty
+// addresses, hostnames, and the IPv6 wildcard "::" keep the default
+// dual-stack behaviour, so "--bind ::" still accepts IPv4 clients.
+func tcpNetworkForBindAddress(bindAddress string) string {
+	parsedAddress, parseErr := netip.ParseAddr(bindAddress)
+	if parseErr != nil || parsedAddress == netip.IPv6Unspecified() {
+		return networkTCP
+	}
+	if parsedAddress.Is4() || parsedAddress.Is4In6() {
+		return networkTCPVersion4
+	}
+	return networkTCPVersion6
+}
+
+// removeStaleUnixSocket deletes a socket file left behind by a previous run.
+// Regular files are never removed, and sockets that still accept connections
+// belong to a live server and cause an error instead.
+func removeStaleUnixSocket(socketPath string) error {
+	fileInfo, statErr := os.Lstat(socketPath)
+	if errors.Is(statErr, fs.ErrNotExist) {
+		return nil
+	}
+	if statErr != nil {
+		return fmt.Errorf("stat unix socket: %w", statErr)
+	}
+	if fileInfo.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("unix socket path is not a socket: %s", socketPath)
+	}
+	connection, dialErr := net.DialTimeout(networkUnix, socketPath, staleSocketDialTimeout)
+	if dialErr == nil {
+		_ = connection.Close()
+		return fmt.Errorf("unix socket is in use: %s", socketPath)
+	}
+	if removeErr := os.Remove(socketPath); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+		return fmt.Errorf("remove stale unix socket: %w", removeErr)
+	}
+	return nil
+}
+
+func removeUnixSockets(endpoints []servingEndpoint) {
+	for _, endpoint := range endpoints {
+		if endpoint.unixSocketPath == "" || endpoint.listener != nil {
+			continue
+		}
+		_ = os.Remove(endpoint.unixSocketPath)
+	}
+}
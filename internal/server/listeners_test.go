@@ -0,0 +1,25 @@
+package server
+
+import "testing"
+
+func TestTCPNetworkForBindAddress(t *testing.T) {
+	testCases := []struct {
+		bindAddress     string
+		expectedNetwork string
+	}{
+		{bindAddress: "", expectedNetwork: "tcp"},
+		{bindAddress: "localhost", expectedNetwork: "tcp"},
+		{bindAddress: "::", expectedNetwork: "tcp"},
+		{bindAddress: "0.0.0.0", expectedNetwork: "tcp4"},
+		{bindAddress: "192.168.1.5", expectedNetwork: "tcp4"},
+		{bindAddress: "::ffff:192.168.1.5", expectedNetwork: "tcp4"},
+		{bindAddress: "::1", expectedNetwork: "tcp6"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.bindAddress, func(t *testing.T) {
+			if network := tcpNetworkForBindAddress(testCase.bindAddress); network != testCase.expectedNetwork {
+				t.Fatalf("expected %s, got %s", testCase.expectedNetwork, network)
+			}
+		})
+	}
+}
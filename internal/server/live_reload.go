@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	liveReloadEventsPath         = internalRoutePrefix + "events"
+	liveReloadScriptPath         = internalRoutePrefix + "live-reload.js"
+	liveReloadEventReload        = "reload"
+	liveReloadEventStylesheets   = "css"
+	liveReloadStylesheetSuffix   = ".css"
+	liveReloadKeepAliveInterval  = 30 * time.Second
+	liveReloadRetryMilliseconds  = 1000
+	liveReloadSubscriberCapacity = 4
+	eventStreamContentType       = "text/event-stream"
+	javaScriptContentType        = "text/javascript; charset=utf-8"
+	cacheControlHeaderName       = "Cache-Control"
+	cacheControlNoCache          = "no-cache"
+	logMessageLiveReloadError    = "live reload watch failed"
+	logMessageLiveReloadChange   = "live reload"
+	logFieldChangedPaths         = "changed"
+)
+
+// liveReloadSnippet is injected into HTML documents served in live reload mode.
+const liveReloadSnippet = `<script src="` + liveReloadScriptPath + `"></script>`
+
+// liveReloadScript reloads the page on "reload" events. On "css" events it
+// swaps the matching same-origin stylesheets, or every same-origin stylesheet
+// when none match, for example because the change reached the page through an
+// @import, so that styles update without losing page state.
+const liveReloadScript = `(function () {
+  if (!window.EventSource) {
+    return;
+  }
+  var source = new EventSource("` + liveReloadEventsPath + `");
+  source.addEventListener("` + liveReloadEventReload + `", function () {
+    window.location.reload();
+  });
+  source.addEventListener("` + liveReloadEventStylesheets + `", function (event) {
+    var changedPaths = JSON.parse(event.data);
+    var sameOrigin = [];
+    var matching = [];
+    document.querySelectorAll('link[rel~="stylesheet"][href]').forEach(function (link) {
+      var stylesheetURL = new URL(link.href, window.location.href);
+      if (stylesheetURL.origin !== window.location.origin) {
+        return;
+      }
+      sameOrigin.push(link);
+      if (changedPaths.indexOf(stylesheetURL.pathname) !== -1) {
+        matching.push(link);
+      }
+    });
+    (matching.length > 0 ? matching : sameOrigin).forEach(function (link) {
+      var reloadedURL = new URL(link.href, window.location.href);
+      reloadedURL.searchParams.set("ghttp-reload", String(Date.now()));
+      var replacement = link.cloneNode(false);
+      replacement.href = reloadedURL.toString();
+      replacement.addEventListener("load", function () {
+        link.remove();
+      });
+      link.after(replacement);
+    });
+  });
+})();
+`
+
+// liveReloadEvent is a single Server-Sent Event.
+type liveReloadEvent struct {
+	name string
+	data string
+}
+
+// liveReloadBroker fans change events out to every connected browser.
+type liveReloadBroker struct {
+	mutex       sync.Mutex
+	subscribers map[chan liveReloadEvent]struct{}
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+func newLiveReloadBroker() *liveReloadBroker {
+	return &liveReloadBroker{subscribers: map[chan liveReloadEvent]struct{}{}, closed: make(chan struct{})}
+}
+
+func (broker *liveReloadBroker) subscribe() chan liveReloadEvent {
+	subscription := make(chan liveReloadEvent, liveReloadSubscriberCapacity)
+	broker.mutex.Lock()
+	broker.subscribers[subscription] = struct{}{}
+	broker.mutex.Unlock()
+	return subscription
+}
+
+func (broker *liveReloadBroker) unsubscribe(subscription chan liveReloadEvent) {
+	broker.mutex.Lock()
+	delete(broker.subscribers, subscription)
+	broker.mutex.Unlock()
+}
+
+// publish delivers the event without blocking. A browser that has fallen
+// behind already has a reload queued, so dropping further events is harmless.
+func (broker *liveReloadBroker) publish(event liveReloadEvent) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	for subscription := range broker.subscribers {
+		select {
+		case subscription <- event:
+		default:
+		}
+	}
+}
+
+// close ends every event stream so that graceful shutdown does not wait for
+// browsers to disconnect. Browsers reconnect to whichever process serves next.
+func (broker *liveReloadBroker) close() {
+	broker.closeOnce.Do(func() {
+		close(broker.closed)
+	})
+}
+
+// publishChanges classifies a batch of changed paths. A batch made only of
+// stylesheets produces a "css" event and anything else a "reload" event; both
+// carry the changed URL paths as a JSON array.
+func (broker *liveReloadBroker) publishChanges(changedPaths []string) {
+	eventName := liveReloadEventStylesheets
+	urlPaths := make([]string, 0, len(changedPaths))
+	for _, changedPath := range changedPaths {
+		if !strings.EqualFold(path.Ext(changedPath), liveReloadStylesheetSuffix) {
+			eventName = liveReloadEventReload
+		}
+		changedURL := url.URL{Path: "/" + changedPath}
+		urlPaths = append(urlPaths, changedURL.EscapedPath())
+	}
+	encodedPaths, _ := json.Marshal(urlPaths)
+	broker.publish(liveReloadEvent{name: eventName, data: string(encodedPaths)})
+}
+
+// publishReload asks every browser to reload, for example after a build
+// whose output may differ from the files that triggered it.
+func (broker *liveReloadBroker) publishReload() {
+	broker.publish(liveReloadEvent{name: liveReloadEventReload, data: "[]"})
+}
+
+// ServeHTTP streams events to one browser until it disconnects or the broker
+// closes. Write deadlines are lifted for the stream, and a comment line is
+// sent periodically to keep intermediaries from timing the connection out.
+func (broker *liveReloadBroker) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	responseController := http.NewResponseController(responseWriter)
+	_ = responseController.SetWriteDeadline(time.Time{})
+	subscription := broker.subscribe()
+	defer broker.unsubscribe(subscription)
+
+	responseWriter.Header().Set(contentTypeHeaderName, eventStreamContentType)
+	responseWriter.Header().Set(cacheControlHeaderName, cacheControlNoCache)
+	responseWriter.WriteHeader(http.StatusOK)
+	if _, writeErr := fmt.Fprintf(responseWriter, "retry: %d\n\n", liveReloadRetryMilliseconds); writeErr != nil {
+		return
+	}
+	if flushErr := responseController.Flush(); flushErr != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(liveReloadKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		var writeErr error
+		select {
+		case <-request.Context().Done():
+			return
+		case <-broker.closed:
+			return
+		case event := <-subscription:
+			_, writeErr = fmt.Fprintf(responseWriter, "event: %s\ndata: %s\n\n", event.name, event.data)
+		case <-keepAlive.C:
+			_, writeErr = fmt.Fprint(responseWriter, ": keep-alive\n\n")
+		}
+		if writeErr != nil {
+			return
+		}
+		if flushErr := responseController.Flush(); flushErr != nil {
+			return
+		}
+	}
+}
+
+func serveLiveReloadScript(responseWriter http.ResponseWriter, request *http.Request) {
+	responseWriter.Header().Set(contentTypeHeaderName, javaScriptContentType)
+	responseWriter.Header().Set(cacheControlHeaderName, cacheControlNoCache)
+	_, _ = responseWriter.Write([]byte(liveReloadScript))
+}
+
+// registerLiveReloadRoutes mounts the event stream and client script.
+func registerLiveReloadRoutes(routes *http.ServeMux, broker *liveReloadBroker) {
+	routes.Handle("GET "+liveReloadEventsPath, broker)
+	routes.HandleFunc("GET "+liveReloadScriptPath, serveLiveReloadScript)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveReloadBrokerClassifiesChanges(t *testing.T) {
+	testCases := []struct {
+		name          string
+		changedPaths  []string
+		expectedEvent liveReloadEvent
+	}{
+		{
+			name:          "stylesheets only",
+			changedPaths:  []string{"css/site.css", "theme dark.CSS"},
+			expectedEvent: liveReloadEvent{name: liveReloadEventStylesheets, data: `["/css/site.css","/theme%20dark.CSS"]`},
+		},
+		{
+			name:          "mixed change reloads",
+			changedPaths:  []string{"css/site.css", "index.html"},
+			expectedEvent: liveReloadEvent{name: liveReloadEventReload, data: `["/css/site.css","/index.html"]`},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			broker := newLiveReloadBroker()
+			subscription := broker.subscribe()
+			defer broker.unsubscribe(subscription)
+
+			broker.publishChanges(testCase.changedPaths)
+
+			select {
+			case event := <-subscription:
+				if event != testCase.expectedEvent {
+					t.Fatalf("expected %+v, got %+v", testCase.expectedEvent, event)
+				}
+			default:
+				t.Fatalf("expected an event to be published")
+			}
+		})
+	}
+}
+
+func TestLiveReloadEventsStreamUntilBrokerCloses(t *testing.T) {
+	broker := newLiveReloadBroker()
+	routes := http.NewServeMux()
+	registerLiveReloadRoutes(routes, broker)
+	testServer := httptest.NewServer(newInternalRoutesHandler(http.NotFoundHandler(), routes))
+	defer testServer.Close()
+
+	requestContext, cancelRequest := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelRequest()
+	request, _ := http.NewRequestWithContext(requestContext, http.MethodGet, testServer.URL+liveReloadEventsPath, nil)
+	response, requestErr := http.DefaultClient.Do(request)
+	if requestErr != nil {
+		t.Fatalf("open event stream: %v", requestErr)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != eventStreamContentType {
+		t.Fatalf("expected event stream content type, got %q", contentType)
+	}
+
+	reader := bufio.NewReader(response.Body)
+	if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, "retry:") {
+		t.Fatalf("expected retry preamble, got %q", line)
+	}
+	_, _ = reader.ReadString('\n')
+	broker.publishChanges([]string{"index.html"})
+	eventLine, _ := reader.ReadString('\n')
+	dataLine, _ := reader.ReadString('\n')
+	if eventLine != "event: reload\n" || dataLine != "data: [\"/index.html\"]\n" {
+		t.Fatalf("unexpected event %q %q", eventLine, dataLine)
+	}
+
+	broker.close()
+	_, _ = reader.ReadString('\n')
+	if _, readErr := reader.ReadString('\n'); readErr == nil {
+		t.Fatalf("expected the stream to end after the broker closed")
+	}
+}
+
+func TestInternalRoutesRejectUnknownPaths(t *testing.T) {
+	routes := http.NewServeMux()
+	registerLiveReloadRoutes(routes, newLiveReloadBroker())
+	handler := newInternalRoutesHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusTeapot)
+	}), routes)
+
+	unknownRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(unknownRecorder, httptest.NewRequest(http.MethodGet, internalRoutePrefix+"missing", nil))
+	if unknownRecorder.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown internal path, got %d", unknownRecorder.Code)
+	}
+
+	scriptRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(scriptRecorder, httptest.NewRequest(http.MethodGet, liveReloadScriptPath, nil))
+	if scriptRecorder.Code != http.StatusOK || !strings.Contains(scriptRecorder.Body.String(), "EventSource") {
+		t.Fatalf("expected live reload script, got %d", scriptRecorder.Code)
+	}
+
+	fileRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(fileRecorder, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	if fileRecorder.Code != http.StatusTeapot {
+		t.Fatalf("expected other paths to reach the file handler, got %d", fileRecorder.Code)
+	}
+}
@@ -69,7 +69,7 @@ func (handler markdownHandler) serveDirectory(responseWriter http.ResponseWriter
 		return
 	}
 
-	if handler.directoryIndexExists(request.URL.Path) {
+	if directoryIndexExists(handler.fileSystem, request.URL.Path) {
 		handler.next.ServeHTTP(responseWriter, request)
 		return
 	}
@@ -152,11 +152,11 @@ func (handler markdownHandler) selectMarkdownCandidate(directoryPath string) (st
 	return "", nil, nil
 }
 
-func (handler markdownHandler) directoryIndexExists(directoryPath string) bool {
+func directoryIndexExists(fileSystem http.FileSystem, directoryPath string) bool {
 	for index := range directoryIndexCandidates {
 		candidateName := directoryIndexCandidates[index]
 		candidatePath := pathpkg.Join(directoryPath, candidateName)
-		fileHandle, openErr := handler.fileSystem.Open(candidatePath)
+		fileHandle, openErr := fileSystem.Open(candidatePath)
 		if openErr != nil {
 			continue
 		}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	pathpkg "path"
+	"strconv"
+	"time"
+
+	"github.com/temirov/ghttp/internal/mocks"
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+const (
+	mockHeaderName       = "X-Ghttp-Mock"
+	logMessageMockFailed = "mock failed"
+)
+
+type mockHandler struct {
+	next           http.Handler
+	directory      mocks.Directory
+	loggingService *logging.Service
+}
+
+// newMockHandler answers requests that resolve to a mock file in the mock
+// directory with the file's body, status, headers and delay, naming the file
+// in X-Ghttp-Mock. HEAD requests use the GET mock when there is no HEAD mock.
+// Requests without a mock reach next.
+func newMockHandler(next http.Handler, directory string, loggingService *logging.Service) http.Handler {
+	return mockHandler{next: next, directory: mocks.Directory{Root: directory}, loggingService: loggingService}
+}
+
+func (handler mockHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	response, found, resolveErr := handler.directory.Resolve(request.Method, request.URL.Path)
+	if !found && request.Method == http.MethodHead {
+		response, found, resolveErr = handler.directory.Resolve(http.MethodGet, request.URL.Path)
+	}
+	if !found {
+		handler.next.ServeHTTP(responseWriter, request)
+		return
+	}
+	if resolveErr != nil {
+		if handler.loggingService != nil {
+			handler.loggingService.Error(logMessageMockFailed, resolveErr, logging.String(logFieldPath, request.URL.Path))
+		}
+		http.Error(responseWriter, resolveErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	if response.Delay > 0 {
+		delayTimer := time.NewTimer(response.Delay)
+		select {
+		case <-delayTimer.C:
+		case <-request.Context().Done():
+			delayTimer.Stop()
+			return
+		}
+	}
+	for name, values := range response.Header {
+		responseWriter.Header()[name] = values
+	}
+	if responseWriter.Header().Get(contentTypeHeaderName) == "" {
+		contentType := mime.TypeByExtension(pathpkg.Ext(response.FilePath))
+		if contentType == "" {
+			contentType = http.DetectContentType(response.Body)
+		}
+		responseWriter.Header().Set(contentTypeHeaderName, contentType)
+	}
+	responseWriter.Header().Set(mockHeaderName, response.FilePath)
+	bodyAllowed := response.Status >= http.StatusOK && response.Status != http.StatusNoContent && response.Status != http.StatusNotModified
+	if bodyAllowed {
+		responseWriter.Header().Set(contentLengthHeaderName, strconv.Itoa(len(response.Body)))
+	}
+	responseWriter.WriteHeader(response.Status)
+	if bodyAllowed && request.Method != http.MethodHead {
+		_, _ = responseWriter.Write(response.Body)
+	}
+}
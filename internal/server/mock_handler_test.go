@@ -0,0 +1,63 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMockHandlerServesMocks(t *testing.T) {
+	mockDirectory := t.TempDir()
+	if mkdirErr := os.MkdirAll(filepath.Join(mockDirectory, "api", "users", "_id"), 0o755); mkdirErr != nil {
+		t.Fatalf("mkdir: %v", mkdirErr)
+	}
+	writeTestFile(t, filepath.Join(mockDirectory, "api", "users.GET.json"), `[{"id":1}]`)
+	writeTestFile(t, filepath.Join(mockDirectory, "api", "users", "POST.json"), "---\nstatus: 422\nheaders:\n  X-Reason: invalid\n---\n{\"error\":\"invalid\"}")
+	writeTestFile(t, filepath.Join(mockDirectory, "api", "users", "_id", "DELETE.txt"), "")
+	writeTestFile(t, filepath.Join(mockDirectory, "api", "users", "_id", "DELETE.meta.yaml"), "status: 204\n")
+	writeTestFile(t, filepath.Join(mockDirectory, "broken.GET.json"), "---\nstatus: [\n---\n")
+
+	handler := newMockHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		_, _ = io.WriteString(responseWriter, "static")
+	}), mockDirectory, nil)
+
+	testCases := []struct {
+		name                string
+		method              string
+		target              string
+		expectedStatus      int
+		expectedBody        string
+		expectedContentType string
+		expectedMock        string
+	}{
+		{name: "get mock", method: http.MethodGet, target: "/api/users", expectedStatus: http.StatusOK, expectedBody: `[{"id":1}]`, expectedContentType: "application/json", expectedMock: "api/users.GET.json"},
+		{name: "head uses get mock", method: http.MethodHead, target: "/api/users", expectedStatus: http.StatusOK, expectedContentType: "application/json", expectedMock: "api/users.GET.json"},
+		{name: "status and headers from front matter", method: http.MethodPost, target: "/api/users", expectedStatus: http.StatusUnprocessableEntity, expectedBody: `{"error":"invalid"}`, expectedContentType: "application/json", expectedMock: "api/users/POST.json"},
+		{name: "no content from sidecar", method: http.MethodDelete, target: "/api/users/5", expectedStatus: http.StatusNoContent, expectedContentType: "text/plain; charset=utf-8", expectedMock: "api/users/_id/DELETE.txt"},
+		{name: "unmocked method falls through", method: http.MethodPut, target: "/api/users", expectedStatus: http.StatusOK, expectedBody: "static"},
+		{name: "invalid meta", method: http.MethodGet, target: "/broken", expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(testCase.method, testCase.target, nil))
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if testCase.expectedBody != "" && recorder.Body.String() != testCase.expectedBody {
+				t.Fatalf("expected body %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+			if testCase.expectedContentType != "" && recorder.Header().Get("Content-Type") != testCase.expectedContentType {
+				t.Fatalf("expected content type %q, got %q", testCase.expectedContentType, recorder.Header().Get("Content-Type"))
+			}
+			if mockFile := recorder.Header().Get("X-Ghttp-Mock"); mockFile != testCase.expectedMock {
+				t.Fatalf("expected mock header %q, got %q", testCase.expectedMock, mockFile)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+const (
+	logMessageProxyError = "proxy error"
+	logFieldUpstream     = "upstream"
+)
+
+// ProxyMount forwards requests under PathPrefix to an upstream server instead
+// of serving them from disk.
+type ProxyMount struct {
+	// PathPrefix matches the request path and its descendants on segment
+	// boundaries, so /api matches /api and /api/users but not /apis.
+	PathPrefix string
+	// Upstream is the backend base URL. Its path is joined with the request path.
+	Upstream *url.URL
+	// StripPrefix removes PathPrefix from the path sent upstream.
+	StripPrefix bool
+	// PreserveHost sends the original Host header instead of the upstream host.
+	PreserveHost bool
+}
+
+type proxyMount struct {
+	configuration ProxyMount
+	proxy         *httputil.ReverseProxy
+}
+
+type proxyMountsHandler struct {
+	next   http.Handler
+	mounts []proxyMount
+}
+
+// newProxyMountsHandler sends requests under a mount to its upstream through
+// httputil.ReverseProxy, which also passes Connection: Upgrade requests such
+// as WebSockets through. The longest matching prefix wins and other requests
+// reach next.
+func newProxyMountsHandler(next http.Handler, configurations []ProxyMount, record *RecordConfiguration, loggingService *logging.Service) http.Handler {
+	mounts := make([]proxyMount, 0, len(configurations))
+	for _, configuration := range configurations {
+		configuration.PathPrefix = "/" + strings.Trim(configuration.PathPrefix, "/")
+		proxy := newMountProxy(configuration, loggingService)
+		if record != nil {
+			attachRecorder(proxy, *record, loggingService)
+		}
+		mounts = append(mounts, proxyMount{configuration: configuration, proxy: proxy})
+	}
+	sort.SliceStable(mounts, func(first int, second int) bool {
+		return len(mounts[first].configuration.PathPrefix) > len(mounts[second].configuration.PathPrefix)
+	})
+	return proxyMountsHandler{next: next, mounts: mounts}
+}
+
+func (handler proxyMountsHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	for _, mount := range handler.mounts {
+		if !pathHasPrefix(request.URL.Path, mount.configuration.PathPrefix) {
+			continue
+		}
+		recordUpstream(responseWriter, mount.configuration.Upstream.String())
+		mount.proxy.ServeHTTP(responseWriter, request)
+		return
+	}
+	handler.next.ServeHTTP(responseWriter, request)
+}
+
+func newMountProxy(configuration ProxyMount, loggingService *logging.Service) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(proxyRequest *httputil.ProxyRequest) {
+			if configuration.StripPrefix {
+				proxyRequest.Out.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(proxyRequest.In.URL.Path, configuration.PathPrefix), "/")
+				proxyRequest.Out.URL.RawPath = ""
+			}
+			proxyRequest.SetURL(configuration.Upstream)
+			proxyRequest.SetXForwarded()
+			if configuration.PreserveHost {
+				proxyRequest.Out.Host = proxyRequest.In.Host
+			}
+		},
+		ErrorHandler: func(responseWriter http.ResponseWriter, request *http.Request, proxyErr error) {
+			if loggingService != nil {
+				loggingService.Error(logMessageProxyError, proxyErr,
+					logging.String(logFieldPath, request.URL.Path),
+					logging.String(logFieldUpstream, configuration.Upstream.String()),
+				)
+			}
+			responseWriter.WriteHeader(http.StatusBadGateway)
+		},
+	}
+}
+
+// pathHasPrefix reports whether requestPath is prefix or lies beneath it.
+func pathHasPrefix(requestPath string, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return requestPath == prefix || strings.HasPrefix(requestPath, prefix+"/")
+}
+
+// recordUpstream notes the upstream on the request log recorder found by
+// unwrapping the response writer.
+func recordUpstream(responseWriter http.ResponseWriter, upstream string) {
+	for responseWriter != nil {
+		if recorder, isRecorder := responseWriter.(*statusRecorder); isRecorder {
+			recorder.upstream = upstream
+			return
+		}
+		unwrapper, canUnwrap := responseWriter.(interface{ Unwrap() http.ResponseWriter })
+		if !canUnwrap {
+			return
+		}
+		responseWriter = unwrapper.Unwrap()
+	}
+}
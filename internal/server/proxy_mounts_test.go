@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProxyMountsHandlerForwardsMatchingRequests(t *testing.T) {
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		fmt.Fprintf(responseWriter, "%s %s", request.Host, request.URL.RequestURI())
+	}))
+	defer upstreamServer.Close()
+	upstreamURL, parseErr := url.Parse(upstreamServer.URL)
+	if parseErr != nil {
+		t.Fatalf("parse upstream: %v", parseErr)
+	}
+	versionedUpstreamURL := *upstreamURL
+	versionedUpstreamURL.Path = "/v2"
+
+	handler := newProxyMountsHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		_, _ = io.WriteString(responseWriter, "static")
+	}), []ProxyMount{
+		{PathPrefix: "/api", Upstream: upstreamURL},
+		{PathPrefix: "/api/v2/", Upstream: &versionedUpstreamURL, StripPrefix: true, PreserveHost: true},
+	}, nil, nil)
+
+	testCases := []struct {
+		name         string
+		target       string
+		expectedBody string
+	}{
+		{name: "prefix kept", target: "/api/users?page=2", expectedBody: upstreamURL.Host + " /api/users?page=2"},
+		{name: "exact prefix", target: "/api", expectedBody: upstreamURL.Host + " /api"},
+		{name: "longest prefix with strip and preserved host", target: "/api/v2/items", expectedBody: "site.test /v2/items"},
+		{name: "segment boundary", target: "/apis", expectedBody: "static"},
+		{name: "other paths", target: "/index.html", expectedBody: "static"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, testCase.target, nil)
+			request.Host = "site.test"
+			recorder := newStatusRecorder(httptest.NewRecorder())
+
+			handler.ServeHTTP(recorder, request)
+
+			responseBody := recorder.ResponseWriter.(*httptest.ResponseRecorder).Body.String()
+			if responseBody != testCase.expectedBody {
+				t.Fatalf("expected body %q, got %q", testCase.expectedBody, responseBody)
+			}
+			proxied := testCase.expectedBody != "static"
+			if proxied != (recorder.upstream != "") {
+				t.Fatalf("expected upstream recorded %t, got %q", proxied, recorder.upstream)
+			}
+		})
+	}
+}
+
+func TestProxyMountsForwardTrustedClientAddress(t *testing.T) {
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		_, _ = io.WriteString(responseWriter, request.Header.Get(forwardedForHeaderName))
+	}))
+	defer upstreamServer.Close()
+	upstreamURL, parseErr := url.Parse(upstreamServer.URL)
+	if parseErr != nil {
+		t.Fatalf("parse upstream: %v", parseErr)
+	}
+
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	handler := newForwardedHeadersHandler(newProxyMountsHandler(http.NotFoundHandler(), []ProxyMount{{PathPrefix: "/api", Upstream: upstreamURL}}, nil, nil), trustedProxies)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	request.RemoteAddr = "10.0.0.2:4000"
+	request.Header.Set(forwardedForHeaderName, "203.0.113.5")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if forwardedFor := recorder.Body.String(); forwardedFor != "203.0.113.5" {
+		t.Fatalf("expected the resolved client in X-Forwarded-For, got %q", forwardedFor)
+	}
+}
+
+func TestProxyMountsHandlerReportsUnreachableUpstream(t *testing.T) {
+	unreachableListener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil {
+		t.Fatalf("listen: %v", listenErr)
+	}
+	unreachableURL := &url.URL{Scheme: "http", Host: unreachableListener.Addr().String()}
+	unreachableListener.Close()
+
+	handler := newProxyMountsHandler(http.NotFoundHandler(), []ProxyMount{{PathPrefix: "/api", Upstream: unreachableURL}}, nil, nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+
+	if recorder.Code != http.StatusBadGateway {
+		t.Fatalf("expected status %d, got %d", http.StatusBadGateway, recorder.Code)
+	}
+}
+
+func TestProxyMountsHandlerPassesConnectionUpgrades(t *testing.T) {
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Upgrade") != "websocket" {
+			http.Error(responseWriter, "upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+		connection, readWriter, hijackErr := http.NewResponseController(responseWriter).Hijack()
+		if hijackErr != nil {
+			return
+		}
+		defer connection.Close()
+		_, _ = readWriter.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		_ = readWriter.Flush()
+		line, _ := readWriter.ReadString('\n')
+		_, _ = readWriter.WriteString("echo " + line)
+		_ = readWriter.Flush()
+	}))
+	defer upstreamServer.Close()
+	upstreamURL, parseErr := url.Parse(upstreamServer.URL)
+	if parseErr != nil {
+		t.Fatalf("parse upstream: %v", parseErr)
+	}
+
+	proxyHandler := newProxyMountsHandler(http.NotFoundHandler(), []ProxyMount{{PathPrefix: "/ws", Upstream: upstreamURL}}, nil, nil)
+	frontServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		proxyHandler.ServeHTTP(newStatusRecorder(responseWriter), request)
+	}))
+	defer frontServer.Close()
+
+	connection, dialErr := net.Dial("tcp", strings.TrimPrefix(frontServer.URL, "http://"))
+	if dialErr != nil {
+		t.Fatalf("dial: %v", dialErr)
+	}
+	defer connection.Close()
+	_, _ = io.WriteString(connection, "GET /ws/socket HTTP/1.1\r\nHost: example.test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	reader := bufio.NewReader(connection)
+	response, readErr := http.ReadResponse(reader, nil)
+	if readErr != nil {
+		t.Fatalf("read response: %v", readErr)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status %d, got %d", http.StatusSwitchingProtocols, response.StatusCode)
+	}
+	_, _ = io.WriteString(connection, "ping\n")
+	echoed, echoErr := reader.ReadString('\n')
+	if echoErr != nil {
+		t.Fatalf("read echo: %v", echoErr)
+	}
+	if echoed != "echo ping\n" {
+		t.Fatalf("expected echoed frame, got %q", echoed)
+	}
+}
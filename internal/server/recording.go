@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+
+	"github.com/temirov/ghttp/internal/recording"
+	"github.com/temirov/ghttp/pkg/logging"
+)
+
+const (
+	acceptEncodingHeaderName = "Accept-Encoding"
+	replayHeaderName         = "X-Ghttp-Replay"
+	replayHeaderValue        = "hit"
+	eventStreamMediaType     = "text/event-stream"
+	logMessageRecordFailed   = "record failed"
+	logMessageReplayFailed   = "replay failed"
+	logMessageRecorded       = "recorded"
+	logFieldFixture          = "fixture"
+)
+
+// RecordConfiguration saves proxied responses as fixtures for ReplayDirectory.
+type RecordConfiguration struct {
+	Directory string
+	// RedactHeaders are removed from responses before they are written, in
+	// addition to recording.DefaultRedactedHeaders.
+	RedactHeaders []string
+}
+
+type recordedRequestContextKey struct{}
+
+// attachRecorder makes the proxy save each complete upstream response under
+// the path and query the client asked for. Dropping the client's
+// Accept-Encoding lets the transport negotiate and decode compression itself,
+// so fixtures hold readable bodies. Protocol switches and event streams are
+// passed through without being recorded.
+func attachRecorder(proxy *httputil.ReverseProxy, configuration RecordConfiguration, loggingService *logging.Service) {
+	store := recording.Store{Directory: configuration.Directory}
+	redactedHeaders := append(append([]string{}, recording.DefaultRedactedHeaders...), configuration.RedactHeaders...)
+	rewrite := proxy.Rewrite
+	proxy.Rewrite = func(proxyRequest *httputil.ProxyRequest) {
+		rewrite(proxyRequest)
+		proxyRequest.Out.Header.Del(acceptEncodingHeaderName)
+		incomingURL := *proxyRequest.In.URL
+		proxyRequest.Out = proxyRequest.Out.WithContext(context.WithValue(proxyRequest.Out.Context(), recordedRequestContextKey{}, &incomingURL))
+	}
+	proxy.ModifyResponse = func(response *http.Response) error {
+		incomingURL, found := response.Request.Context().Value(recordedRequestContextKey{}).(*url.URL)
+		if !found || response.StatusCode == http.StatusSwitchingProtocols || isEventStream(response.Header) {
+			return nil
+		}
+		body, readErr := io.ReadAll(response.Body)
+		response.Body.Close()
+		if readErr != nil {
+			return readErr
+		}
+		response.Body = io.NopCloser(bytes.NewReader(body))
+		exchange := recording.Exchange{
+			Method: response.Request.Method,
+			Path:   incomingURL.Path,
+			Query:  incomingURL.RawQuery,
+			Status: response.StatusCode,
+			Header: response.Header.Clone(),
+		}
+		recording.Redact(exchange.Header, redactedHeaders)
+		exchange.Header.Del(contentLengthHeaderName)
+		exchange.SetBody(body)
+		if saveErr := store.Save(exchange); saveErr != nil {
+			if loggingService != nil {
+				loggingService.Error(logMessageRecordFailed, saveErr, logging.String(logFieldPath, incomingURL.Path))
+			}
+			return nil
+		}
+		if loggingService != nil {
+			fixturePath, _ := store.FixturePath(exchange.Method, exchange.Path, exchange.Query)
+			loggingService.Info(logMessageRecorded, logging.String(logFieldFixture, fixturePath))
+		}
+		return nil
+	}
+}
+
+func isEventStream(header http.Header) bool {
+	mediaType, _, parseErr := mime.ParseMediaType(header.Get(contentTypeHeaderName))
+	return parseErr == nil && mediaType == eventStreamMediaType
+}
+
+type replayHandler struct {
+	next           http.Handler
+	store          recording.Store
+	loggingService *logging.Service
+}
+
+// newReplayHandler answers requests that have a recording with the recorded
+// status, headers and body, marking them with X-Ghttp-Replay. HEAD requests
+// use the GET recording when there is no HEAD recording. Requests without a
+// recording reach next.
+func newReplayHandler(next http.Handler, directory string, loggingService *logging.Service) http.Handler {
+	return replayHandler{next: next, store: recording.Store{Directory: directory}, loggingService: loggingService}
+}
+
+func (handler replayHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	exchange, loadErr := handler.store.Load(request.Method, request.URL.Path, request.URL.RawQuery)
+	if errors.Is(loadErr, recording.ErrFixtureNotFound) && request.Method == http.MethodHead {
+		exchange, loadErr = handler.store.Load(http.MethodGet, request.URL.Path, request.URL.RawQuery)
+	}
+	if errors.Is(loadErr, recording.ErrFixtureNotFound) {
+		handler.next.ServeHTTP(responseWriter, request)
+		return
+	}
+	var body []byte
+	if loadErr == nil {
+		body, loadErr = exchange.BodyBytes()
+	}
+	if loadErr != nil {
+		if handler.loggingService != nil {
+			handler.loggingService.Error(logMessageReplayFailed, loadErr, logging.String(logFieldPath, request.URL.Path))
+		}
+		http.Error(responseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	for name, values := range exchange.Header {
+		responseWriter.Header()[name] = values
+	}
+	responseWriter.Header().Set(replayHeaderName, replayHeaderValue)
+	responseWriter.Header().Set(contentLengthHeaderName, strconv.Itoa(len(body)))
+	responseWriter.WriteHeader(exchange.Status)
+	if request.Method != http.MethodHead {
+		_, _ = responseWriter.Write(body)
+	}
+}
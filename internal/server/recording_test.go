@@ -0,0 +1,89 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/temirov/ghttp/internal/recording"
+)
+
+func TestRecordedResponsesReplayOffline(t *testing.T) {
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.Header().Set("Set-Cookie", "session=secret")
+		responseWriter.Header().Set("X-Internal-Token", "secret")
+		responseWriter.Header().Set("X-Request-Id", "abc")
+		responseWriter.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(responseWriter, `{"path":"`+request.URL.Path+`"}`)
+	}))
+	defer upstreamServer.Close()
+	upstreamURL, parseErr := url.Parse(upstreamServer.URL)
+	if parseErr != nil {
+		t.Fatalf("parse upstream: %v", parseErr)
+	}
+	fixtureDirectory := t.TempDir()
+
+	recordingHandler := newProxyMountsHandler(http.NotFoundHandler(), []ProxyMount{{PathPrefix: "/", Upstream: upstreamURL}}, &RecordConfiguration{Directory: fixtureDirectory, RedactHeaders: []string{"x-internal-token"}}, nil)
+	recordRequest := httptest.NewRequest(http.MethodGet, "/api/users?b=2&a=1", nil)
+	recordRequest.Header.Set("Accept-Encoding", "gzip")
+	recordRecorder := httptest.NewRecorder()
+	recordingHandler.ServeHTTP(recordRecorder, recordRequest)
+	if recordRecorder.Code != http.StatusAccepted || recordRecorder.Body.String() != `{"path":"/api/users"}` {
+		t.Fatalf("expected proxied response, got %d %q", recordRecorder.Code, recordRecorder.Body.String())
+	}
+
+	fixturePath, pathErr := recording.Store{Directory: fixtureDirectory}.FixturePath(http.MethodGet, "/api/users", "a=1&b=2")
+	if pathErr != nil {
+		t.Fatalf("fixture path: %v", pathErr)
+	}
+	fixtureContent, readErr := os.ReadFile(fixturePath)
+	if readErr != nil {
+		t.Fatalf("read fixture: %v", readErr)
+	}
+	for _, secret := range []string{"session=secret", "X-Internal-Token"} {
+		if strings.Contains(string(fixtureContent), secret) {
+			t.Fatalf("expected %q to be redacted from %s", secret, fixtureContent)
+		}
+	}
+
+	replayHandler := newReplayHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		_, _ = io.WriteString(responseWriter, "static")
+	}), fixtureDirectory, nil)
+	testCases := []struct {
+		name           string
+		method         string
+		target         string
+		expectedStatus int
+		expectedBody   string
+		expectedReplay string
+	}{
+		{name: "recorded request", method: http.MethodGet, target: "/api/users?a=1&b=2", expectedStatus: http.StatusAccepted, expectedBody: `{"path":"/api/users"}`, expectedReplay: "hit"},
+		{name: "head uses get recording", method: http.MethodHead, target: "/api/users?a=1&b=2", expectedStatus: http.StatusAccepted, expectedReplay: "hit"},
+		{name: "different query falls through", method: http.MethodGet, target: "/api/users", expectedStatus: http.StatusOK, expectedBody: "static"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			replayHandler.ServeHTTP(recorder, httptest.NewRequest(testCase.method, testCase.target, nil))
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if recorder.Body.String() != testCase.expectedBody {
+				t.Fatalf("expected body %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+			if replayed := recorder.Header().Get("X-Ghttp-Replay"); replayed != testCase.expectedReplay {
+				t.Fatalf("expected replay header %q, got %q", testCase.expectedReplay, replayed)
+			}
+			if testCase.expectedReplay != "" && recorder.Header().Get("X-Request-Id") != "abc" {
+				t.Fatalf("expected recorded headers, got %v", recorder.Header())
+			}
+		})
+	}
+}
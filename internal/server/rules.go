@@ -0,0 +1,117 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	ruleHeaderName     = "X-Ghttp-Rule"
+	ruleDefaultNameTag = "rule-"
+)
+
+// Rule redirects or rewrites requests whose path matches Pattern. Rules are
+// evaluated in order and the first matching rule fires.
+type Rule struct {
+	// Name identifies the rule in the X-Ghttp-Rule response header. Unnamed
+	// rules are reported by their one-based position.
+	Name    string
+	Pattern *regexp.Regexp
+	// Host, when set, must match the request host without its port.
+	Host *regexp.Regexp
+	// Methods, when not empty, restrict the rule to these request methods.
+	Methods []string
+	// Redirect and Rewrite are mutually exclusive targets. The part of the
+	// path matched by Pattern is replaced by the target, with $1 or ${name}
+	// expanding to captures, so "^/old/(.*)" with "/new/$1" maps /old/a to
+	// /new/a. A redirect target may be an absolute URL.
+	Redirect string
+	Rewrite  string
+	// Status is the redirect status code.
+	Status int
+	// DropQuery discards the request query instead of carrying it over.
+	DropQuery bool
+}
+
+type rulesHandler struct {
+	next  http.Handler
+	rules []Rule
+}
+
+// newRulesHandler applies the rules before the request reaches next.
+func newRulesHandler(next http.Handler, rules []Rule) http.Handler {
+	return rulesHandler{next: next, rules: rules}
+}
+
+func (handler rulesHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	for ruleIndex, rule := range handler.rules {
+		target, matched := rule.apply(request)
+		if !matched {
+			continue
+		}
+		ruleName := rule.Name
+		if ruleName == "" {
+			ruleName = ruleDefaultNameTag + strconv.Itoa(ruleIndex+1)
+		}
+		responseWriter.Header().Set(ruleHeaderName, ruleName)
+		targetPath, targetQuery, _ := strings.Cut(target, "?")
+		query := mergeRuleQuery(targetQuery, request.URL.RawQuery, rule.DropQuery)
+		if rule.Redirect != "" {
+			location := targetPath
+			if query != "" {
+				location += "?" + query
+			}
+			http.Redirect(responseWriter, request, location, rule.Status)
+			return
+		}
+		rewrittenRequest := rewriteRequestPath(request, targetPath)
+		rewrittenRequest.URL.RawQuery = query
+		rewrittenRequest.RequestURI = rewrittenRequest.URL.RequestURI()
+		handler.next.ServeHTTP(responseWriter, rewrittenRequest)
+		return
+	}
+	handler.next.ServeHTTP(responseWriter, request)
+}
+
+// apply returns the expanded target when the rule matches the request.
+func (rule Rule) apply(request *http.Request) (string, bool) {
+	if len(rule.Methods) > 0 && !slices.Contains(rule.Methods, request.Method) {
+		return "", false
+	}
+	if rule.Host != nil {
+		host := request.Host
+		if splitHost, _, splitErr := net.SplitHostPort(host); splitErr == nil {
+			host = splitHost
+		}
+		if !rule.Host.MatchString(host) {
+			return "", false
+		}
+	}
+	requestPath := request.URL.Path
+	matchIndexes := rule.Pattern.FindStringSubmatchIndex(requestPath)
+	if matchIndexes == nil {
+		return "", false
+	}
+	targetTemplate := rule.Rewrite
+	if rule.Redirect != "" {
+		targetTemplate = rule.Redirect
+	}
+	expanded := rule.Pattern.ExpandString(nil, targetTemplate, requestPath, matchIndexes)
+	return requestPath[:matchIndexes[0]] + string(expanded) + requestPath[matchIndexes[1]:], true
+}
+
+// mergeRuleQuery combines a query written in the rule target with the query
+// of the original request, which follows it unless dropped.
+func mergeRuleQuery(targetQuery string, requestQuery string, dropRequestQuery bool) string {
+	if dropRequestQuery || requestQuery == "" {
+		return targetQuery
+	}
+	if targetQuery == "" {
+		return requestQuery
+	}
+	return targetQuery + "&" + requestQuery
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRulesHandlerRedirectsAndRewrites(t *testing.T) {
+	rules := []Rule{
+		{Pattern: regexp.MustCompile(`^/old/(.*)`), Redirect: "/new/$1", Status: http.StatusMovedPermanently},
+		{Name: "fixtures", Pattern: regexp.MustCompile(`^/api/v1/`), Rewrite: "/fixtures/v1/"},
+		{Name: "docs-host", Pattern: regexp.MustCompile(`^/$`), Host: regexp.MustCompile(`^docs\.`), Redirect: "https://example.test/docs", Status: http.StatusFound, DropQuery: true},
+		{Name: "post-only", Pattern: regexp.MustCompile(`^/form$`), Methods: []string{http.MethodPost}, Rewrite: "/form-handler.json?source=rule"},
+	}
+	handler := newRulesHandler(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		_, _ = responseWriter.Write([]byte(request.URL.RequestURI()))
+	}), rules)
+
+	testCases := []struct {
+		name             string
+		method           string
+		host             string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+		expectedBody     string
+		expectedRule     string
+	}{
+		{name: "redirect with capture and query", method: http.MethodGet, target: "/old/a/b?x=1", expectedStatus: http.StatusMovedPermanently, expectedLocation: "/new/a/b?x=1", expectedRule: "rule-1"},
+		{name: "rewrite keeps the rest of the path", method: http.MethodGet, target: "/api/v1/users.json?page=2", expectedStatus: http.StatusOK, expectedBody: "/fixtures/v1/users.json?page=2", expectedRule: "fixtures"},
+		{name: "host condition", method: http.MethodGet, host: "docs.example.test:8080", target: "/?drop=me", expectedStatus: http.StatusFound, expectedLocation: "https://example.test/docs", expectedRule: "docs-host"},
+		{name: "host condition not met", method: http.MethodGet, host: "www.example.test", target: "/", expectedStatus: http.StatusOK, expectedBody: "/"},
+		{name: "method condition with target query", method: http.MethodPost, target: "/form?id=7", expectedStatus: http.StatusOK, expectedBody: "/form-handler.json?source=rule&id=7", expectedRule: "post-only"},
+		{name: "method condition not met", method: http.MethodGet, target: "/form", expectedStatus: http.StatusOK, expectedBody: "/form"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(testCase.method, testCase.target, nil)
+			if testCase.host != "" {
+				request.Host = testCase.host
+			}
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if location := recorder.Header().Get("Location"); location != testCase.expectedLocation {
+				t.Fatalf("expected location %q, got %q", testCase.expectedLocation, location)
+			}
+			if testCase.expectedBody != "" && recorder.Body.String() != testCase.expectedBody {
+				t.Fatalf("expected body %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+			if firedRule := recorder.Header().Get("X-Ghttp-Rule"); firedRule != testCase.expectedRule {
+				t.Fatalf("expected rule header %q, got %q", testCase.expectedRule, firedRule)
+			}
+		})
+	}
+}
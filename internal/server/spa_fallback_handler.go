@@ -0,0 +1,97 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	pathpkg "path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	acceptHeaderName           = "Accept"
+	xhtmlMediaType             = "application/xhtml+xml"
+	acceptQualityParameterName = "q"
+)
+
+type spaFallbackHandler struct {
+	next                http.Handler
+	fileSystem          http.FileSystem
+	fallbackRequestPath string
+}
+
+// newSPAFallbackHandler serves the fallback document for client-side routes:
+// GET and HEAD requests for missing paths without a file extension whose
+// Accept header prefers HTML. Missing assets, and requests from scripts that
+// do not ask for HTML, still receive the regular 404.
+func newSPAFallbackHandler(next http.Handler, fileSystem http.FileSystem, fallbackRelativePath string) http.Handler {
+	cleanPath := pathpkg.Clean(pathpkg.Join(initialFileRootRequestPath, strings.ReplaceAll(fallbackRelativePath, "\\", "/")))
+	return spaFallbackHandler{next: next, fileSystem: fileSystem, fallbackRequestPath: cleanPath}
+}
+
+func (handler spaFallbackHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if !handler.isClientRoute(request) {
+		handler.next.ServeHTTP(responseWriter, request)
+		return
+	}
+	fallbackFile, openErr := handler.fileSystem.Open(handler.fallbackRequestPath)
+	if openErr != nil {
+		handler.next.ServeHTTP(responseWriter, request)
+		return
+	}
+	defer fallbackFile.Close()
+	fallbackInfo, statErr := fallbackFile.Stat()
+	if statErr != nil || fallbackInfo.IsDir() {
+		handler.next.ServeHTTP(responseWriter, request)
+		return
+	}
+	http.ServeContent(responseWriter, request, fallbackInfo.Name(), fallbackInfo.ModTime(), fallbackFile)
+}
+
+func (handler spaFallbackHandler) isClientRoute(request *http.Request) bool {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return false
+	}
+	if pathpkg.Ext(request.URL.Path) != "" || !prefersMediaType(request.Header.Get(acceptHeaderName), htmlMediaType, xhtmlMediaType) {
+		return false
+	}
+	file, openErr := handler.fileSystem.Open(request.URL.Path)
+	if openErr == nil {
+		file.Close()
+		return false
+	}
+	return errors.Is(openErr, fs.ErrNotExist)
+}
+
+// prefersMediaType reports whether one of the media types is among the most
+// preferred types of an Accept header. Wildcards do not count, so fetch and
+// XHR requests that accept anything are not mistaken for page navigations.
+func prefersMediaType(acceptHeader string, mediaTypes ...string) bool {
+	preferredQuality := 0.0
+	otherQuality := 0.0
+	for _, mediaRange := range strings.Split(acceptHeader, ",") {
+		mediaType, parameters, parseErr := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if parseErr != nil {
+			continue
+		}
+		quality := 1.0
+		if rawQuality, found := parameters[acceptQualityParameterName]; found {
+			parsedQuality, qualityErr := strconv.ParseFloat(rawQuality, 64)
+			if qualityErr != nil {
+				continue
+			}
+			quality = parsedQuality
+		}
+		switch {
+		case slices.Contains(mediaTypes, mediaType):
+			preferredQuality = max(preferredQuality, quality)
+		case strings.HasSuffix(mediaType, "/*"):
+		default:
+			otherQuality = max(otherQuality, quality)
+		}
+	}
+	return preferredQuality > 0 && preferredQuality >= otherQuality
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const browserNavigationAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+func TestSPAFallbackHandlerServesClientRoutes(t *testing.T) {
+	directoryPath := t.TempDir()
+	if writeErr := os.WriteFile(filepath.Join(directoryPath, "index.html"), []byte("<html>app shell</html>"), 0o644); writeErr != nil {
+		t.Fatalf("write index: %v", writeErr)
+	}
+	if writeErr := os.WriteFile(filepath.Join(directoryPath, "about.html"), []byte("<html>about</html>"), 0o644); writeErr != nil {
+		t.Fatalf("write about: %v", writeErr)
+	}
+	fileSystem := http.Dir(directoryPath)
+	handler := newSPAFallbackHandler(http.FileServer(fileSystem), fileSystem, "index.html")
+
+	testCases := []struct {
+		name           string
+		method         string
+		target         string
+		accept         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "client route", method: http.MethodGet, target: "/users/42", accept: browserNavigationAccept, expectedStatus: http.StatusOK, expectedBody: "app shell"},
+		{name: "client route with trailing slash", method: http.MethodGet, target: "/settings/", accept: browserNavigationAccept, expectedStatus: http.StatusOK, expectedBody: "app shell"},
+		{name: "existing file", method: http.MethodGet, target: "/about.html", accept: browserNavigationAccept, expectedStatus: http.StatusOK, expectedBody: "about"},
+		{name: "missing asset", method: http.MethodGet, target: "/static/app.js", accept: browserNavigationAccept, expectedStatus: http.StatusNotFound},
+		{name: "fetch accepting anything", method: http.MethodGet, target: "/api/users", accept: "*/*", expectedStatus: http.StatusNotFound},
+		{name: "json preferred", method: http.MethodGet, target: "/api/users", accept: "application/json, text/html;q=0.5", expectedStatus: http.StatusNotFound},
+		{name: "post", method: http.MethodPost, target: "/users/42", accept: browserNavigationAccept, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(testCase.method, testCase.target, nil)
+			request.Header.Set("Accept", testCase.accept)
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if testCase.expectedBody != "" && !strings.Contains(recorder.Body.String(), testCase.expectedBody) {
+				t.Fatalf("expected body to contain %q, got %q", testCase.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestSPAFallbackCoexistsWithInitialFile(t *testing.T) {
+	directoryPath := t.TempDir()
+	if writeErr := os.WriteFile(filepath.Join(directoryPath, "app.html"), []byte("<html>app shell</html>"), 0o644); writeErr != nil {
+		t.Fatalf("write app: %v", writeErr)
+	}
+	fileServer := FileServer{}
+	handler := fileServer.buildFileHandler(FileServerConfiguration{
+		DirectoryPath:           directoryPath,
+		InitialFileRelativePath: "app.html",
+		SPAFallbackRelativePath: "app.html",
+	})
+
+	for _, target := range []string{"/", "/dashboard"} {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.Header.Set("Accept", browserNavigationAccept)
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "app shell") {
+			t.Fatalf("expected %s to serve the app shell, got %d %q", target, recorder.Code, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	bitsPerKilobit = 1000
+	bitsPerByte    = 8
+	// throttleChunksPerSecond splits writes so that throttled bodies arrive
+	// as a steady trickle instead of in one burst per second.
+	throttleChunksPerSecond = 20
+	throttleMinimumChunk    = 512
+)
+
+// ThrottleConfiguration limits bandwidth to simulate a slow network. Zero
+// rates leave that direction unlimited.
+type ThrottleConfiguration struct {
+	DownloadKbps int
+	UploadKbps   int
+	// RoundTripTime delays the start of every response.
+	RoundTripTime time.Duration
+	// Global shares one budget across all connections instead of giving
+	// each connection its own.
+	Global bool
+}
+
+// bandwidthLimiter hands out time slots for bytes at a fixed rate. Callers
+// reserve a slot and sleep until it ends, so concurrent writers sharing a
+// limiter split its rate between them.
+type bandwidthLimiter struct {
+	bytesPerSecond float64
+	mutex          sync.Mutex
+	nextAvailable  time.Time
+}
+
+func newBandwidthLimiter(kilobitsPerSecond int) *bandwidthLimiter {
+	if kilobitsPerSecond <= 0 {
+		return nil
+	}
+	return &bandwidthLimiter{bytesPerSecond: float64(kilobitsPerSecond) * bitsPerKilobit / bitsPerByte}
+}
+
+func (limiter *bandwidthLimiter) chunkSize() int {
+	return max(int(limiter.bytesPerSecond/throttleChunksPerSecond), throttleMinimumChunk)
+}
+
+func (limiter *bandwidthLimiter) wait(ctx context.Context, byteCount int) error {
+	limiter.mutex.Lock()
+	now := time.Now()
+	start := limiter.nextAvailable
+	if start.Before(now) {
+		start = now
+	}
+	limiter.nextAvailable = start.Add(time.Duration(float64(byteCount) / limiter.bytesPerSecond * float64(time.Second)))
+	deadline := limiter.nextAvailable
+	limiter.mutex.Unlock()
+	return sleepContext(ctx, time.Until(deadline))
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+	sleepTimer := time.NewTimer(duration)
+	defer sleepTimer.Stop()
+	select {
+	case <-sleepTimer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttleBudget is the download and upload allowance of one connection, or
+// of the whole server when throttling is global.
+type throttleBudget struct {
+	download *bandwidthLimiter
+	upload   *bandwidthLimiter
+}
+
+type throttleBudgetContextKey struct{}
+
+// throttle applies a ThrottleConfiguration to requests.
+type throttle struct {
+	configuration ThrottleConfiguration
+	globalBudget  *throttleBudget
+}
+
+func newThrottle(configuration ThrottleConfiguration) *throttle {
+	throttle := &throttle{configuration: configuration}
+	if configuration.Global {
+		throttle.globalBudget = throttle.newBudget()
+	}
+	return throttle
+}
+
+func (throttle *throttle) newBudget() *throttleBudget {
+	return &throttleBudget{
+		download: newBandwidthLimiter(throttle.configuration.DownloadKbps),
+		upload:   newBandwidthLimiter(throttle.configuration.UploadKbps),
+	}
+}
+
+// connContext gives every accepted connection its own budget. It is meant
+// for http.Server.ConnContext.
+func (throttle *throttle) connContext(ctx context.Context, _ net.Conn) context.Context {
+	if throttle.globalBudget != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, throttleBudgetContextKey{}, throttle.newBudget())
+}
+
+func (throttle *throttle) budget(request *http.Request) *throttleBudget {
+	if throttle.globalBudget != nil {
+		return throttle.globalBudget
+	}
+	if budget, found := request.Context().Value(throttleBudgetContextKey{}).(*throttleBudget); found {
+		return budget
+	}
+	return throttle.newBudget()
+}
+
+// wrap delays each request by the round-trip time and then serves it with a
+// rate-limited request body and response writer.
+func (throttle *throttle) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if sleepContext(request.Context(), throttle.configuration.RoundTripTime) != nil {
+			return
+		}
+		budget := throttle.budget(request)
+		if budget.upload != nil && request.Body != nil && request.Body != http.NoBody {
+			request.Body = &throttledReadCloser{ReadCloser: request.Body, limiter: budget.upload, ctx: request.Context()}
+		}
+		if budget.download != nil {
+			responseWriter = &throttledResponseWriter{ResponseWriter: responseWriter, limiter: budget.download, ctx: request.Context()}
+		}
+		next.ServeHTTP(responseWriter, request)
+	})
+}
+
+// throttledResponseWriter writes the body in small chunks, waiting for the
+// limiter before each one and flushing after it.
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	limiter *bandwidthLimiter
+	ctx     context.Context
+}
+
+func (writer *throttledResponseWriter) Write(content []byte) (int, error) {
+	responseController := http.NewResponseController(writer.ResponseWriter)
+	chunkSize := writer.limiter.chunkSize()
+	totalWritten := 0
+	for len(content) > 0 {
+		chunk := content[:min(chunkSize, len(content))]
+		if waitErr := writer.limiter.wait(writer.ctx, len(chunk)); waitErr != nil {
+			return totalWritten, waitErr
+		}
+		written, writeErr := writer.ResponseWriter.Write(chunk)
+		totalWritten += written
+		if writeErr != nil {
+			return totalWritten, writeErr
+		}
+		_ = responseController.Flush()
+		content = content[len(chunk):]
+	}
+	return totalWritten, nil
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (writer *throttledResponseWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// throttledReadCloser paces reads of a request body.
+type throttledReadCloser struct {
+	io.ReadCloser
+	limiter *bandwidthLimiter
+	ctx     context.Context
+}
+
+func (reader *throttledReadCloser) Read(buffer []byte) (int, error) {
+	if len(buffer) > reader.limiter.chunkSize() {
+		buffer = buffer[:reader.limiter.chunkSize()]
+	}
+	read, readErr := reader.ReadCloser.Read(buffer)
+	if read > 0 {
+		if waitErr := reader.limiter.wait(reader.ctx, read); waitErr != nil {
+			return read, waitErr
+		}
+	}
+	return read, readErr
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottleLimitsResponseAndRequestBodies(t *testing.T) {
+	// 160 kbps is 20000 bytes per second.
+	payload := strings.Repeat("x", 4000)
+	testCases := []struct {
+		name            string
+		configuration   ThrottleConfiguration
+		requestBody     string
+		expectedMinimum time.Duration
+	}{
+		{name: "download", configuration: ThrottleConfiguration{DownloadKbps: 160}, expectedMinimum: 150 * time.Millisecond},
+		{name: "upload", configuration: ThrottleConfiguration{UploadKbps: 160}, requestBody: payload, expectedMinimum: 150 * time.Millisecond},
+		{name: "round trip", configuration: ThrottleConfiguration{RoundTripTime: 100 * time.Millisecond}, expectedMinimum: 100 * time.Millisecond},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := newThrottle(testCase.configuration).wrap(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, _ = io.Copy(io.Discard, request.Body)
+				_, _ = io.WriteString(responseWriter, payload)
+			}))
+			recorder := httptest.NewRecorder()
+			startTime := time.Now()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/artifact.bin", strings.NewReader(testCase.requestBody)))
+			elapsed := time.Since(startTime)
+
+			if recorder.Body.String() != payload {
+				t.Fatalf("expected the full body, got %d bytes", recorder.Body.Len())
+			}
+			if elapsed < testCase.expectedMinimum {
+				t.Fatalf("expected at least %s, got %s", testCase.expectedMinimum, elapsed)
+			}
+		})
+	}
+}
+
+func TestThrottleBudgetsPerConnectionOrGlobal(t *testing.T) {
+	perConnection := newThrottle(ThrottleConfiguration{DownloadKbps: 100})
+	firstRequest := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(perConnection.connContext(context.Background(), nil))
+	secondRequest := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(perConnection.connContext(context.Background(), nil))
+	if perConnection.budget(firstRequest) == perConnection.budget(secondRequest) {
+		t.Fatalf("expected separate connections to get separate budgets")
+	}
+	if perConnection.budget(firstRequest) != perConnection.budget(firstRequest) {
+		t.Fatalf("expected requests on one connection to share a budget")
+	}
+
+	global := newThrottle(ThrottleConfiguration{DownloadKbps: 100, Global: true})
+	firstRequest = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(global.connContext(context.Background(), nil))
+	secondRequest = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(global.connContext(context.Background(), nil))
+	if global.budget(firstRequest) != global.budget(secondRequest) {
+		t.Fatalf("expected a global throttle to share one budget")
+	}
+}
+
+func TestThrottledWriterStopsWhenClientLeaves(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writer := &throttledResponseWriter{ResponseWriter: httptest.NewRecorder(), limiter: newBandwidthLimiter(8), ctx: ctx}
+	written, writeErr := writer.Write(bytes.Repeat([]byte("x"), 4096))
+	if writeErr == nil || written != 0 {
+		t.Fatalf("expected a cancelled write, got %d bytes and %v", written, writeErr)
+	}
+}
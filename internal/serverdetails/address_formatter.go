@@ -12,6 +12,7 @@ const (
 	bindAddressEmptyValue            = ""
 	ipv4AddressAnyValue              = "0.0.0.0"
 	ipv4AddressLoopbackValue         = "127.0.0.1"
+	ipv6AddressAnyValue              = "::"
 	loggingDisplayHostLocalhostValue = "localhost"
 )
 
@@ -25,17 +26,36 @@ func NewServingAddressFormatter() ServingAddressFormatter {
 }
 
 // FormatHostAndPortForLogging returns the host and port combination to display
-// in logs. Any empty, wildcard, or loopback bind addresses are mapped to the
-// more user-friendly "localhost" value.
+// in logs. Any empty, wildcard (IPv4 or IPv6), or IPv4 loopback bind addresses
+// are mapped to the more user-friendly "localhost" value.
 func (formatter ServingAddressFormatter) FormatHostAndPortForLogging(bindAddress string, port string) string {
 	sanitizedHost := strings.TrimSpace(bindAddress)
 	switch sanitizedHost {
-	case bindAddressEmptyValue, ipv4AddressAnyValue, ipv4AddressLoopbackValue:
+	case bindAddressEmptyValue, ipv4AddressAnyValue, ipv4AddressLoopbackValue, ipv6AddressAnyValue:
 		sanitizedHost = loggingDisplayHostLocalhostValue
 	}
 	return net.JoinHostPort(sanitizedHost, port)
 }
 
+// FormatURLsForLogging returns the distinct URLs reachable through each of the
+// bind addresses, preserving their order.
+func (formatter ServingAddressFormatter) FormatURLsForLogging(scheme string, bindAddresses []string, port string) []string {
+	if len(bindAddresses) == 0 {
+		bindAddresses = []string{bindAddressEmptyValue}
+	}
+	seen := map[string]struct{}{}
+	urls := make([]string, 0, len(bindAddresses))
+	for _, bindAddress := range bindAddresses {
+		formattedURL := formatter.FormatURLForLogging(scheme, bindAddress, port)
+		if _, exists := seen[formattedURL]; exists {
+			continue
+		}
+		seen[formattedURL] = struct{}{}
+		urls = append(urls, formattedURL)
+	}
+	return urls
+}
+
 // FormatURLForLogging returns a full URL with scheme for logging output.
 func (formatter ServingAddressFormatter) FormatURLForLogging(scheme string, bindAddress string, port string) string {
 	normalizedScheme := strings.TrimSuffix(strings.TrimSpace(scheme), "://")
@@ -12,6 +12,7 @@ const (
 	testNameLoopbackBindAddress           = "loopback bind address becomes localhost"
 	testNameExternalBindAddressPreserved  = "external bind address is preserved"
 	testNameHostnameWithWhitespaceTrimmed = "hostname with whitespace is trimmed"
+	testNameIPv6WildcardBindAddress       = "ipv6 wildcard bind address becomes localhost"
 	testNameFormatURLHTTP                 = "format url for http scheme"
 	testNameFormatURLHTTPS                = "format url for https scheme"
 	testNameFormatURLWithSchemeSuffix     = "format url trims scheme suffix"
@@ -21,6 +22,7 @@ const (
 	bindAddressExternalValue              = "192.168.10.50"
 	bindAddressHostnameWithWhitespace     = "  example.com  "
 	bindAddressIpvSixValue                = "2001:db8::1"
+	bindAddressIpvSixWildcardValue        = "::"
 	portValue                             = "8000"
 	expectedLocalhostDisplay              = "localhost:8000"
 	expectedExternalDisplay               = "192.168.10.50:8000"
@@ -52,6 +54,11 @@ func TestServingAddressFormatter_FormatHostAndPortForLogging(t *testing.T) {
 			bindAddress: bindAddressLoopbackValue,
 			expected:    expectedLocalhostDisplay,
 		},
+		{
+			name:        testNameIPv6WildcardBindAddress,
+			bindAddress: bindAddressIpvSixWildcardValue,
+			expected:    expectedLocalhostDisplay,
+		},
 		{
 			name:        testNameExternalBindAddressPreserved,
 			bindAddress: bindAddressExternalValue,
@@ -125,3 +132,24 @@ func TestServingAddressFormatter_FormatURLForLogging(t *testing.T) {
 		})
 	}
 }
+
+func TestServingAddressFormatter_FormatURLsForLoggingDeduplicates(t *testing.T) {
+	formatter := serverdetails.NewServingAddressFormatter()
+	bindAddresses := []string{bindAddressLoopbackValue, bindAddressEmptyValue, bindAddressExternalValue, bindAddressIpvSixValue}
+
+	actual := formatter.FormatURLsForLogging("http", bindAddresses, portValue)
+	expected := []string{expectedHTTPURL, "http://" + expectedExternalDisplay, "http://[2001:db8::1]:8000"}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected urls %v, got %v", expected, actual)
+	}
+	for index := range expected {
+		if actual[index] != expected[index] {
+			t.Fatalf("expected urls %v, got %v", expected, actual)
+		}
+	}
+
+	defaultURLs := formatter.FormatURLsForLogging("https", nil, portValue)
+	if len(defaultURLs) != 1 || defaultURLs[0] != expectedHTTPSURL {
+		t.Fatalf("expected default url %s, got %v", expectedHTTPSURL, defaultURLs)
+	}
+}
@@ -0,0 +1,200 @@
+// Package socketactivation retrieves listening sockets passed to the process
+// by systemd, or any supervisor implementing the same LISTEN_FDS protocol.
+package socketactivation
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EnvironmentListenPID names the variable holding the intended recipient process ID.
+	EnvironmentListenPID = "LISTEN_PID"
+	// EnvironmentListenFDs names the variable holding the number of passed descriptors.
+	EnvironmentListenFDs = "LISTEN_FDS"
+	// EnvironmentListenFDNames names the variable holding colon-separated descriptor names.
+	EnvironmentListenFDNames = "LISTEN_FDNAMES"
+	// EnvironmentHandoffFDs names the variable holding the number of descriptors
+	// handed to a re-executed ghttp process by its predecessor.
+	EnvironmentHandoffFDs = "GHTTP_HANDOFF_FDS"
+	// EnvironmentHandoffFDNames names the variable holding colon-separated handoff descriptor names.
+	EnvironmentHandoffFDNames = "GHTTP_HANDOFF_FDNAMES"
+	// EnvironmentHandoffReadyFD names the variable holding the descriptor a
+	// re-executed process writes to once it serves the handed-off listeners.
+	EnvironmentHandoffReadyFD = "GHTTP_HANDOFF_READY_FD"
+	// FirstListenFileDescriptor is the first descriptor number passed by the supervisor.
+	FirstListenFileDescriptor = 3
+
+	fileDescriptorNameSeparator = ":"
+	handoffReadyByte            = 1
+)
+
+// Environment exposes the process state consulted by the activation protocol.
+type Environment interface {
+	LookupEnv(key string) (string, bool)
+	Unsetenv(key string) error
+	ProcessID() int
+}
+
+// ProcessEnvironment reads activation state from the running process.
+type ProcessEnvironment struct{}
+
+// NewProcessEnvironment constructs a ProcessEnvironment.
+func NewProcessEnvironment() ProcessEnvironment {
+	return ProcessEnvironment{}
+}
+
+// LookupEnv reports the value of an environment variable.
+func (environment ProcessEnvironment) LookupEnv(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// Unsetenv removes an environment variable.
+func (environment ProcessEnvironment) Unsetenv(key string) error {
+	return os.Unsetenv(key)
+}
+
+// ProcessID reports the current process ID.
+func (environment ProcessEnvironment) ProcessID() int {
+	return os.Getpid()
+}
+
+// Descriptor identifies a passed file descriptor and its configured name.
+type Descriptor struct {
+	FileDescriptor int
+	Name           string
+}
+
+// NamedListener pairs an inherited listener with its descriptor name.
+type NamedListener struct {
+	Name     string
+	Listener net.Listener
+}
+
+// Descriptors parses the activation variables. It returns no descriptors when
+// the variables are absent or addressed to a different process.
+func Descriptors(environment Environment) ([]Descriptor, error) {
+	processIDValue, processIDPresent := environment.LookupEnv(EnvironmentListenPID)
+	if !processIDPresent {
+		return nil, nil
+	}
+	processID, processIDErr := strconv.Atoi(strings.TrimSpace(processIDValue))
+	if processIDErr != nil {
+		return nil, fmt.Errorf("parse %s: %w", EnvironmentListenPID, processIDErr)
+	}
+	if processID != environment.ProcessID() {
+		return nil, nil
+	}
+	return parseDescriptors(environment, EnvironmentListenFDs, EnvironmentListenFDNames)
+}
+
+// HandoffDescriptors parses the variables set by a ghttp process that passed
+// its listeners to this one. The parent cannot know the child process ID in
+// advance, so unlike LISTEN_PID no recipient check is performed.
+func HandoffDescriptors(environment Environment) ([]Descriptor, error) {
+	if _, present := environment.LookupEnv(EnvironmentHandoffFDs); !present {
+		return nil, nil
+	}
+	return parseDescriptors(environment, EnvironmentHandoffFDs, EnvironmentHandoffFDNames)
+}
+
+func parseDescriptors(environment Environment, countKey string, namesKey string) ([]Descriptor, error) {
+	countValue, _ := environment.LookupEnv(countKey)
+	count, countErr := strconv.Atoi(strings.TrimSpace(countValue))
+	if countErr != nil || count < 0 {
+		return nil, fmt.Errorf("invalid %s value %q", countKey, countValue)
+	}
+	var names []string
+	if namesValue, namesPresent := environment.LookupEnv(namesKey); namesPresent && namesValue != "" {
+		names = strings.Split(namesValue, fileDescriptorNameSeparator)
+	}
+	if len(names) > 0 && len(names) != count {
+		return nil, fmt.Errorf("%s lists %d names for %d descriptors", namesKey, len(names), count)
+	}
+	descriptors := make([]Descriptor, 0, count)
+	for index := 0; index < count; index++ {
+		descriptor := Descriptor{FileDescriptor: FirstListenFileDescriptor + index}
+		if len(names) > 0 {
+			descriptor.Name = names[index]
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors, nil
+}
+
+// HandoffEnvironment returns the variables that describe descriptors passed
+// to a child process as ExtraFiles: one listener per name, in order, followed
+// by the write end of the readiness pipe.
+func HandoffEnvironment(names []string) []string {
+	return []string{
+		EnvironmentHandoffFDs + "=" + strconv.Itoa(len(names)),
+		EnvironmentHandoffFDNames + "=" + strings.Join(names, fileDescriptorNameSeparator),
+		EnvironmentHandoffReadyFD + "=" + strconv.Itoa(FirstListenFileDescriptor+len(names)),
+	}
+}
+
+// SignalHandoffReady tells the process that handed over its listeners that
+// this one is serving them. It does nothing when no handoff took place.
+func SignalHandoffReady(environment Environment) error {
+	readyValue, present := environment.LookupEnv(EnvironmentHandoffReadyFD)
+	if !present {
+		return nil
+	}
+	_ = environment.Unsetenv(EnvironmentHandoffReadyFD)
+	readyDescriptor, parseErr := strconv.Atoi(strings.TrimSpace(readyValue))
+	if parseErr != nil || readyDescriptor < FirstListenFileDescriptor {
+		return fmt.Errorf("invalid %s value %q", EnvironmentHandoffReadyFD, readyValue)
+	}
+	readyFile := os.NewFile(uintptr(readyDescriptor), EnvironmentHandoffReadyFD)
+	defer readyFile.Close()
+	if _, writeErr := readyFile.Write([]byte{handoffReadyByte}); writeErr != nil {
+		return fmt.Errorf("signal handoff readiness: %w", writeErr)
+	}
+	return nil
+}
+
+// Listeners converts the passed descriptors into listeners and clears the
+// activation variables so that child processes do not inherit them.
+func Listeners(environment Environment) ([]NamedListener, error) {
+	descriptors, descriptorsErr := Descriptors(environment)
+	if descriptorsErr != nil {
+		return nil, descriptorsErr
+	}
+	for _, key := range []string{EnvironmentListenPID, EnvironmentListenFDs, EnvironmentListenFDNames} {
+		_ = environment.Unsetenv(key)
+	}
+	return openListeners(descriptors)
+}
+
+// HandoffListeners converts descriptors handed over by a previous ghttp
+// process into listeners and clears the handoff variables.
+func HandoffListeners(environment Environment) ([]NamedListener, error) {
+	descriptors, descriptorsErr := HandoffDescriptors(environment)
+	if descriptorsErr != nil {
+		return nil, descriptorsErr
+	}
+	for _, key := range []string{EnvironmentHandoffFDs, EnvironmentHandoffFDNames} {
+		_ = environment.Unsetenv(key)
+	}
+	return openListeners(descriptors)
+}
+
+func openListeners(descriptors []Descriptor) ([]NamedListener, error) {
+	listeners := make([]NamedListener, 0, len(descriptors))
+	for _, descriptor := range descriptors {
+		file := os.NewFile(uintptr(descriptor.FileDescriptor), descriptor.Name)
+		listener, listenerErr := net.FileListener(file)
+		_ = file.Close()
+		if listenerErr != nil {
+			for _, opened := range listeners {
+				_ = opened.Listener.Close()
+			}
+			return nil, fmt.Errorf("inherit descriptor %d: %w", descriptor.FileDescriptor, listenerErr)
+		}
+		listeners = append(listeners, NamedListener{Name: descriptor.Name, Listener: listener})
+	}
+	return listeners, nil
+}
@@ -0,0 +1,151 @@
+package socketactivation
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type stubEnvironment struct {
+	values    map[string]string
+	processID int
+}
+
+func (environment stubEnvironment) LookupEnv(key string) (string, bool) {
+	value, present := environment.values[key]
+	return value, present
+}
+
+func (environment stubEnvironment) Unsetenv(key string) error {
+	delete(environment.values, key)
+	return nil
+}
+
+func (environment stubEnvironment) ProcessID() int {
+	return environment.processID
+}
+
+func TestDescriptors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		values        map[string]string
+		expected      []Descriptor
+		expectedError bool
+	}{
+		{
+			name:     "not activated",
+			values:   map[string]string{},
+			expected: nil,
+		},
+		{
+			name:     "addressed to another process",
+			values:   map[string]string{EnvironmentListenPID: "7", EnvironmentListenFDs: "1"},
+			expected: nil,
+		},
+		{
+			name:   "unnamed descriptors",
+			values: map[string]string{EnvironmentListenPID: "42", EnvironmentListenFDs: "2"},
+			expected: []Descriptor{
+				{FileDescriptor: 3},
+				{FileDescriptor: 4},
+			},
+		},
+		{
+			name:   "named descriptors",
+			values: map[string]string{EnvironmentListenPID: "42", EnvironmentListenFDs: "2", EnvironmentListenFDNames: "http:https"},
+			expected: []Descriptor{
+				{FileDescriptor: 3, Name: "http"},
+				{FileDescriptor: 4, Name: "https"},
+			},
+		},
+		{
+			name:          "name count mismatch",
+			values:        map[string]string{EnvironmentListenPID: "42", EnvironmentListenFDs: "2", EnvironmentListenFDNames: "http"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid descriptor count",
+			values:        map[string]string{EnvironmentListenPID: "42", EnvironmentListenFDs: "many"},
+			expectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			environment := stubEnvironment{values: testCase.values, processID: 42}
+			descriptors, err := Descriptors(environment)
+			if testCase.expectedError {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(descriptors) == 0 && len(testCase.expected) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(descriptors, testCase.expected) {
+				t.Fatalf("expected %+v, got %+v", testCase.expected, descriptors)
+			}
+		})
+	}
+}
+
+func TestHandoffDescriptorsRoundTripEnvironment(t *testing.T) {
+	values := map[string]string{}
+	for _, assignment := range HandoffEnvironment([]string{"https", "http"}) {
+		key, value, _ := strings.Cut(assignment, "=")
+		values[key] = value
+	}
+	environment := stubEnvironment{values: values, processID: 42}
+
+	descriptors, err := HandoffDescriptors(environment)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Descriptor{{FileDescriptor: 3, Name: "https"}, {FileDescriptor: 4, Name: "http"}}
+	if !reflect.DeepEqual(descriptors, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, descriptors)
+	}
+	if values[EnvironmentHandoffReadyFD] != "5" {
+		t.Fatalf("expected readiness descriptor 5, got %q", values[EnvironmentHandoffReadyFD])
+	}
+
+	systemdDescriptors, systemdErr := Descriptors(environment)
+	if systemdErr != nil || systemdDescriptors != nil {
+		t.Fatalf("expected handoff variables to be ignored by socket activation, got %+v (%v)", systemdDescriptors, systemdErr)
+	}
+}
+
+func TestSignalHandoffReadyWritesToDescriptor(t *testing.T) {
+	readyReader, readyWriter, pipeErr := os.Pipe()
+	if pipeErr != nil {
+		t.Fatalf("pipe: %v", pipeErr)
+	}
+	defer readyReader.Close()
+	environment := stubEnvironment{values: map[string]string{
+		EnvironmentHandoffReadyFD: strconv.Itoa(int(readyWriter.Fd())),
+	}}
+
+	if err := SignalHandoffReady(environment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, readErr := io.ReadAll(readyReader)
+	if readErr != nil {
+		t.Fatalf("read readiness pipe: %v", readErr)
+	}
+	if len(content) != 1 {
+		t.Fatalf("expected a single readiness byte, got %v", content)
+	}
+	if _, present := environment.values[EnvironmentHandoffReadyFD]; present {
+		t.Fatalf("expected readiness variable to be cleared")
+	}
+	if err := SignalHandoffReady(environment); err != nil {
+		t.Fatalf("expected repeated signal to be a no-op, got %v", err)
+	}
+}
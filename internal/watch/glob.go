@@ -0,0 +1,56 @@
+package watch
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const globAnySegments = "**"
+
+// MatchGlob reports whether a slash-separated path relative to the watched
+// root matches the pattern. A pattern without a slash matches the last path
+// element at any depth, so "*.md" or "node_modules" behave as in .gitignore.
+// A pattern with a slash, including a leading one, is anchored at the root,
+// and a "**" element matches any number of directories, so "src/**" matches
+// src and everything below it.
+func MatchGlob(pattern string, relativePath string) bool {
+	pathSegments := strings.Split(relativePath, "/")
+	if !strings.Contains(pattern, "/") && pattern != globAnySegments {
+		matched, _ := path.Match(pattern, pathSegments[len(pathSegments)-1])
+		return matched
+	}
+	return matchGlobSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), pathSegments)
+}
+
+// ValidateGlob reports a malformed pattern before it is used for matching.
+func ValidateGlob(pattern string) error {
+	for _, segment := range strings.Split(strings.TrimPrefix(pattern, "/"), "/") {
+		if segment == globAnySegments {
+			continue
+		}
+		if _, matchErr := path.Match(segment, ""); matchErr != nil {
+			return fmt.Errorf("invalid glob %q: %w", pattern, matchErr)
+		}
+	}
+	return nil
+}
+
+func matchGlobSegments(patternSegments []string, pathSegments []string) bool {
+	if len(patternSegments) == 0 {
+		return len(pathSegments) == 0
+	}
+	if patternSegments[0] == globAnySegments {
+		for skipped := 0; skipped <= len(pathSegments); skipped++ {
+			if matchGlobSegments(patternSegments[1:], pathSegments[skipped:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(pathSegments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(patternSegments[0], pathSegments[0])
+	return matched && matchGlobSegments(patternSegments[1:], pathSegments[1:])
+}
@@ -0,0 +1,40 @@
+package watch
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		name         string
+		pattern      string
+		relativePath string
+		expected     bool
+	}{
+		{name: "basename at depth", pattern: "*.md", relativePath: "docs/guide/intro.md", expected: true},
+		{name: "basename mismatch", pattern: "*.md", relativePath: "docs/intro.html", expected: false},
+		{name: "directory name at depth", pattern: "node_modules", relativePath: "web/node_modules", expected: true},
+		{name: "anchored pattern", pattern: "src/*.go", relativePath: "src/main.go", expected: true},
+		{name: "anchored pattern does not float", pattern: "src/*.go", relativePath: "lib/src/main.go", expected: false},
+		{name: "double star matches the directory", pattern: "src/**", relativePath: "src", expected: true},
+		{name: "double star matches descendants", pattern: "src/**", relativePath: "src/a/b/c.css", expected: true},
+		{name: "double star in the middle", pattern: "content/**/*.md", relativePath: "content/post.md", expected: true},
+		{name: "leading slash anchors", pattern: "/public", relativePath: "public", expected: true},
+		{name: "leading slash with deeper path", pattern: "/public", relativePath: "site/public", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if matched := MatchGlob(testCase.pattern, testCase.relativePath); matched != testCase.expected {
+				t.Fatalf("MatchGlob(%q, %q) = %t, expected %t", testCase.pattern, testCase.relativePath, matched, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestValidateGlobRejectsMalformedPatterns(t *testing.T) {
+	if validateErr := ValidateGlob("src/**/*.go"); validateErr != nil {
+		t.Fatalf("expected valid pattern, got %v", validateErr)
+	}
+	if validateErr := ValidateGlob("src/[a-"); validateErr == nil {
+		t.Fatalf("expected malformed pattern to be rejected")
+	}
+}
@@ -0,0 +1,173 @@
+// Package watch reports batches of file changes below a directory tree.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is the quiet period used when Options.Debounce is zero.
+const DefaultDebounce = 100 * time.Millisecond
+
+// Options configure a Watcher.
+type Options struct {
+	// Debounce is the quiet period after the last change before a batch is
+	// reported, so that editors and build tools writing several files in a
+	// row produce a single notification.
+	Debounce time.Duration
+	// Ignore reports whether a slash-separated path relative to the root is
+	// skipped. Ignored directories are not watched at all.
+	Ignore func(relativePath string, isDirectory bool) bool
+}
+
+// Watcher watches a directory tree recursively. Paths with a segment that
+// starts with a dot, such as .git or editor swap files, are never reported.
+type Watcher struct {
+	rootPath string
+	options  Options
+	notifier *fsnotify.Watcher
+}
+
+// New starts watching every directory below rootPath.
+func New(rootPath string, options Options) (*Watcher, error) {
+	if options.Debounce <= 0 {
+		options.Debounce = DefaultDebounce
+	}
+	notifier, notifierErr := fsnotify.NewWatcher()
+	if notifierErr != nil {
+		return nil, fmt.Errorf("create watcher: %w", notifierErr)
+	}
+	watcher := &Watcher{rootPath: filepath.Clean(rootPath), options: options, notifier: notifier}
+	if addErr := watcher.addTree(watcher.rootPath); addErr != nil {
+		_ = notifier.Close()
+		return nil, addErr
+	}
+	return watcher, nil
+}
+
+// Close stops watching. It is safe to call after Run has returned.
+func (watcher *Watcher) Close() error {
+	return watcher.notifier.Close()
+}
+
+// Run delivers debounced batches of changed paths, relative to the root and
+// sorted, until the context is cancelled. Watcher errors are passed to
+// onError and do not stop the loop. Run closes the watcher when it returns.
+func (watcher *Watcher) Run(ctx context.Context, onChange func([]string), onError func(error)) {
+	defer watcher.notifier.Close()
+	pending := map[string]struct{}{}
+	debounceTimer := time.NewTimer(watcher.options.Debounce)
+	debounceTimer.Stop()
+	defer debounceTimer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, open := <-watcher.notifier.Events:
+			if !open {
+				return
+			}
+			relativePath, relevant := watcher.handleEvent(event, onError)
+			if !relevant {
+				continue
+			}
+			pending[relativePath] = struct{}{}
+			debounceTimer.Reset(watcher.options.Debounce)
+		case watchErr, open := <-watcher.notifier.Errors:
+			if !open {
+				return
+			}
+			if onError != nil {
+				onError(watchErr)
+			}
+		case <-debounceTimer.C:
+			if len(pending) == 0 {
+				continue
+			}
+			changedPaths := make([]string, 0, len(pending))
+			for changedPath := range pending {
+				changedPaths = append(changedPaths, changedPath)
+			}
+			sort.Strings(changedPaths)
+			pending = map[string]struct{}{}
+			onChange(changedPaths)
+		}
+	}
+}
+
+// handleEvent starts watching newly created directories and reports whether
+// the event belongs in the next batch. Attribute-only changes are dropped.
+func (watcher *Watcher) handleEvent(event fsnotify.Event, onError func(error)) (string, bool) {
+	if event.Op == fsnotify.Chmod {
+		return "", false
+	}
+	relativePath, relativeErr := filepath.Rel(watcher.rootPath, event.Name)
+	if relativeErr != nil || relativePath == "." || strings.HasPrefix(relativePath, "..") {
+		return "", false
+	}
+	relativePath = filepath.ToSlash(relativePath)
+	isDirectory := false
+	if event.Has(fsnotify.Create) {
+		if fileInfo, statErr := os.Stat(event.Name); statErr == nil && fileInfo.IsDir() {
+			isDirectory = true
+			if watcher.ignored(relativePath, true) {
+				return "", false
+			}
+			if addErr := watcher.addTree(event.Name); addErr != nil && onError != nil {
+				onError(addErr)
+			}
+		}
+	}
+	if watcher.ignored(relativePath, isDirectory) {
+		return "", false
+	}
+	return relativePath, true
+}
+
+func (watcher *Watcher) addTree(directoryPath string) error {
+	return filepath.WalkDir(directoryPath, func(currentPath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if errors.Is(walkErr, fs.ErrNotExist) {
+				return nil
+			}
+			return walkErr
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if currentPath != watcher.rootPath {
+			relativePath, relativeErr := filepath.Rel(watcher.rootPath, currentPath)
+			if relativeErr != nil {
+				return relativeErr
+			}
+			if watcher.ignored(filepath.ToSlash(relativePath), true) {
+				return filepath.SkipDir
+			}
+		}
+		if addErr := watcher.notifier.Add(currentPath); addErr != nil {
+			return fmt.Errorf("watch %s: %w", currentPath, addErr)
+		}
+		return nil
+	})
+}
+
+func (watcher *Watcher) ignored(relativePath string, isDirectory bool) bool {
+	for _, segment := range strings.Split(relativePath, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+	if watcher.options.Ignore != nil {
+		return watcher.options.Ignore(relativePath, isDirectory)
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startWatcher(t *testing.T, rootPath string, options Options) <-chan []string {
+	t.Helper()
+	watcher, watcherErr := New(rootPath, options)
+	if watcherErr != nil {
+		t.Fatalf("new watcher: %v", watcherErr)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	batches := make(chan []string, 8)
+	go watcher.Run(ctx, func(changedPaths []string) {
+		batches <- changedPaths
+	}, func(err error) {
+		t.Errorf("watch error: %v", err)
+	})
+	return batches
+}
+
+func awaitBatch(t *testing.T, batches <-chan []string) []string {
+	t.Helper()
+	select {
+	case batch := <-batches:
+		return batch
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for changes")
+		return nil
+	}
+}
+
+func writeFile(t *testing.T, filePath string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", filePath, err)
+	}
+}
+
+func TestWatcherDebouncesChangesIntoOneBatch(t *testing.T) {
+	rootPath := t.TempDir()
+	batches := startWatcher(t, rootPath, Options{Debounce: 200 * time.Millisecond})
+
+	writeFile(t, filepath.Join(rootPath, "b.css"), "body{}")
+	writeFile(t, filepath.Join(rootPath, "a.html"), "<p>one</p>")
+	writeFile(t, filepath.Join(rootPath, "a.html"), "<p>two</p>")
+
+	batch := awaitBatch(t, batches)
+	if !reflect.DeepEqual(batch, []string{"a.html", "b.css"}) {
+		t.Fatalf("expected one sorted batch, got %v", batch)
+	}
+}
+
+func TestWatcherFollowsNewDirectoriesAndSkipsIgnoredPaths(t *testing.T) {
+	rootPath := t.TempDir()
+	if err := os.Mkdir(filepath.Join(rootPath, "node_modules"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	batches := startWatcher(t, rootPath, Options{
+		Debounce: 50 * time.Millisecond,
+		Ignore: func(relativePath string, isDirectory bool) bool {
+			return strings.HasPrefix(relativePath, "node_modules")
+		},
+	})
+
+	nestedDirectory := filepath.Join(rootPath, "docs", "guide")
+	if err := os.MkdirAll(nestedDirectory, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	awaitBatch(t, batches)
+
+	writeFile(t, filepath.Join(rootPath, "node_modules", "lib.js"), "ignored")
+	writeFile(t, filepath.Join(rootPath, ".index.html.swp"), "ignored")
+	writeFile(t, filepath.Join(nestedDirectory, "page.md"), "# Page")
+
+	batch := awaitBatch(t, batches)
+	if !reflect.DeepEqual(batch, []string{"docs/guide/page.md"}) {
+		t.Fatalf("expected only the nested page, got %v", batch)
+	}
+}
@@ -119,6 +119,11 @@ func (service *Service) Info(message string, fields ...Field) {
 	service.log(zapcore.InfoLevel, message, nil, fields...)
 }
 
+// Warn writes a warning message.
+func (service *Service) Warn(message string, fields ...Field) {
+	service.log(zapcore.WarnLevel, message, nil, fields...)
+}
+
 // Error writes an error message with the provided error.
 func (service *Service) Error(message string, err error, fields ...Field) {
 	service.log(zapcore.ErrorLevel, message, err, fields...)
@@ -145,6 +150,8 @@ func (service *Service) log(level zapcore.Level, message string, err error, fiel
 	switch level {
 	case zapcore.ErrorLevel:
 		service.logger.Error(message, zapFields...)
+	case zapcore.WarnLevel:
+		service.logger.Warn(message, zapFields...)
 	default:
 		service.logger.Info(message, zapFields...)
 	}
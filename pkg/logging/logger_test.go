@@ -186,6 +186,32 @@ func TestServiceLogsStructuredMessages(t *testing.T) {
 	assertJSONField(t, errorEntry, jsonErrorKey, structuredError.Error())
 }
 
+func TestServiceLogsStructuredWarnings(t *testing.T) {
+	logBuffer := &bytes.Buffer{}
+	jsonEncoderConfig := zap.NewProductionEncoderConfig()
+	jsonEncoderConfig.MessageKey = jsonMessageKey
+	jsonEncoderConfig.LevelKey = jsonLevelKey
+	jsonEncoderConfig.TimeKey = ""
+	jsonCore := zapcore.NewCore(zapcore.NewJSONEncoder(jsonEncoderConfig), zapcore.AddSync(logBuffer), zapcore.InfoLevel)
+	loggingService, err := logging.NewServiceWithLogger(logging.TypeJSON, zap.New(jsonCore))
+	if err != nil {
+		t.Fatalf("failed to create structured logging service: %v", err)
+	}
+
+	loggingService.Warn(jsonLogMessage, logging.Int(jsonFieldKeyStatus, jsonFieldValueStatus))
+	if syncErr := loggingService.Sync(); syncErr != nil {
+		t.Fatalf("failed to sync structured logger: %v", syncErr)
+	}
+
+	warnEntry := map[string]any{}
+	if err := json.Unmarshal(bytes.TrimSpace(logBuffer.Bytes()), &warnEntry); err != nil {
+		t.Fatalf("failed to parse warn entry: %v", err)
+	}
+	assertJSONField(t, warnEntry, jsonMessageKey, jsonLogMessage)
+	assertJSONField(t, warnEntry, jsonLevelKey, "warn")
+	assertJSONField(t, warnEntry, jsonFieldKeyStatus, float64(jsonFieldValueStatus))
+}
+
 func assertJSONField(t *testing.T, entry map[string]any, key string, expected any) {
 	t.Helper()
 	value, exists := entry[key]